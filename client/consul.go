@@ -1,17 +1,31 @@
 package client
 
 import (
+	"context"
+
 	consul "github.com/hashicorp/consul/api"
 )
 
 // NewConsulClient is used to create a new client to interact with Consul.
-func NewConsulClient(addr string) (*consul.Client, error) {
+// Requests are cancelled once the context, when not nil, is done.
+func NewConsulClient(ctx context.Context, addr string) (*consul.Client, error) {
 	config := consul.DefaultConfig()
 
 	if addr != "" {
 		config.Address = addr
 	}
 
+	// Apply the context to each request so an overall deadline also cancels
+	// Consul lookups and lock waits.
+	if ctx != nil && ctx.Done() != nil {
+		httpClient, err := consul.NewHttpClient(config.Transport, config.TLSConfig)
+		if err != nil {
+			return nil, err
+		}
+		httpClient.Transport = &contextRoundTripper{ctx: ctx, next: httpClient.Transport}
+		config.HttpClient = httpClient
+	}
+
 	c, err := consul.NewClient(config)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// contextRoundTripper cancels each request, along with the reading of its
+// response body, once the context is done. The context of the request itself
+// is still honoured, so per-request deadlines and cancellation continue to
+// apply.
+type contextRoundTripper struct {
+	ctx  context.Context
+	next http.RoundTripper
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (c *contextRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	ctx, cancel := context.WithCancel(req.Context())
+	go func() {
+		select {
+		case <-c.ctx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	resp, err := c.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelBody releases the context of the request once the response body is
+// closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close closes the response body and releases the context of the request.
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
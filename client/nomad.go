@@ -1,15 +1,63 @@
 package client
 
 import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
 	nomad "github.com/hashicorp/nomad/api"
+	"github.com/jrasell/levant/levant/structs"
+	"github.com/rs/zerolog/log"
 )
 
+// sensitiveHeaderParts identify header names whose values are redacted when
+// logged.
+var sensitiveHeaderParts = []string{"auth", "cookie", "key", "password", "secret", "token"}
+
 // NewNomadClient is used to create a new client to interact with Nomad.
 func NewNomadClient(addr string) (*nomad.Client, error) {
+	return NewNomadTargetClient(&structs.ClientConfig{Addr: addr})
+}
+
+// NewNomadTargetClient is used to create a new client to interact with Nomad
+// which targets the address, region and namespace of the client config, when
+// set, rather than those of the environment or agent. The headers of the
+// client config are added to every request, and requests are cancelled once
+// the context of the client config is done.
+func NewNomadTargetClient(clientConfig *structs.ClientConfig) (*nomad.Client, error) {
 	config := nomad.DefaultConfig()
 
-	if addr != "" {
-		config.Address = addr
+	if clientConfig.Addr != "" {
+		config.Address = clientConfig.Addr
+	}
+	if clientConfig.Region != "" {
+		config.Region = clientConfig.Region
+	}
+	if clientConfig.Namespace != "" {
+		config.Namespace = clientConfig.Namespace
+	}
+
+	ctx := clientConfig.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	headers := clientConfig.Headers
+	if len(headers) > 0 {
+		log.Debug().Msgf("levant/client: adding custom headers to Nomad requests: %s",
+			strings.Join(redactHeaders(headers), ", "))
+	}
+
+	// The default HTTP client is replaced only when requests need the custom
+	// headers or the context applied.
+	if len(headers) > 0 || ctx.Done() != nil {
+		httpClient, err := nomadHTTPClient(config.TLSConfig, headers, ctx)
+		if err != nil {
+			return nil, err
+		}
+		config.HttpClient = httpClient
 	}
 
 	c, err := nomad.NewClient(config)
@@ -19,3 +67,78 @@ func NewNomadClient(addr string) (*nomad.Client, error) {
 
 	return c, nil
 }
+
+// nomadHTTPClient builds an HTTP client matching the Nomad API default,
+// including the TLS configuration, whose transport adds the headers and
+// context to each request.
+func nomadHTTPClient(tlsConfig *nomad.TLSConfig, headers http.Header, ctx context.Context) (*http.Client, error) {
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSHandshakeTimeout = 10 * time.Second
+
+	httpClient := &http.Client{Transport: transport}
+	if err := nomad.ConfigureTLS(httpClient, tlsConfig); err != nil {
+		return nil, err
+	}
+
+	var rt http.RoundTripper = transport
+	if len(headers) > 0 {
+		rt = &headerRoundTripper{headers: headers, next: rt}
+	}
+	if ctx.Done() != nil {
+		rt = &contextRoundTripper{ctx: ctx, next: rt}
+	}
+
+	httpClient.Transport = rt
+	return httpClient, nil
+}
+
+// headerRoundTripper adds the custom headers to each request before passing it
+// to the next round tripper.
+type headerRoundTripper struct {
+	headers http.Header
+	next    http.RoundTripper
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (h *headerRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	// A RoundTripper must not modify the passed request.
+	req = req.Clone(req.Context())
+	for k, v := range h.headers {
+		req.Header[k] = append([]string(nil), v...)
+	}
+
+	return h.next.RoundTrip(req)
+}
+
+// redactHeaders returns the headers as sorted key=value strings with the
+// values of sensitive headers redacted.
+func redactHeaders(headers http.Header) []string {
+
+	var out []string
+
+	for k, values := range headers {
+		for _, v := range values {
+			if isSensitiveHeader(k) {
+				v = "<redacted>"
+			}
+			out = append(out, k+"="+v)
+		}
+	}
+	sort.Strings(out)
+
+	return out
+}
+
+// isSensitiveHeader identifies headers, such as Authorization, whose values
+// should not be logged.
+func isSensitiveHeader(name string) bool {
+	name = strings.ToLower(name)
+	for _, p := range sensitiveHeaderParts {
+		if strings.Contains(name, p) {
+			return true
+		}
+	}
+	return false
+}
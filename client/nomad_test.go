@@ -0,0 +1,153 @@
+package client
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jrasell/levant/levant/structs"
+)
+
+func TestNomad_headerRoundTripper(t *testing.T) {
+
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+		w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+
+	headers := http.Header{}
+	headers.Add("Authorization", "Bearer abc")
+	headers.Add("X-Team", "platform")
+
+	c, err := NewNomadTargetClient(&structs.ClientConfig{Addr: srv.URL, Headers: headers})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err = c.Jobs().List(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got.Get("Authorization") != "Bearer abc" || got.Get("X-Team") != "platform" {
+		t.Fatalf("expected custom headers on request, got %v", got)
+	}
+	// The headers belong to the client config, so are not added to the
+	// requests of clients created without them.
+	c, err = NewNomadClient(srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err = c.Jobs().List(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got.Get("Authorization") != "" || got.Get("X-Team") != "" {
+		t.Fatalf("expected no custom headers on request, got %v", got)
+	}
+}
+
+func TestNomad_clientContext(t *testing.T) {
+
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	nc, err := NewNomadTargetClient(&structs.ClientConfig{Addr: srv.URL, Context: ctx})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cc, err := NewConsulClient(ctx, srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Requests in flight when the context is cancelled are abandoned rather
+	// than waiting on the server.
+	start := time.Now()
+	if _, _, err = nc.Jobs().List(nil); err == nil {
+		t.Fatal("expected Nomad request to be cancelled")
+	}
+	if _, _, err = cc.KV().List("levant", nil); err == nil {
+		t.Fatal("expected Consul request to be cancelled")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("expected requests to be cancelled by the context, took %v", elapsed)
+	}
+}
+
+func TestNomad_contextRoundTripper(t *testing.T) {
+
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-r.Context().Done():
+			case <-release:
+			}
+		}
+		w.Write([]byte(`ok`))
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	root, cancelRoot := context.WithCancel(context.Background())
+	defer cancelRoot()
+
+	c := &http.Client{Transport: &contextRoundTripper{ctx: root, next: http.DefaultTransport}}
+
+	// The context of the request is honoured while the root context is
+	// still live.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/slow", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	start := time.Now()
+	if _, err = c.Do(req.WithContext(ctx)); err == nil {
+		t.Fatal("expected request to be cancelled by its own context")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("expected request to be cancelled by its own context, took %v", elapsed)
+	}
+
+	// Responses returned before the root context is done can still be read.
+	resp, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	if body, err := ioutil.ReadAll(resp.Body); err != nil || string(body) != "ok" {
+		t.Fatalf("expected body ok, got %q: %v", body, err)
+	}
+}
+
+func TestNomad_redactHeaders(t *testing.T) {
+
+	headers := http.Header{}
+	headers.Add("Authorization", "Bearer abc")
+	headers.Add("X-Api-Key", "abc")
+	headers.Add("X-Team", "platform")
+
+	expected := []string{"Authorization=<redacted>", "X-Api-Key=<redacted>", "X-Team=platform"}
+	if out := redactHeaders(headers); !reflect.DeepEqual(out, expected) {
+		t.Fatalf("expected %v, got %v", expected, out)
+	}
+}
@@ -0,0 +1,95 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jrasell/levant/helper"
+	"github.com/jrasell/levant/levant"
+	"github.com/jrasell/levant/logging"
+)
+
+// ACLPolicyApplyCommand is the command implementation that allows users to
+// render a Nomad ACL policy template and apply it.
+type ACLPolicyApplyCommand struct {
+	Meta
+}
+
+// Help provides the help information for the acl-policy-apply command.
+func (c *ACLPolicyApplyCommand) Help() string {
+	helpText := `
+Usage: levant acl-policy-apply [options] -name=<name> TEMPLATE
+
+  Render a Nomad ACL policy rules template and upsert the policy into Nomad,
+  creating or updating it. The rules are validated by Nomad when the policy
+  is applied. Variables are passed in the same way as the deploy command.
+
+Arguments:
+
+  TEMPLATE  nomad ACL policy rules template
+` + applyHelpOptions + `
+ACL Policy Options:
+
+  -description=<description>
+    The description of the ACL policy.
+
+  -name=<name>
+    The name of the ACL policy. This is required.
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis is provides a brief summary of the acl-policy-apply command.
+func (c *ACLPolicyApplyCommand) Synopsis() string {
+	return "Render and apply a Nomad ACL policy from a template"
+}
+
+// Run triggers a run of the Levant ACL policy apply functions.
+func (c *ACLPolicyApplyCommand) Run(args []string) int {
+
+	var addr, consulAddr, description, name, logLevel, logFormat string
+	var variables []string
+
+	flags := c.Meta.FlagSet("acl-policy-apply", FlagSetVars|FlagSetNomad)
+	flags.Usage = func() { c.UI.Output(c.Help()) }
+	flags.StringVar(&addr, "address", "", "")
+	flags.StringVar(&consulAddr, "consul-address", "", "")
+	flags.StringVar(&description, "description", "", "")
+	flags.StringVar(&name, "name", "", "")
+	flags.StringVar(&logLevel, "log-level", "INFO", "")
+	flags.StringVar(&logFormat, "log-format", "HUMAN", "")
+	flags.Var((*helper.FlagStringSlice)(&variables), "var-file", "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = flags.Args()
+	if len(args) != 1 {
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	if name == "" {
+		c.UI.Error(c.Help())
+		c.UI.Error("\nERROR: The -name flag must be set")
+		return 1
+	}
+
+	if err := logging.SetupLogger(logLevel, logFormat); err != nil {
+		c.UI.Error(fmt.Sprintf("Error setting up logging: %v", err))
+	}
+
+	rules, err := c.Meta.renderApplyTemplate(args[0], variables, consulAddr, addr)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("[ERROR] levant/command: %v", err))
+		return 1
+	}
+
+	if err = levant.TriggerACLPolicyApply(name, description, rules, c.Meta.clientConfig(addr, false)); err != nil {
+		c.UI.Error(fmt.Sprintf("[ERROR] levant/command: %v", err))
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,97 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jrasell/levant/levant"
+	"github.com/mitchellh/cli"
+)
+
+// allocIDLength is the length allocation IDs are shortened to within the
+// human output, matching the Nomad CLI.
+const allocIDLength = 8
+
+// groupAllocStatusOutput is the machine-readable representation of the
+// allocations of a single task group emitted once a deployment finishes.
+type groupAllocStatusOutput struct {
+	Group       string               `json:"group"`
+	Healthy     int                  `json:"healthy"`
+	Unhealthy   int                  `json:"unhealthy"`
+	Pending     int                  `json:"pending"`
+	Allocations []*allocStatusOutput `json:"allocations"`
+}
+
+type allocStatusOutput struct {
+	ID           string `json:"id"`
+	Node         string `json:"node"`
+	ClientStatus string `json:"client_status"`
+	Health       string `json:"health"`
+}
+
+// outputAllocStatus writes the allocation status of each task group to the
+// UI, either as tables or as a JSON line per group.
+func outputAllocStatus(ui cli.Ui, format string, groups []*levant.GroupAllocStatus) error {
+
+	if len(groups) == 0 {
+		return nil
+	}
+
+	if strings.ToUpper(format) == outputFormatJSON {
+		for _, g := range groups {
+			o := &groupAllocStatusOutput{
+				Group:     g.Group,
+				Healthy:   g.Healthy,
+				Unhealthy: g.Unhealthy,
+				Pending:   g.Pending,
+			}
+			for _, a := range g.Allocations {
+				o.Allocations = append(o.Allocations, &allocStatusOutput{
+					ID:           a.ID,
+					Node:         a.Node,
+					ClientStatus: a.ClientStatus,
+					Health:       a.Health,
+				})
+			}
+
+			out, err := json.Marshal(o)
+			if err != nil {
+				return err
+			}
+			ui.Output(string(out))
+		}
+		return nil
+	}
+
+	var buf strings.Builder
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "Group\tAllocation\tNode\tStatus\tHealth")
+	for _, g := range groups {
+		for _, a := range g.Allocations {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", g.Group, shortAllocID(a.ID), a.Node, a.ClientStatus, a.Health)
+		}
+	}
+
+	fmt.Fprintln(w, "\nGroup\tHealthy\tUnhealthy\tPending")
+	for _, g := range groups {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\n", g.Group, g.Healthy, g.Unhealthy, g.Pending)
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	ui.Output(strings.TrimSpace(buf.String()))
+	return nil
+}
+
+// shortAllocID shortens the allocation ID for the human output.
+func shortAllocID(id string) string {
+	if len(id) > allocIDLength {
+		return id[:allocIDLength]
+	}
+	return id
+}
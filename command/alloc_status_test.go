@@ -0,0 +1,47 @@
+package command
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/jrasell/levant/levant"
+	"github.com/mitchellh/cli"
+)
+
+func TestAllocStatus_outputAllocStatus(t *testing.T) {
+
+	groups := []*levant.GroupAllocStatus{
+		{
+			Group:   "cache",
+			Healthy: 1,
+			Allocations: []*levant.AllocStatus{
+				{ID: "d7f2c1a4-1b2c-3d4e-5f60-718293a4b5c6", Node: "node-1", ClientStatus: "running", Health: levant.AllocHealthHealthy},
+			},
+		},
+	}
+
+	ui := cli.NewMockUi()
+	if err := outputAllocStatus(ui, outputFormatHuman, groups); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := ui.OutputWriter.String()
+	for _, s := range []string{"Group", "d7f2c1a4 ", "node-1", "healthy", "Unhealthy"} {
+		if !strings.Contains(out, s) {
+			t.Fatalf("expected output to contain %q, got:\n%s", s, out)
+		}
+	}
+
+	ui = cli.NewMockUi()
+	if err := outputAllocStatus(ui, "json", groups); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var g groupAllocStatusOutput
+	if err := json.Unmarshal([]byte(strings.TrimSpace(ui.OutputWriter.String())), &g); err != nil {
+		t.Fatalf("expected JSON line, got error %v", err)
+	}
+	if g.Group != "cache" || g.Healthy != 1 || len(g.Allocations) != 1 {
+		t.Fatalf("unexpected JSON output: %+v", g)
+	}
+}
@@ -0,0 +1,85 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jrasell/levant/template"
+)
+
+// applyHelpOptions are the options shared by the commands which render and
+// apply a template to Nomad.
+const applyHelpOptions = `
+General Options:
+
+  -address=<http_address>
+    The Nomad HTTP API address including port which Levant will use to make
+    calls.
+
+  -allow-func=<name>
+    Restrict the template functions available when rendering to those listed.
+    You can repeat this flag multiple times to allow multiple functions.
+
+  -consul-address=<addr>
+    The Consul host and port to use when making Consul KeyValue lookups for
+    template rendering.
+
+  -deny-func=<name>
+    Disallow a template function when rendering, such as fileContents. You can
+    repeat this flag multiple times to deny multiple functions.
+
+  -header=<key=value>
+    Add a custom HTTP header to every Nomad API request, such as an
+    Authorization header required by a proxy in front of Nomad. You can
+    repeat this flag multiple times to add multiple headers.
+
+  -log-level=<level>
+    Specify the verbosity level of Levant's logs. Valid values include DEBUG,
+    INFO, and WARN, in decreasing order of verbosity. The default is INFO.
+
+  -log-format=<format>
+    Specify the format of Levant's logs. Valid values are HUMAN or JSON. The
+    default is HUMAN.
+
+  -remote-header=<key=value>
+    Add an HTTP header, such as Authorization, to the requests made when the
+    template or a var-file is an http(s) URL. You can repeat this flag
+    multiple times to add multiple headers.
+
+  -remote-timeout=<duration>
+    The time allowed to fetch each remote template or var-file, such as 10s.
+    The default is 30s.
+
+  -var-file=<file>
+    The variables file to render the template with. You can repeat this flag
+    multiple times to supply multiple var-files.
+
+  -explain-vars
+    Log each template variable with the source which provided its final
+    value, such as the var-file, env or flag, and the sources it overrode.
+    The variables are logged before the template is rendered.
+
+  -var-precedence=<sources>
+    A comma separated list of the variable sources to merge, lowest precedence
+    first. Valid sources are file, env and flag; env variables are read from
+    the environment with the LEVANT_VAR_ prefix removed. Sources not listed
+    are not used. [default: file,flag]
+`
+
+// renderApplyTemplate renders the template using the variables and options
+// shared with the job commands.
+func (m *Meta) renderApplyTemplate(templateFile string, variableFiles []string, consulAddr, nomadAddr string) ([]byte, error) {
+
+	renderOpts, err := m.renderOptions()
+	if err != nil {
+		return nil, err
+	}
+	renderOpts.NomadAddr = nomadAddr
+
+	tpl, err := template.RenderTemplate(templateFile, variableFiles, consulAddr, &m.flagVars, renderOpts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to render template %s: %v", templateFile, err)
+	}
+
+	return []byte(strings.TrimSpace(tpl.String())), nil
+}
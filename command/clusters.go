@@ -0,0 +1,158 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/jrasell/levant/levant/structs"
+	"github.com/mitchellh/cli"
+)
+
+// parseNomadAddrs splits the comma separated list of Nomad addresses,
+// ignoring any empty entries.
+func parseNomadAddrs(addrs string) []string {
+	var out []string
+	for _, a := range strings.Split(addrs, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// runOnClusters runs fn against each of the Nomad addresses in turn and
+// outputs a summary of the results. Failures are reported but do not stop the
+// remaining clusters unless failFast is set. The first non-zero exit code is
+// returned.
+func runOnClusters(ui cli.Ui, addrs []string, failFast bool, fn func(addr string) int) int {
+	return runInTurn(ui, "Running against Nomad cluster", "Cluster results", addrs, failFast, fn)
+}
+
+// runInTurn runs fn for each of the names in turn, outputting the heading
+// before each run and a summary of the results once all have run. Failures
+// are reported but do not stop the remaining runs unless failFast is set. The
+// first non-zero exit code is returned.
+func runInTurn(ui cli.Ui, heading, results string, names []string, failFast bool, fn func(name string) int) int {
+
+	codes := runConcurrently(ui, heading, names, failFast, 1, fn)
+
+	ui.Output(fmt.Sprintf("==> %s:", results))
+	for _, name := range names {
+		code, ok := codes[name]
+		switch {
+		case !ok:
+			ui.Output(fmt.Sprintf("    %s: skipped", name))
+		case code == 0:
+			ui.Output(fmt.Sprintf("    %s: successful", name))
+		default:
+			ui.Output(fmt.Sprintf("    %s: failed with exit code %d", name, code))
+		}
+	}
+
+	return firstExitCode(names, codes)
+}
+
+// runConcurrently runs fn for each of the names, with up to parallel runs at
+// once, outputting the heading as each run starts. Runs are started in the
+// order of the names, so a parallel of 1 runs each in turn. Once a run fails
+// with failFast set, the runs not yet started are skipped. The exit code of
+// each run is returned keyed on its name; skipped runs are not included.
+func runConcurrently(ui cli.Ui, heading string, names []string, failFast bool, parallel int,
+	fn func(name string) int) map[string]int {
+
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		failed bool
+	)
+	codes := make(map[string]int, len(names))
+	sem := make(chan struct{}, parallel)
+
+	for _, name := range names {
+		sem <- struct{}{}
+
+		mu.Lock()
+		stop := failed && failFast
+		mu.Unlock()
+		if stop {
+			<-sem
+			break
+		}
+
+		ui.Output(fmt.Sprintf("==> %s %s", heading, name))
+
+		wg.Add(1)
+		go func(name string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			code := fn(name)
+
+			mu.Lock()
+			codes[name] = code
+			failed = failed || code != 0
+			mu.Unlock()
+		}(name)
+	}
+
+	wg.Wait()
+	return codes
+}
+
+// firstExitCode returns the first non-zero exit code of the runs in the order
+// of the names, or zero if every run that ran was successful.
+func firstExitCode(names []string, codes map[string]int) int {
+	for _, name := range names {
+		if code := codes[name]; code != 0 {
+			return code
+		}
+	}
+	return 0
+}
+
+// renderNomadAddr returns the Nomad address used by template functions while
+// rendering. The job is rendered once, so when deploying to multiple clusters
+// the first cluster is used.
+func renderNomadAddr(addr string, addrs []string) string {
+	if addr == "" && len(addrs) > 0 {
+		return addrs[0]
+	}
+	return addr
+}
+
+// clusterTemplateConfig returns a copy of the template config with its own
+// copy of the rendered job, so the job can be modified while being deployed
+// to a single cluster without affecting the others.
+func clusterTemplateConfig(config *structs.TemplateConfig) (*structs.TemplateConfig, error) {
+
+	raw, err := json.Marshal(config.Job)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &nomad.Job{}
+	if err := json.Unmarshal(raw, job); err != nil {
+		return nil, err
+	}
+
+	out := *config
+	out.Job = job
+	return &out, nil
+}
+
+// clusterClientConfig returns a copy of the client config targeting the
+// passed Nomad address.
+func clusterClientConfig(config *structs.ClientConfig, addr string) *structs.ClientConfig {
+	out := *config
+	out.Addr = addr
+	return &out
+}
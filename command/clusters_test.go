@@ -0,0 +1,72 @@
+package command
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestClusters_parseNomadAddrs(t *testing.T) {
+	cases := []struct {
+		in       string
+		expected []string
+	}{
+		{"", nil},
+		{"http://a:4646", []string{"http://a:4646"}},
+		{"http://a:4646, http://b:4646,,", []string{"http://a:4646", "http://b:4646"}},
+	}
+
+	for _, tc := range cases {
+		if out := parseNomadAddrs(tc.in); !reflect.DeepEqual(out, tc.expected) {
+			t.Fatalf("expected %v for %q but got %v", tc.expected, tc.in, out)
+		}
+	}
+}
+
+func TestClusters_runOnClusters(t *testing.T) {
+	addrs := []string{"a", "b", "c"}
+	codes := map[string]int{"a": 0, "b": 2, "c": 0}
+
+	cases := []struct {
+		failFast bool
+		ran      []string
+		summary  []string
+	}{
+		{
+			failFast: false,
+			ran:      []string{"a", "b", "c"},
+			summary:  []string{"a: successful", "b: failed with exit code 2", "c: successful"},
+		},
+		{
+			failFast: true,
+			ran:      []string{"a", "b"},
+			summary:  []string{"a: successful", "b: failed with exit code 2", "c: skipped"},
+		},
+	}
+
+	for _, tc := range cases {
+		ui := cli.NewMockUi()
+		var ran []string
+
+		code := runOnClusters(ui, addrs, tc.failFast, func(addr string) int {
+			ran = append(ran, addr)
+			return codes[addr]
+		})
+
+		if code != 2 {
+			t.Fatalf("expected exit code 2 but got %d", code)
+		}
+		if !reflect.DeepEqual(ran, tc.ran) {
+			t.Fatalf("expected %v to run but got %v", tc.ran, ran)
+		}
+
+		out := ui.OutputWriter.String()
+		for _, s := range tc.summary {
+			if !strings.Contains(out, s) {
+				t.Fatalf("expected output to contain %q, got:\n%s", s, out)
+			}
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package command
+
+import (
+	"context"
+	"time"
+)
+
+// deadlineExitCode is the exit code used when the deadline of a command is
+// reached, matching that of the timeout utility.
+const deadlineExitCode = 124
+
+// startDeadline returns a context cancelled once the deadline passes, to be
+// applied to every Nomad and Consul request, and hook, run by the command.
+// The returned function releases the context and reports whether the
+// deadline was reached.
+func startDeadline(deadline time.Duration) (ctx context.Context, stop func() bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), deadline)
+
+	return ctx, func() bool {
+		reached := ctx.Err() == context.DeadlineExceeded
+		cancel()
+		return reached
+	}
+}
@@ -0,0 +1,26 @@
+package command
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeadline_startDeadline(t *testing.T) {
+
+	ctx, stop := startDeadline(time.Hour)
+	if ctx.Done() == nil {
+		t.Fatal("expected the context to carry the deadline")
+	}
+	if stop() {
+		t.Fatal("expected deadline not to be reached")
+	}
+	if ctx.Err() == nil {
+		t.Fatal("expected the context to be released")
+	}
+
+	ctx, stop = startDeadline(10 * time.Millisecond)
+	<-ctx.Done()
+	if !stop() {
+		t.Fatal("expected deadline to be reached")
+	}
+}
@@ -1,8 +1,12 @@
 package command
 
 import (
+	"flag"
 	"fmt"
+	"os"
+	"sort"
 	"strings"
+	"time"
 
 	nomad "github.com/hashicorp/nomad/api"
 	"github.com/jrasell/levant/helper"
@@ -10,6 +14,7 @@ import (
 	"github.com/jrasell/levant/levant/structs"
 	"github.com/jrasell/levant/logging"
 	"github.com/jrasell/levant/template"
+	isatty "github.com/mattn/go-isatty"
 )
 
 // DeployCommand is the command implementation that allows users to deploy a
@@ -27,30 +32,116 @@ Usage: levant deploy [options] [TEMPLATE]
   command supports passing variables individually on the command line. Multiple
   commands can be passed in the format of -var 'key=value'. Variables passed
   via the command line take precedence over the same variable declared within
-  a passed variable file.
+  a passed variable file. Templates containing multiple jobs, such as the
+  output of Nomad Pack, have each job deployed in turn.
 
 Arguments:
 
   TEMPLATE nomad job template
-    If no argument is given we look for a single *.nomad file
+    If no argument is given we look for a single *.nomad file. The template,
+    and any var-file, may also be an http(s) URL which is fetched before
+    rendering.
 
 General Options:
 
+  -accept-no-diff
+    Treat a plan with no scheduler changes as a successful no-op. If the job
+    specification still differs from the running job, for example only in
+    fields the scheduler diff does not include, the job is registered.
+
   -address=<http_address>
     The Nomad HTTP API address including port which Levant will use to make
     calls.
 
+  -alloc-status-format=<format>
+    The format of the allocation status printed once the deployment finishes.
+    Valid values are HUMAN or JSON. When JSON is used a line is written for
+    each task group. The default is HUMAN.
+
+  -allow-func=<name>
+    Restrict the template functions available when rendering to those listed.
+    You can repeat this flag multiple times to allow multiple functions.
+
   -allow-stale
     Allow stale consistency mode for requests into nomad.
 
+  -auto-approve
+    Skip the interactive confirmation prompt shown between the plan and the
+    deployment when Levant is run from a terminal. The prompt is never shown
+    when stdin is not a terminal.
+
+  -batch-timeout=<duration>
+    The maximum time to wait for the allocations of a batch job to complete,
+    such as 10m. Batch deployments succeed once all allocations complete with
+    no task exiting nonzero. The default of 0 waits indefinitely.
+
+  -canary=<num>
+    Override the canary count of the update stanza of each task group in the
+    rendered job. A value of 0 disables canaries for the deployment.
+
   -canary-auto-promote=<seconds>
     The time in seconds, after which Levant will auto-promote a canary job
     if all canaries within the deployment are healthy.
 
+  -cancel-on-interrupt
+    Fail the deployment in Nomad when Levant receives an interrupt or
+    terminate signal while watching it. Without this flag the deployment
+    continues and Levant exits with status 130, logging the deployment status
+    and the levant watch command to resume watching.
+
   -consul-address=<addr>
     The Consul host and port to use when making Consul KeyValue lookups for
     template rendering.
 
+  -count-from-running=<bool>
+    Choose whether the task group counts come from the running job, when
+    true, or from the template, when false, for this deployment. When true
+    the counts of the running job are copied into the rendered job before the
+    plan, so the plan reflects the counts deployed. False is the same as
+    -force-count. When not set the counts of the running job are used for the
+    deployment but not the plan.
+
+  -deadline=<duration>
+    The maximum time, such as 30m, allowed for the whole command including
+    rendering, planning, deploying and watching the job. Once reached, any
+    requests to Nomad and Consul and any running hook are cancelled and
+    Levant exits with a status 124. By default there is no deadline.
+
+  -deny-func=<name>
+    Disallow a template function when rendering, such as fileContents. You can
+    repeat this flag multiple times to deny multiple functions.
+
+  -deploy-lock
+    Acquire a Consul session lock, keyed on the job ID, before running the
+    plan and deployment and release it once finished. This prevents
+    concurrent runs of Levant from deploying the same job at the same time.
+    Levant exits 1 if the lock is not acquired within the timeout.
+
+  -deploy-lock-prefix=<prefix>
+    The Consul KV prefix under which the deploy lock of the job is stored.
+    The default is levant/deploy-lock.
+
+  -deploy-lock-timeout=<duration>
+    The maximum time to wait for the deploy lock to be released by another
+    run of Levant. The default is 15s.
+
+  -diff-context
+    Include the unchanged fields of edited objects in the plan output, so
+    the changes can be reviewed alongside the surrounding configuration.
+    By default only the changed fields are shown.
+
+  -dry-run
+    Render the job, validate it with Nomad and run the plan without
+    registering it, reporting the validation and plan results together. The
+    exit code is 1 if validation fails or the plan fails, for example due to
+    -fail-on-destructive, and follows -ignore-no-changes when there are no
+    changes. It can not be used with the -plan-only flag.
+
+  -fail-on-placement-failure
+    Fail before the job is registered if the Nomad plan indicates any task
+    group can not be placed, such as due to constraints or exhausted
+    resources. The reasons are logged for each group regardless.
+
   -force
     Execute deployment even though there were no changes.
 
@@ -62,20 +153,93 @@ General Options:
     Use the taskgroup count from the Nomad jobfile instead of the count that
     is currently set in a running job.
 
+  -fail-on-destructive
+    Fail the deploy if the Nomad plan indicates any of the changes will force
+    allocations to be destroyed and recreated. In-place updates are allowed.
+
+  -fail-fast
+    Used in conjunction with -nomad-addrs or -targets to stop at the first
+    cluster or target which fails rather than continuing with the remaining
+    ones. Targets already being deployed with -parallel finish. When the
+    template contains multiple jobs, this also stops at the first job which
+    fails.
+
+  -fail-on-hook-error
+    Exit with a status 1 when the -post-deploy-hook fails, even though the
+    deployment was successful.
+
+  -format=<format>
+    The format used to output the changes identified by the plan. Valid
+    values are log, which logs a line for each changed field, tree, which
+    outputs an indented tree of the changed groups, tasks, objects and fields,
+    and grouped, which outputs the changes in sections by impact: destructive,
+    in-place, additions, deletions and other changes. The default is log.
+
+  -hcl-version=<version>
+    The HCL version used to parse the rendered job, either 1 or 2. HCL2 jobs
+    are parsed by the Nomad API and require Nomad 1.0 or later; use 1 for
+    legacy job specifications. By default, HCL2 is used when the cluster is
+    running Nomad 1.0 or later and HCL1 otherwise.
+
+  -healthy-grace=<duration>
+    Once the deployment is successful, continue watching its allocations
+    for the duration, such as 2m, and fail the deployment if any of them
+    fail, become unhealthy or restart. The default of 0 does not watch the
+    allocations once the deployment is successful.
+
+  -ignore-count-changes
+    Ignore changes to the count of task groups within the plan, such as
+    those caused by autoscaling, so a plan where only counts have drifted
+    reports no changes.
+
+  -ignore-field=<objName:fieldName>
+    Ignore changes to the named field within the plan, such as
+    Job:Meta[deployed_at] for a timestamp which changes on every run. Ignored changes are not logged
+    or counted; if all changes are ignored the plan reports no changes. You can
+    repeat this flag multiple times to ignore multiple fields.
+
   -ignore-no-changes
     By default if no changes are detected when running a deployment Levant will
     exit with a status 1 to indicate a deployment didn't happen. This behaviour
     can be changed using this flag so that Levant will exit cleanly ensuring CD
     pipelines don't fail when no changes are detected.
 
+  -image=<group.task=image>
+    Override the Docker image of the named task within the rendered job, such
+    as cache.redis=redis:4.0. The task must exist and have an image config
+    field. You can repeat this flag multiple times to override multiple
+    tasks.
+
+  -keep-rendered[=<file>]
+    Write the rendered job, as submitted to Nomad, to disk when the deployment
+    fails. If no file is given a temporary file is used and its location is
+    logged.
+
+  -keep-rendered-always
+    Used in conjunction with -keep-rendered to write the rendered job on every
+    deployment, rather than only on failure.
+
   -vault
     This flag makes levant load the vault token from the current ENV.
     It can not be used at the same time than -vault-token=<vault-token> flag
 
+  -vault-renew
+    Used in conjunction with -vault or -vault-token to periodically renew the
+    Vault token through the Vault API, at the address set by VAULT_ADDR, for
+    the duration of the deployment. The VAULT_CACERT, VAULT_CAPATH,
+    VAULT_CLIENT_CERT, VAULT_CLIENT_KEY, VAULT_TLS_SERVER_NAME,
+    VAULT_SKIP_VERIFY and VAULT_NAMESPACE variables are also used. Renewal
+    failures are logged as warnings and retried until the token expires.
+
   -vault-token=<vault-token>
     The vault token used to deploy the application to nomad with vault support
     This flag can not be used at the same time than -vault flag
 
+  -header=<key=value>
+    Add a custom HTTP header to every Nomad API request, such as an
+    Authorization header required by a proxy in front of Nomad. You can
+    repeat this flag multiple times to add multiple headers.
+
   -log-level=<level>
     Specify the verbosity level of Levant's logs. Valid values include DEBUG,
     INFO, and WARN, in decreasing order of verbosity. The default is INFO.
@@ -84,10 +248,147 @@ General Options:
     Specify the format of Levant's logs. Valid values are HUMAN or JSON. The
     default is HUMAN.
 
+  -max-plan-depth=<num>
+    The maximum depth of nested objects walked when logging the changes of
+    the plan. Deeper changes are not logged and a warning is shown. The
+    default is 32.
+
+  -mark-stable
+    Mark the job version created by a successful deployment as stable, once
+    any post-deploy hook has run, so it is a target of auto-revert and the
+    revert command. If the post-deploy hook fails the version is marked
+    unstable instead.
+
+  -mark-unstable
+    Mark the job version created by a successful deployment as unstable so
+    it is not a target of auto-revert or the revert command.
+
+  -message=<message>
+    A note, such as the CI build which triggered the deployment, attached to
+    the registered job version as a version tag so it is shown within the job
+    history. Nomad clusters older than 1.9 do not support version tags and the
+    message is skipped with a warning.
+
+  -no-changes-exit-code=<code>
+    The exit code to use when the plan does not detect any changes, taking
+    precedence over -ignore-no-changes. The default is 1.
+
+  -nomad-addrs=<addrs>
+    A comma separated list of Nomad HTTP API addresses. The job is rendered
+    once and then planned and deployed against each cluster in turn, with a
+    summary of the results. It can not be used with the -address flag.
+
+  -on-failure-hook=<command>
+    A command run using the shell after a failed deployment, with the same
+    environment as the -post-deploy-hook. A failure of the hook is logged.
+
+  -parallel=<num>
+    Used in conjunction with -targets to deploy to up to this many targets
+    at once. Requires -auto-approve unless running a -dry-run or -plan-only,
+    and the logs of the targets are interleaved, each line carrying the name
+    of its target. The default is 1, which deploys to each target in turn.
+
+  -plan-optional
+    Skip the plan, with a warning, and continue to register the job if the
+    Nomad plan endpoint is restricted by ACLs or a proxy, or is not
+    supported. Other plan errors still fail the deployment unless -force is
+    used. It can not be used with -dry-run or -plan-only.
+
+  -plan-only
+    Render the job and run the plan without deploying it, using the same
+    flags as the deployment. The exit code is 0 when there are no changes, 2
+    when there are changes and 1 on error. The -no-changes-exit-code flag can
+    be used to override the no changes exit code.
+
+  -plan-diff-against-file=<file>
+    Write a unified diff between the specification of the running job and
+    the rendered job, as canonical JSON, to the file before the plan is run.
+    A job which is not registered is diffed against an empty specification.
+    Fields matching the -redact patterns are redacted.
+
+  -post-deploy-hook=<command>
+    A command run using the shell after a successful deployment, such as a
+    cache warmup or notification. The environment includes LEVANT_JOB_ID,
+    LEVANT_NOMAD_ADDR, LEVANT_DEPLOYMENT_ID and LEVANT_DEPLOY_STATUS. A
+    failure is logged but does not change the exit code unless the
+    -fail-on-hook-error flag is set.
+
+  -pre-deploy-hook=<command>
+    A command run using the shell after a successful plan, and approval, but
+    before the job is registered, such as a smoke test or approval script.
+    The deployment is aborted if the command exits nonzero. The environment
+    includes LEVANT_JOB_ID, LEVANT_NOMAD_ADDR and LEVANT_PLAN_CHANGES.
+
+  -priority=<num>
+    Override the priority of the rendered job. Valid values are between 1 and
+    100.
+
+  -readiness-http=<group.task=url>
+    Once the allocations of the job are placed, poll the URL for the named
+    task in each running allocation until it returns a 2xx status before
+    reporting success, for tasks such as raw exec services and sidecars. The
+    URL may use ${NOMAD_ADDR_<label>}, ${NOMAD_IP_<label>} and
+    ${NOMAD_PORT_<label>}, resolved from the network of the allocation. You
+    can repeat this flag multiple times to check multiple tasks.
+
+  -readiness-timeout=<duration>
+    The maximum time to wait for the -readiness-http URLs to return a 2xx
+    status before the deployment fails. The default is 5m.
+
+  -redact=<pattern>
+    Replace the old and new values of changed fields matching the glob
+    pattern, such as *_PASSWORD, with *** in the plan output while still
+    showing that the field changed. Patterns are matched case insensitively
+    against the field name, map key and objName:fieldName. Matching fields
+    are also redacted from the job logged by -show-job. You can repeat this
+    flag multiple times to redact multiple patterns.
+
+  -remote-header=<key=value>
+    Add an HTTP header, such as Authorization, to the requests made when the
+    template or a var-file is an http(s) URL. You can repeat this flag
+    multiple times to add multiple headers.
+
+  -remote-timeout=<duration>
+    The time allowed to fetch each remote template or var-file, such as 10s.
+    The default is 30s.
+
+  -show-job
+    Log the rendered job as JSON before the plan is run, so the final job
+    specification is visible without a separate render step. Fields matching
+    the -redact patterns, and the Vault token, are redacted.
+
+  -skip-if-image-unchanged[=<group.task>]
+    Skip the deployment, exiting cleanly, if the images of the rendered job
+    are the same as those of the running job. If a group.task name is given
+    only the image of that task is compared. Images are compared as written,
+    so referencing images by digest is recommended.
+
+  -system-timeout=<duration>
+    The maximum time to wait for a system job to be running on all eligible
+    nodes, such as 5m. The default of 0 waits indefinitely.
+
+  -targets=<file>
+    A YAML or JSON file listing the targets to deploy to, each with a name
+    and any of an address, region and namespace. The job is rendered once
+    and then planned and deployed against each target, with a table of the
+    status of each target. It can not be used with the -address or
+    -nomad-addrs flag.
+
   -var-file=<file>
     Used in conjunction with the -job-file will deploy a templated job to your
     Nomad cluster. You can repeat this flag multiple times to supply multiple var-files.
     [default: levant.(json|yaml|yml|tf)]
+
+  -explain-vars
+    Log each template variable with the source which provided its final
+    value, such as the var-file, env or flag, and the sources it overrode.
+    The variables are logged before the template is rendered.
+
+  -var-precedence=<sources>
+    A comma separated list of the variable sources to merge, lowest precedence
+    first. Valid sources are file, env and flag; env variables are read from
+    the environment with the LEVANT_VAR_ prefix removed. Sources not listed
+    are not used. [default: file,flag]
 `
 	return strings.TrimSpace(helpText)
 }
@@ -98,10 +399,17 @@ func (c *DeployCommand) Synopsis() string {
 }
 
 // Run triggers a run of the Levant template and deploy functions.
-func (c *DeployCommand) Run(args []string) int {
+func (c *DeployCommand) Run(args []string) (exitCode int) {
 
 	var err error
 	var level, format string
+	var canary, hclVersion, noChangesExitCode, parallel int
+	var countFromRunning, failFast, deployLock, markStable, markUnstable bool
+	var deployLockPrefix string
+	var deadline, deployLockTimeout time.Duration
+	var opts deployOptions
+	var nomadAddrs, targetsFile string
+	var keepRendered, skipIfImageUnchanged helper.FlagOptionalString
 
 	config := &levant.DeployConfig{
 		Client:   &structs.ClientConfig{},
@@ -110,41 +418,200 @@ func (c *DeployCommand) Run(args []string) int {
 		Template: &structs.TemplateConfig{},
 	}
 
-	flags := c.Meta.FlagSet("deploy", FlagSetVars)
+	flags := c.Meta.FlagSet("deploy", FlagSetVars|FlagSetNomad)
 	flags.Usage = func() { c.UI.Output(c.Help()) }
 
+	flags.BoolVar(&config.Plan.AcceptNoDiff, "accept-no-diff", false, "")
 	flags.StringVar(&config.Client.Addr, "address", "", "")
+	flags.StringVar(&opts.allocStatusFormat, "alloc-status-format", outputFormatHuman, "")
 	flags.BoolVar(&config.Client.AllowStale, "allow-stale", false, "")
+	flags.BoolVar(&opts.autoApprove, "auto-approve", false, "")
+	flags.DurationVar(&config.Deploy.BatchTimeout, "batch-timeout", 0, "")
+	flags.IntVar(&canary, "canary", 0, "")
 	flags.IntVar(&config.Deploy.Canary, "canary-auto-promote", 0, "")
+	flags.BoolVar(&config.Deploy.CancelOnInterrupt, "cancel-on-interrupt", false, "")
 	flags.StringVar(&config.Client.ConsulAddr, "consul-address", "", "")
+	flags.BoolVar(&countFromRunning, "count-from-running", false, "")
+	flags.DurationVar(&deadline, "deadline", 0, "")
+	flags.BoolVar(&deployLock, "deploy-lock", false, "")
+	flags.StringVar(&deployLockPrefix, "deploy-lock-prefix", levant.DefaultDeployLockPrefix, "")
+	flags.DurationVar(&deployLockTimeout, "deploy-lock-timeout", 15*time.Second, "")
+	flags.BoolVar(&config.Plan.DiffContext, "diff-context", false, "")
+	flags.BoolVar(&opts.dryRun, "dry-run", false, "")
 	flags.BoolVar(&config.Deploy.Force, "force", false, "")
 	flags.BoolVar(&config.Deploy.ForceBatch, "force-batch", false, "")
 	flags.BoolVar(&config.Deploy.ForceCount, "force-count", false, "")
+	flags.BoolVar(&config.Plan.FailOnDestructive, "fail-on-destructive", false, "")
+	flags.BoolVar(&config.Plan.FailOnPlacementFailure, "fail-on-placement-failure", false, "")
+	flags.BoolVar(&failFast, "fail-fast", false, "")
+	flags.BoolVar(&opts.failOnHookError, "fail-on-hook-error", false, "")
+	flags.StringVar(&config.Plan.Format, "format", structs.PlanFormatLog, "")
+	flags.IntVar(&hclVersion, "hcl-version", template.HCLVersionAuto, "")
+	flags.DurationVar(&config.Deploy.HealthyGrace, "healthy-grace", 0, "")
 	flags.BoolVar(&config.Plan.IgnoreNoChanges, "ignore-no-changes", false, "")
+	flags.BoolVar(&config.Plan.IgnoreCountChanges, "ignore-count-changes", false, "")
+	flags.Var((*helper.FlagStringSlice)(&config.Plan.IgnoreFields), "ignore-field", "")
+	flags.IntVar(&config.Plan.MaxPlanDepth, "max-plan-depth", levant.DefaultMaxPlanDepth, "")
+	flags.BoolVar(&markStable, "mark-stable", false, "")
+	flags.BoolVar(&markUnstable, "mark-unstable", false, "")
+	flags.StringVar(&config.Deploy.Message, "message", "", "")
+	flags.IntVar(&noChangesExitCode, "no-changes-exit-code", 1, "")
+	flags.StringVar(&nomadAddrs, "nomad-addrs", "", "")
+	flags.IntVar(&parallel, "parallel", 1, "")
+	flags.Var(&keepRendered, "keep-rendered", "")
+	flags.BoolVar(&config.Deploy.KeepRenderedAlways, "keep-rendered-always", false, "")
 	flags.StringVar(&level, "log-level", "INFO", "")
+	flags.BoolVar(&opts.planOnly, "plan-only", false, "")
+	flags.BoolVar(&config.Plan.Optional, "plan-optional", false, "")
+	flags.StringVar(&opts.onFailureHook, "on-failure-hook", "", "")
+	flags.StringVar(&opts.postDeployHook, "post-deploy-hook", "", "")
+	flags.StringVar(&opts.preDeployHook, "pre-deploy-hook", "", "")
+	flags.Var((*helper.Flag)(&config.Template.Images), "image", "")
+	flags.StringVar(&config.Plan.SpecDiffFile, "plan-diff-against-file", "", "")
+	flags.IntVar(&config.Template.Priority, "priority", 0, "")
+	flags.Var((*helper.FlagStringSlice)(&config.Plan.Redact), "redact", "")
+	flags.Var((*helper.Flag)(&config.Deploy.Readiness), "readiness-http", "")
+	flags.DurationVar(&config.Deploy.ReadinessTimeout, "readiness-timeout", 5*time.Minute, "")
+	flags.DurationVar(&config.Deploy.SystemTimeout, "system-timeout", 0, "")
+	flags.StringVar(&targetsFile, "targets", "", "")
 	flags.StringVar(&format, "log-format", "HUMAN", "")
 	flags.StringVar(&config.Deploy.VaultToken, "vault-token", "", "")
+	flags.BoolVar(&config.Plan.ShowJob, "show-job", false, "")
+	flags.Var(&skipIfImageUnchanged, "skip-if-image-unchanged", "")
 	flags.BoolVar(&config.Deploy.EnvVault, "vault", false, "")
+	flags.BoolVar(&config.Deploy.VaultRenew, "vault-renew", false, "")
 
 	flags.Var((*helper.FlagStringSlice)(&config.Template.VariableFiles), "var-file", "")
 
 	if err = flags.Parse(args); err != nil {
 		return 1
 	}
+	config.Client.Headers = c.Meta.nomadHeaders
+
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "canary":
+			config.Template.Canary = &canary
+		case "count-from-running":
+			config.Plan.CountFromRunning = countFromRunning
+			if !countFromRunning {
+				config.Deploy.ForceCount = true
+			}
+		case "no-changes-exit-code":
+			config.Plan.NoChangesExitCode = &noChangesExitCode
+		}
+	})
 
 	args = flags.Args()
 
+	config.Deploy.KeepRendered = keepRendered.Enabled
+	config.Deploy.KeepRenderedPath = keepRendered.Value
+	opts.skipIfImageUnchanged = skipIfImageUnchanged
+
+	if err = validateNoChangesExitCode(config.Plan); err != nil {
+		c.UI.Error(fmt.Sprintf("[ERROR] levant/command: %v", err))
+		return 1
+	}
+
+	if err = validatePlanFormat(config.Plan.Format); err != nil {
+		c.UI.Error(fmt.Sprintf("[ERROR] levant/command: %v", err))
+		return 1
+	}
+
+	if err = validateRedactPatterns(config.Plan.Redact); err != nil {
+		c.UI.Error(fmt.Sprintf("[ERROR] levant/command: %v", err))
+		return 1
+	}
+
+	if config.Plan.CountFromRunning && config.Deploy.ForceCount {
+		c.UI.Error(c.Help())
+		c.UI.Error("\nERROR: Can not use -count-from-running=true and -force-count flag at the same time")
+		return 1
+	}
+
+	if opts.dryRun && opts.planOnly {
+		c.UI.Error(c.Help())
+		c.UI.Error("\nERROR: Can not use -dry-run and -plan-only flag at the same time")
+		return 1
+	}
+
+	if config.Plan.Optional && (opts.dryRun || opts.planOnly) {
+		c.UI.Error(c.Help())
+		c.UI.Error("\nERROR: Can not use -plan-optional with the -dry-run or -plan-only flag")
+		return 1
+	}
+
+	addrs := parseNomadAddrs(nomadAddrs)
+	if len(addrs) > 0 && config.Client.Addr != "" {
+		c.UI.Error(c.Help())
+		c.UI.Error("\nERROR: Can not use -address and -nomad-addrs flag at the same time")
+		return 1
+	}
+
+	if targetsFile != "" && (len(addrs) > 0 || config.Client.Addr != "") {
+		c.UI.Error(c.Help())
+		c.UI.Error("\nERROR: Can not use -targets with the -address or -nomad-addrs flag")
+		return 1
+	}
+
+	if parallel < 1 || (parallel > 1 && targetsFile == "") {
+		c.UI.Error(c.Help())
+		c.UI.Error("\nERROR: The -parallel flag must be at least 1 and can only be above 1 with -targets")
+		return 1
+	}
+
+	// The plans of the targets can not be approved interactively when the
+	// targets are deployed concurrently.
+	if parallel > 1 && !opts.autoApprove && !opts.dryRun && !opts.planOnly {
+		c.UI.Error(c.Help())
+		c.UI.Error("\nERROR: Can not use -parallel without the -auto-approve, -dry-run or -plan-only flag")
+		return 1
+	}
+
+	if markStable && markUnstable {
+		c.UI.Error(c.Help())
+		c.UI.Error("\nERROR: Can not use -mark-stable and -mark-unstable flag at the same time")
+		return 1
+	}
+	if markStable || markUnstable {
+		config.Deploy.MarkStable = &markStable
+	}
+
 	if config.Deploy.EnvVault == true && config.Deploy.VaultToken != "" {
 		c.UI.Error(c.Help())
 		c.UI.Error("\nERROR: Can not used -vault and -vault-token flag at the same time")
 		return 1
 	}
 
+	if config.Deploy.VaultRenew && !config.Deploy.EnvVault && config.Deploy.VaultToken == "" {
+		c.UI.Error(c.Help())
+		c.UI.Error("\nERROR: Can not use -vault-renew without the -vault or -vault-token flag")
+		return 1
+	}
+
+	if err = validateOutputFormat(opts.allocStatusFormat); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
 	if err = logging.SetupLogger(level, format); err != nil {
 		c.UI.Error(err.Error())
 		return 1
 	}
 
+	// Bound the whole command, from rendering through to watching the
+	// deployment, by the deadline.
+	if deadline > 0 {
+		ctx, stop := startDeadline(deadline)
+		config.Client.Context = ctx
+		defer func() {
+			if stop() {
+				c.UI.Error(fmt.Sprintf("[ERROR] levant/command: deadline of %v reached", deadline))
+				exitCode = deadlineExitCode
+			}
+		}()
+	}
+
 	if len(args) == 1 {
 		config.Template.TemplateFile = args[0]
 	} else if len(args) == 0 {
@@ -158,48 +625,240 @@ func (c *DeployCommand) Run(args []string) int {
 		return 1
 	}
 
-	config.Template.Job, err = template.RenderJob(config.Template.TemplateFile,
-		config.Template.VariableFiles, config.Client.ConsulAddr, &c.Meta.flagVars)
+	renderOpts, err := c.Meta.renderOptions()
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("[ERROR] levant/command: %v", err))
 		return 1
 	}
-
-	if config.Deploy.Canary > 0 {
-		if err = c.checkCanaryAutoPromote(config.Template.Job, config.Deploy.Canary); err != nil {
+	renderOpts.Context = config.Client.Context
+	var targets []*deployTarget
+	if targetsFile != "" {
+		if targets, err = loadDeployTargets(targetsFile); err != nil {
 			c.UI.Error(fmt.Sprintf("[ERROR] levant/command: %v", err))
 			return 1
 		}
+		addrs = targetAddrs(targets)
+	}
+	renderOpts.NomadAddr = renderNomadAddr(config.Client.Addr, addrs)
+	renderOpts.NomadRegion = config.Client.Region
+	renderOpts.NomadNamespace = config.Client.Namespace
+	if len(targets) > 0 {
+		targets[0].applyRenderTarget(renderOpts)
+	}
+	renderOpts.HCLVersion = hclVersion
+
+	jobs, err := template.RenderJobs(config.Template.TemplateFile,
+		config.Template.VariableFiles, config.Client.ConsulAddr, &c.Meta.flagVars, renderOpts)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("[ERROR] levant/command: %v", err))
+		return 1
 	}
 
-	if config.Deploy.ForceBatch {
-		if err = c.checkForceBatch(config.Template.Job, config.Deploy.ForceBatch); err != nil {
+	// deployJob checks and deploys a single rendered job, to each cluster in
+	// turn when deploying to multiple clusters.
+	deployJob := func(config *levant.DeployConfig) int {
+		if err := applyJobOverrides(config.Template); err != nil {
 			c.UI.Error(fmt.Sprintf("[ERROR] levant/command: %v", err))
 			return 1
 		}
+
+		if config.Deploy.Canary > 0 {
+			if err := c.checkCanaryAutoPromote(config.Template.Job, config.Deploy.Canary); err != nil {
+				c.UI.Error(fmt.Sprintf("[ERROR] levant/command: %v", err))
+				return 1
+			}
+		}
+
+		if config.Deploy.ForceBatch {
+			if err := c.checkForceBatch(config.Template.Job, config.Deploy.ForceBatch); err != nil {
+				c.UI.Error(fmt.Sprintf("[ERROR] levant/command: %v", err))
+				return 1
+			}
+		}
+
+		if len(config.Deploy.Readiness) > 0 {
+			if err := checkReadiness(config.Template.Job, config.Deploy.Readiness); err != nil {
+				c.UI.Error(fmt.Sprintf("[ERROR] levant/command: %v", err))
+				return 1
+			}
+		}
+
+		if deployLock {
+			lock, err := levant.AcquireDeployLock(config.Client, deployLockPrefix,
+				*config.Template.Job.ID, deployLockTimeout)
+			if err != nil {
+				c.UI.Error(fmt.Sprintf("[ERROR] levant/command: %v", err))
+				return 1
+			}
+			defer lock.Release()
+		}
+
+		if len(targets) > 0 {
+			return c.deployTargets(config, opts, targets, failFast, parallel)
+		}
+		if len(addrs) == 0 {
+			return c.deploy(config, opts)
+		}
+
+		// Deploy the rendered job to each cluster in turn, giving each its own
+		// copy of the job as the deployment updates it.
+		return runOnClusters(c.UI, addrs, failFast, func(addr string) int {
+			tmplConfig, err := clusterTemplateConfig(config.Template)
+			if err != nil {
+				c.UI.Error(fmt.Sprintf("[ERROR] levant/command: %v", err))
+				return 1
+			}
+
+			deploy := *config.Deploy
+			return c.deploy(&levant.DeployConfig{
+				Client:   clusterClientConfig(config.Client, addr),
+				Deploy:   &deploy,
+				Plan:     config.Plan,
+				Template: tmplConfig,
+			}, opts)
+		})
 	}
 
-	if !config.Deploy.Force {
-		p := levant.PlanConfig{
+	if len(jobs) == 1 {
+		config.Template.Job = jobs[0]
+		return deployJob(config)
+	}
+
+	// Templates holding multiple jobs, such as the output of Nomad Pack, have
+	// each job planned and deployed in turn with its own copy of the config.
+	ids := make([]string, len(jobs))
+	byID := make(map[string]*nomad.Job, len(jobs))
+	for i, job := range jobs {
+		ids[i] = *job.ID
+		byID[*job.ID] = job
+	}
+
+	return runInTurn(c.UI, "Deploying job", "Job results", ids, failFast, func(id string) int {
+		tmplConfig := *config.Template
+		tmplConfig.Job = byID[id]
+		deploy := *config.Deploy
+
+		return deployJob(&levant.DeployConfig{
 			Client:   config.Client,
+			Deploy:   &deploy,
 			Plan:     config.Plan,
-			Template: config.Template,
+			Template: &tmplConfig,
+			Logger:   config.Logger,
+		})
+	})
+}
+
+// deployOptions are the deploy command flags which control how far the
+// deployment of each cluster proceeds.
+type deployOptions struct {
+	autoApprove       bool
+	dryRun            bool
+	planOnly          bool
+	preDeployHook     string
+	postDeployHook    string
+	onFailureHook     string
+	failOnHookError   bool
+	allocStatusFormat string
+
+	skipIfImageUnchanged helper.FlagOptionalString
+}
+
+// deploy runs the plan, when not forced, followed by the deployment of the
+// rendered job against a single Nomad cluster and returns the exit code. The
+// job is not registered when running a dry run or plan only.
+func (c *DeployCommand) deploy(config *levant.DeployConfig, opts deployOptions) int {
+
+	p := levant.PlanConfig{
+		Client:   config.Client,
+		Plan:     config.Plan,
+		Template: config.Template,
+		Logger:   config.Logger,
+	}
+
+	switch {
+	case opts.dryRun:
+		if err := levant.TriggerDryRun(&p); err != nil {
+			return planErrorExitCode(err, p.Plan)
 		}
+		return 0
+	case opts.planOnly:
+		return planOnlyExitCode(levant.TriggerPlan(&p), p.Plan)
+	}
 
-		planSuccess, changes := levant.TriggerPlan(&p)
-		if !planSuccess {
+	if opts.skipIfImageUnchanged.Enabled {
+		unchanged, err := levant.TriggerImageCheck(config, opts.skipIfImageUnchanged.Value)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("[ERROR] levant/command: %v", err))
 			return 1
-		} else if !changes && p.Plan.IgnoreNoChanges {
+		}
+		if unchanged {
 			return 0
 		}
 	}
 
-	success := levant.TriggerDeployment(config, nil)
-	if !success {
+	if !config.Deploy.Force {
+		if err := levant.TriggerPlan(&p); err != nil {
+			return planErrorExitCode(err, p.Plan)
+		}
+
+		if !opts.autoApprove && !c.confirmDeploy() {
+			c.UI.Output("Deployment cancelled")
+			return 1
+		}
+	}
+
+	hook := &levant.DeployHook{
+		Context:   config.Client.Context,
+		JobID:     *config.Template.Job.ID,
+		NomadAddr: config.Client.Addr,
+	}
+	if !config.Deploy.Force {
+		hook.PlanChanges = &p.ChangeCount
+	}
+
+	if opts.preDeployHook != "" {
+		hook.Command = opts.preDeployHook
+		if err := levant.RunPreDeployHook(hook); err != nil {
+			return 1
+		}
+	}
+
+	err := levant.TriggerDeployment(config, nil)
+
+	// Report the final state of the allocations once the job is registered;
+	// failing to query them is logged but does not affect the result of the
+	// deployment.
+	if config.EvalID != "" {
+		c.outputAllocStatus(config, opts.allocStatusFormat)
+	}
+
+	hook.DeploymentID = config.DeploymentID
+	hook.Status = levant.DeployStatus(err)
+
+	var hookErr error
+	switch {
+	case err == nil && opts.postDeployHook != "":
+		hook.Command = opts.postDeployHook
+		hookErr = levant.RunPostDeployHook(hook)
+	case err != nil && opts.onFailureHook != "":
+		hook.Command = opts.onFailureHook
+		levant.RunOnFailureHook(hook)
+	}
+
+	// A failed post-deploy hook, such as a smoke test, means the version
+	// should not be a revert target so it is marked unstable.
+	if err == nil && config.Deploy.MarkStable != nil {
+		stable := *config.Deploy.MarkStable && hookErr == nil
+		if markErr := levant.MarkJobStability(config, stable); markErr != nil {
+			return 1
+		}
+	}
+
+	if hookErr != nil && opts.failOnHookError {
 		return 1
 	}
 
-	return 0
+	return deployErrorExitCode(err)
 }
 
 func (c *DeployCommand) checkCanaryAutoPromote(job *nomad.Job, canaryAutoPromote int) error {
@@ -230,3 +889,63 @@ func (c *DeployCommand) checkForceBatch(job *nomad.Job, forceBatch bool) error {
 
 	return fmt.Errorf("force-batch passed but job is not periodic")
 }
+
+// checkReadiness checks that each task named, in the form group.task, within
+// the readiness config exists within the job and that the job runs long lived
+// allocations whose readiness can be polled.
+func checkReadiness(job *nomad.Job, readiness map[string]string) error {
+
+	if job.Type != nil && *job.Type == nomad.JobTypeBatch {
+		return fmt.Errorf("readiness-http passed but job %s is a batch job", *job.ID)
+	}
+
+	var names []string
+	for name := range readiness {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if findGroupTask(job, name) == nil {
+			return fmt.Errorf("unable to check readiness of %s as no task matches the group.task name", name)
+		}
+	}
+	return nil
+}
+
+// confirmDeploy asks the operator to approve the planned changes before the job
+// is registered. Approval is assumed when stdin is not a terminal so that
+// non-interactive pipelines are not blocked.
+func (c *DeployCommand) confirmDeploy() bool {
+
+	if !isatty.IsTerminal(os.Stdin.Fd()) && !isatty.IsCygwinTerminal(os.Stdin.Fd()) {
+		return true
+	}
+
+	answer, err := c.UI.Ask("Apply these changes? [y/N]")
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("[ERROR] levant/command: %v", err))
+		return false
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+// outputAllocStatus writes the status of the allocations of the deployment,
+// or of the latest version of the job when it does not use deployments.
+func (c *DeployCommand) outputAllocStatus(config *levant.DeployConfig, format string) {
+
+	groups, err := levant.TriggerAllocStatus(*config.Template.Job.ID, config.DeploymentID, config.Client)
+	if err != nil {
+		return
+	}
+
+	if err = outputAllocStatus(c.UI, format, groups); err != nil {
+		c.UI.Error(fmt.Sprintf("[ERROR] levant/command: %v", err))
+	}
+}
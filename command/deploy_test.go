@@ -3,6 +3,7 @@ package command
 import (
 	"testing"
 
+	nomad "github.com/hashicorp/nomad/api"
 	"github.com/jrasell/levant/template"
 )
 
@@ -30,7 +31,7 @@ func TestDeploy_checkCanaryAutoPromote(t *testing.T) {
 	}
 
 	for i, c := range cases {
-		job, err := template.RenderJob(c.File, []string{}, "", &fVars)
+		job, err := template.RenderJob(c.File, []string{}, "", &fVars, nil)
 		if err != nil {
 			t.Fatalf("case %d failed: %v", i, err)
 		}
@@ -61,7 +62,7 @@ func TestDeploy_checkForceBatch(t *testing.T) {
 	}
 
 	for i, c := range cases {
-		job, err := template.RenderJob(c.File, []string{}, "", &fVars)
+		job, err := template.RenderJob(c.File, []string{}, "", &fVars, nil)
 		if err != nil {
 			t.Fatalf("case %d failed: %v", i, err)
 		}
@@ -72,3 +73,26 @@ func TestDeploy_checkForceBatch(t *testing.T) {
 		}
 	}
 }
+
+func TestDeploy_checkReadiness(t *testing.T) {
+
+	job := &nomad.Job{
+		ID:   stringToPtr("example"),
+		Type: stringToPtr(nomad.JobTypeService),
+		TaskGroups: []*nomad.TaskGroup{
+			{Name: stringToPtr("api"), Tasks: []*nomad.Task{{Name: "server"}, {Name: "proxy"}}},
+		},
+	}
+
+	if err := checkReadiness(job, map[string]string{"api.server": "http://${NOMAD_ADDR_http}/ready"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := checkReadiness(job, map[string]string{"api.worker": "http://${NOMAD_ADDR_http}/ready"}); err == nil {
+		t.Fatal("expected error for unknown task")
+	}
+
+	job.Type = stringToPtr(nomad.JobTypeBatch)
+	if err := checkReadiness(job, map[string]string{"api.server": "http://${NOMAD_ADDR_http}/ready"}); err == nil {
+		t.Fatal("expected error for batch job")
+	}
+}
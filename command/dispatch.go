@@ -33,6 +33,11 @@ General Options:
     The Nomad HTTP API address including port which Levant will use to make
     calls.
 
+  -header=<key=value>
+    Add a custom HTTP header to every Nomad API request, such as an
+    Authorization header required by a proxy in front of Nomad. You can
+    repeat this flag multiple times to add multiple headers.
+
   -log-level=<level>
     Specify the verbosity level of Levant's logs. Valid values include DEBUG,
     INFO, and WARN, in decreasing order of verbosity. The default is INFO.
@@ -48,7 +53,9 @@ Dispatch Options:
     merged into the job's metadata. The job may define a default value for the
     key which is overridden when dispatching. The flag can be provided more 
     than once to inject multiple metadata key/value pairs. Arbitrary keys are
-    not allowed. The parameterized job must allow the key to be merged.
+    not allowed. The parameterized job must allow the key to be merged. The
+    keys are checked against the meta_required and meta_optional keys of the
+    job before it is dispatched.
 `
 	return strings.TrimSpace(helpText)
 }
@@ -64,7 +71,7 @@ func (c *DispatchCommand) Run(args []string) int {
 	var meta []string
 	var addr, logLevel, logFormat string
 
-	flags := c.Meta.FlagSet("dispatch", FlagSetVars)
+	flags := c.Meta.FlagSet("dispatch", FlagSetVars|FlagSetNomad)
 	flags.Usage = func() { c.UI.Output(c.Help()) }
 	flags.Var((*flaghelper.StringFlag)(&meta), "meta", "")
 	flags.StringVar(&addr, "address", "", "")
@@ -113,7 +120,7 @@ func (c *DispatchCommand) Run(args []string) int {
 		metaMap[split[0]] = split[1]
 	}
 
-	success := levant.TriggerDispatch(job, metaMap, payload, addr)
+	success := levant.TriggerDispatch(job, metaMap, payload, c.Meta.clientConfig(addr, false))
 	if !success {
 		return 1
 	}
@@ -3,9 +3,15 @@ package command
 import (
 	"bufio"
 	"flag"
+	"fmt"
 	"io"
+	"net/http"
+	"strings"
+	"time"
 
 	"github.com/jrasell/levant/helper"
+	"github.com/jrasell/levant/levant/structs"
+	"github.com/jrasell/levant/template"
 	"github.com/mitchellh/cli"
 )
 
@@ -18,6 +24,7 @@ const (
 	FlagSetNone        FlagSetFlags = 0
 	FlagSetBuildFilter FlagSetFlags = 1 << iota
 	FlagSetVars
+	FlagSetNomad
 )
 
 // Meta contains the meta-options and functionality that nearly every
@@ -26,7 +33,14 @@ type Meta struct {
 	UI cli.Ui
 
 	// These are set by command-line flags
-	flagVars map[string]string
+	flagVars      map[string]string
+	varPrecedence string
+	allowFuncs    []string
+	denyFuncs     []string
+	remoteHeaders http.Header
+	remoteTimeout time.Duration
+	explainVars   bool
+	nomadHeaders  http.Header
 }
 
 // FlagSet returns a FlagSet with the common flags that every
@@ -37,6 +51,17 @@ func (m *Meta) FlagSet(n string, fs FlagSetFlags) *flag.FlagSet {
 	// FlagSetVars tells us what variables to use
 	if fs&FlagSetVars != 0 {
 		f.Var((*helper.Flag)(&m.flagVars), "var", "")
+		f.StringVar(&m.varPrecedence, "var-precedence", "", "")
+		f.Var((*helper.FlagStringSlice)(&m.allowFuncs), "allow-func", "")
+		f.Var((*helper.FlagStringSlice)(&m.denyFuncs), "deny-func", "")
+		f.Var((*headerFlag)(&m.remoteHeaders), "remote-header", "")
+		f.DurationVar(&m.remoteTimeout, "remote-timeout", 0, "")
+		f.BoolVar(&m.explainVars, "explain-vars", false, "")
+	}
+
+	// FlagSetNomad adds the flags which configure the Nomad API client.
+	if fs&FlagSetNomad != 0 {
+		f.Var((*headerFlag)(&m.nomadHeaders), "header", "")
 	}
 
 	// Create an io.Writer that writes to our Ui properly for errors.
@@ -51,3 +76,65 @@ func (m *Meta) FlagSet(n string, fs FlagSetFlags) *flag.FlagSet {
 
 	return f
 }
+
+// headerFlag parses the key=value header flag into the headers, such as
+// those added to the requests of the Nomad API clients or sent when fetching
+// remote templates and variable files.
+type headerFlag http.Header
+
+func (h *headerFlag) String() string {
+	return ""
+}
+
+// Set takes a header flag argument and adds it to the headers.
+func (h *headerFlag) Set(value string) error {
+	k, v, err := parseHeaderFlag(value)
+	if err != nil {
+		return err
+	}
+
+	if *h == nil {
+		*h = make(headerFlag)
+	}
+	http.Header(*h).Add(k, v)
+	return nil
+}
+
+// parseHeaderFlag splits the key=value header flag argument.
+func parseHeaderFlag(value string) (string, string, error) {
+	kv := strings.SplitN(value, "=", 2)
+	if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+		return "", "", fmt.Errorf("header %q must be in the format key=value", value)
+	}
+	return strings.TrimSpace(kv[0]), kv[1], nil
+}
+
+// renderOptions returns the template render options configured by the common
+// variable flags.
+func (m *Meta) renderOptions() (*template.RenderOptions, error) {
+
+	opts := &template.RenderOptions{
+		AllowFuncs:    m.allowFuncs,
+		DenyFuncs:     m.denyFuncs,
+		NomadHeaders:  m.nomadHeaders,
+		RemoteHeaders: m.remoteHeaders,
+		RemoteTimeout: m.remoteTimeout,
+		ExplainVars:   m.explainVars,
+	}
+
+	if m.varPrecedence != "" {
+		p, err := helper.ParseVarPrecedence(m.varPrecedence)
+		if err != nil {
+			return nil, err
+		}
+		opts.VarPrecedence = p
+	}
+
+	return opts, nil
+}
+
+// clientConfig returns the config of the Nomad API client for the address,
+// including the headers of the -header flag.
+func (m *Meta) clientConfig(addr string, allowStale bool) *structs.ClientConfig {
+	return &structs.ClientConfig{Addr: addr, AllowStale: allowStale, Headers: m.nomadHeaders}
+}
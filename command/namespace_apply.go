@@ -0,0 +1,80 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jrasell/levant/helper"
+	"github.com/jrasell/levant/levant"
+	"github.com/jrasell/levant/logging"
+)
+
+// NamespaceApplyCommand is the command implementation that allows users to
+// render a Nomad namespace template and apply it.
+type NamespaceApplyCommand struct {
+	Meta
+}
+
+// Help provides the help information for the namespace-apply command.
+func (c *NamespaceApplyCommand) Help() string {
+	helpText := `
+Usage: levant namespace-apply [options] TEMPLATE
+
+  Render a Nomad namespace specification template and register the namespace
+  with Nomad, creating or updating it. The rendered specification is HCL or
+  JSON containing the name, description and optional quota of the namespace.
+  Variables are passed in the same way as the deploy command.
+
+Arguments:
+
+  TEMPLATE  nomad namespace specification template
+` + applyHelpOptions
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis is provides a brief summary of the namespace-apply command.
+func (c *NamespaceApplyCommand) Synopsis() string {
+	return "Render and apply a Nomad namespace from a template"
+}
+
+// Run triggers a run of the Levant namespace apply functions.
+func (c *NamespaceApplyCommand) Run(args []string) int {
+
+	var addr, consulAddr, logLevel, logFormat string
+	var variables []string
+
+	flags := c.Meta.FlagSet("namespace-apply", FlagSetVars|FlagSetNomad)
+	flags.Usage = func() { c.UI.Output(c.Help()) }
+	flags.StringVar(&addr, "address", "", "")
+	flags.StringVar(&consulAddr, "consul-address", "", "")
+	flags.StringVar(&logLevel, "log-level", "INFO", "")
+	flags.StringVar(&logFormat, "log-format", "HUMAN", "")
+	flags.Var((*helper.FlagStringSlice)(&variables), "var-file", "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = flags.Args()
+	if len(args) != 1 {
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	if err := logging.SetupLogger(logLevel, logFormat); err != nil {
+		c.UI.Error(fmt.Sprintf("Error setting up logging: %v", err))
+	}
+
+	spec, err := c.Meta.renderApplyTemplate(args[0], variables, consulAddr, addr)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("[ERROR] levant/command: %v", err))
+		return 1
+	}
+
+	if err = levant.TriggerNamespaceApply(spec, c.Meta.clientConfig(addr, false)); err != nil {
+		c.UI.Error(fmt.Sprintf("[ERROR] levant/command: %v", err))
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,113 @@
+package command
+
+import (
+	"fmt"
+	"sort"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/jrasell/levant/levant/structs"
+)
+
+const (
+	minJobPriority = 1
+	maxJobPriority = 100
+)
+
+// applyJobOverrides updates the rendered job with any values the operator has
+// passed via CLI flags. This is performed before the plan so that the changes
+// are reflected within the plan output.
+func applyJobOverrides(config *structs.TemplateConfig) error {
+
+	if config.Priority != 0 {
+		if config.Priority < minJobPriority || config.Priority > maxJobPriority {
+			return fmt.Errorf("priority %v is invalid; must be between %v and %v",
+				config.Priority, minJobPriority, maxJobPriority)
+		}
+		config.Job.Priority = &config.Priority
+	}
+
+	if config.Canary != nil {
+		if err := overrideCanary(config.Job, *config.Canary); err != nil {
+			return err
+		}
+	}
+
+	if len(config.Images) > 0 {
+		if err := overrideImages(config.Job, config.Images); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// overrideImages sets the image config field of the tasks named, in the form
+// group.task, within the images map. Each task must exist and already have an
+// image config field, such as a task using the Docker driver.
+func overrideImages(job *nomad.Job, images map[string]string) error {
+
+	var names []string
+	for name := range images {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		task := findGroupTask(job, name)
+		if task == nil {
+			return fmt.Errorf("unable to set image of %s as no task matches the group.task name", name)
+		}
+		if _, ok := task.Config["image"]; !ok {
+			return fmt.Errorf("unable to set image of %s as the task does not have an image config field", name)
+		}
+		task.Config["image"] = images[name]
+	}
+
+	return nil
+}
+
+// findGroupTask returns the task identified by the group.task name. Groups and
+// tasks are matched on their full names so names containing dots are matched
+// correctly.
+func findGroupTask(job *nomad.Job, name string) *nomad.Task {
+	for _, group := range job.TaskGroups {
+		if group.Name == nil {
+			continue
+		}
+		for _, task := range group.Tasks {
+			if *group.Name+"."+task.Name == name {
+				return task
+			}
+		}
+	}
+	return nil
+}
+
+// overrideCanary sets the canary count on the job and each task group update
+// stanza. Groups without an update stanza inherit the job level stanza.
+func overrideCanary(job *nomad.Job, canary int) error {
+
+	if canary < 0 {
+		return fmt.Errorf("canary %v is invalid; must not be negative", canary)
+	}
+
+	if job.Update != nil {
+		c := canary
+		job.Update.Canary = &c
+	}
+
+	for _, group := range job.TaskGroups {
+		if group.Update != nil {
+			c := canary
+			group.Update.Canary = &c
+			continue
+		}
+
+		if job.Update == nil && canary > 0 {
+			return fmt.Errorf("unable to set canary on group %s as neither the job nor group has an update stanza",
+				*group.Name)
+		}
+	}
+
+	return nil
+}
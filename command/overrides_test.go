@@ -0,0 +1,140 @@
+package command
+
+import (
+	"testing"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/jrasell/levant/levant/structs"
+)
+
+func TestOverrides_priority(t *testing.T) {
+
+	cases := []struct {
+		Priority int
+		Expected int
+		Error    bool
+	}{
+		{0, 50, false},
+		{80, 80, false},
+		{101, 50, true},
+	}
+
+	for i, tc := range cases {
+		p := 50
+		config := &structs.TemplateConfig{
+			Job:      &nomad.Job{Priority: &p},
+			Priority: tc.Priority,
+		}
+
+		err := applyJobOverrides(config)
+		if (err != nil) != tc.Error {
+			t.Fatalf("case %d: unexpected error result: %v", i, err)
+		}
+		if *config.Job.Priority != tc.Expected {
+			t.Fatalf("case %d: got priority %v, expected %v", i, *config.Job.Priority, tc.Expected)
+		}
+	}
+}
+
+func TestOverrides_canary(t *testing.T) {
+
+	newJob := func() *nomad.Job {
+		one := 1
+		return &nomad.Job{
+			Update: &nomad.UpdateStrategy{Canary: &one},
+			TaskGroups: []*nomad.TaskGroup{
+				{Name: stringToPtr("web"), Update: &nomad.UpdateStrategy{Canary: &one}},
+				{Name: stringToPtr("api")},
+			},
+		}
+	}
+
+	for _, canary := range []int{0, 3} {
+		c := canary
+		config := &structs.TemplateConfig{Job: newJob(), Canary: &c}
+
+		if err := applyJobOverrides(config); err != nil {
+			t.Fatalf("unexpected error setting canary %v: %v", canary, err)
+		}
+		if *config.Job.Update.Canary != canary {
+			t.Fatalf("got job canary %v, expected %v", *config.Job.Update.Canary, canary)
+		}
+		if *config.Job.TaskGroups[0].Update.Canary != canary {
+			t.Fatalf("got group canary %v, expected %v", *config.Job.TaskGroups[0].Update.Canary, canary)
+		}
+		if config.Job.TaskGroups[1].Update != nil {
+			t.Fatal("expected group without update stanza to be left unchanged")
+		}
+	}
+
+	// Without a canary override the job is left untouched.
+	config := &structs.TemplateConfig{Job: newJob()}
+	if err := applyJobOverrides(config); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *config.Job.TaskGroups[0].Update.Canary != 1 {
+		t.Fatalf("got group canary %v, expected 1", *config.Job.TaskGroups[0].Update.Canary)
+	}
+
+	// A group without any update stanza to inherit cannot have canaries.
+	two := 2
+	config = &structs.TemplateConfig{
+		Job:    &nomad.Job{TaskGroups: []*nomad.TaskGroup{{Name: stringToPtr("web")}}},
+		Canary: &two,
+	}
+	if err := applyJobOverrides(config); err == nil {
+		t.Fatal("expected error setting canary on job without update stanza")
+	}
+
+	neg := -1
+	config = &structs.TemplateConfig{Job: newJob(), Canary: &neg}
+	if err := applyJobOverrides(config); err == nil {
+		t.Fatal("expected error setting negative canary")
+	}
+}
+
+func stringToPtr(s string) *string {
+	return &s
+}
+
+func TestOverrides_images(t *testing.T) {
+
+	newJob := func() *nomad.Job {
+		return &nomad.Job{
+			TaskGroups: []*nomad.TaskGroup{
+				{
+					Name: stringToPtr("cache"),
+					Tasks: []*nomad.Task{
+						{Name: "redis", Config: map[string]interface{}{"image": "redis:3.2"}},
+						{Name: "script", Config: map[string]interface{}{"command": "/bin/sh"}},
+					},
+				},
+				{
+					Name:  stringToPtr("web.v2"),
+					Tasks: []*nomad.Task{{Name: "nginx", Config: map[string]interface{}{"image": "nginx:1.16"}}},
+				},
+			},
+		}
+	}
+
+	config := &structs.TemplateConfig{
+		Job:    newJob(),
+		Images: map[string]string{"cache.redis": "redis:4.0", "web.v2.nginx": "nginx:1.17"},
+	}
+	if err := applyJobOverrides(config); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if img := config.Job.TaskGroups[0].Tasks[0].Config["image"]; img != "redis:4.0" {
+		t.Fatalf("got image %v, expected redis:4.0", img)
+	}
+	if img := config.Job.TaskGroups[1].Tasks[0].Config["image"]; img != "nginx:1.17" {
+		t.Fatalf("got image %v, expected nginx:1.17", img)
+	}
+
+	for _, name := range []string{"cache.memcached", "cache", "cache.script"} {
+		config := &structs.TemplateConfig{Job: newJob(), Images: map[string]string{name: "redis:4.0"}}
+		if err := applyJobOverrides(config); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
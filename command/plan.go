@@ -1,9 +1,14 @@
 package command
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"path"
 	"strings"
+	"time"
 
+	nomad "github.com/hashicorp/nomad/api"
 	"github.com/jrasell/levant/helper"
 	"github.com/jrasell/levant/levant"
 	"github.com/jrasell/levant/levant/structs"
@@ -11,6 +16,10 @@ import (
 	"github.com/jrasell/levant/template"
 )
 
+// planAdditionsExitCode is the exit code of a plan summarising additions which
+// identified task groups or tasks added to the job.
+const planAdditionsExitCode = 2
+
 // PlanCommand is the command implementation that allows users to plan a
 // Nomad job based on passed templates and variables.
 type PlanCommand struct {
@@ -26,36 +35,122 @@ Usage: levant plan [options] [TEMPLATE]
   command supports passing variables individually on the command line. Multiple
   commands can be passed in the format of -var 'key=value'. Variables passed
   via the command line take precedence over the same variable declared within
-  a passed variable file.
+  a passed variable file. Templates containing multiple jobs, such as the
+  output of Nomad Pack, have each job planned in turn.
 
 Arguments:
 
   TEMPLATE nomad job template
-    If no argument is given we look for a single *.nomad file
+    If no argument is given we look for a single *.nomad file. The template,
+    and any var-file, may also be an http(s) URL which is fetched before
+    rendering.
 
 General Options:
 
+  -accept-no-diff
+    Treat a plan with no scheduler changes as a successful no-op. If the job
+    specification still differs from the running job, for example only in
+    fields the scheduler diff does not include, the job is registered.
+
   -address=<http_address>
     The Nomad HTTP API address including port which Levant will use to make
     calls.
 
+  -allow-func=<name>
+    Restrict the template functions available when rendering to those listed.
+    You can repeat this flag multiple times to allow multiple functions.
+
   -allow-stale
     Allow stale consistency mode for requests into nomad.
 		
+  -canary=<num>
+    Override the canary count of the update stanza of each task group in the
+    rendered job. A value of 0 disables canaries.
+
   -consul-address=<addr>
     The Consul host and port to use when making Consul KeyValue lookups for
     template rendering.
 
+  -count-from-running
+    Copy the task group counts of the running job into the rendered job
+    before running the plan, so the plan reflects the counts a deployment
+    would use rather than the counts in the template.
+
+  -deadline=<duration>
+    The maximum time, such as 5m, allowed for the whole command including
+    rendering and planning the job. Once reached, any requests to Nomad and
+    Consul are cancelled and Levant exits with a status 124. By default there
+    is no deadline.
+
+  -deny-func=<name>
+    Disallow a template function when rendering, such as fileContents. You can
+    repeat this flag multiple times to deny multiple functions.
+
+  -diff-context
+    Include the unchanged fields of edited objects in the plan output, so
+    the changes can be reviewed alongside the surrounding configuration.
+    By default only the changed fields are shown.
+
   -force-count
     Use the taskgroup count from the Nomad jobfile instead of the count that
     is currently set in a running job.
 
+  -fail-on-destructive
+    Fail the plan if the Nomad plan indicates any of the changes will force
+    allocations to be destroyed and recreated. In-place updates are allowed.
+
+  -fail-on-placement-failure
+    Fail before the job is registered if the Nomad plan indicates any task
+    group can not be placed, such as due to constraints or exhausted
+    resources. The reasons are logged for each group regardless.
+
+  -fail-fast
+    Used in conjunction with -nomad-addrs to stop at the first cluster which
+    fails rather than continuing with the remaining clusters. When the
+    template contains multiple jobs, this also stops at the first job which
+    fails.
+
+  -format=<format>
+    The format used to output the changes identified by the plan. Valid
+    values are log, which logs a line for each changed field, tree, which
+    outputs an indented tree of the changed groups, tasks, objects and fields,
+    and grouped, which outputs the changes in sections by impact: destructive,
+    in-place, additions, deletions and other changes. The default is log.
+
+  -hcl-version=<version>
+    The HCL version used to parse the rendered job, either 1 or 2. HCL2 jobs
+    are parsed by the Nomad API and require Nomad 1.0 or later; use 1 for
+    legacy job specifications. By default, HCL2 is used when the cluster is
+    running Nomad 1.0 or later and HCL1 otherwise.
+
+  -ignore-count-changes
+    Ignore changes to the count of task groups within the plan, such as
+    those caused by autoscaling, so a plan where only counts have drifted
+    reports no changes.
+
+  -ignore-field=<objName:fieldName>
+    Ignore changes to the named field within the plan, such as
+    Job:Meta[deployed_at] for a timestamp which changes on every run. Ignored changes are not logged
+    or counted; if all changes are ignored the plan reports no changes. You can
+    repeat this flag multiple times to ignore multiple fields.
+
   -ignore-no-changes
     By default if no changes are detected when running a plan Levant will
     exit with a status 1 to indicate there are no changes. This behaviour
     can be changed using this flag so that Levant will exit cleanly ensuring CD
     pipelines don't fail when no changes are detected.
 
+  -image=<group.task=image>
+    Override the Docker image of the named task within the rendered job, such
+    as cache.redis=redis:4.0. The task must exist and have an image config
+    field. You can repeat this flag multiple times to override multiple
+    tasks.
+
+  -header=<key=value>
+    Add a custom HTTP header to every Nomad API request, such as an
+    Authorization header required by a proxy in front of Nomad. You can
+    repeat this flag multiple times to add multiple headers.
+
   -log-level=<level>
     Specify the verbosity level of Levant's logs. Valid values include DEBUG,
     INFO, and WARN, in decreasing order of verbosity. The default is INFO.
@@ -64,10 +159,78 @@ General Options:
     Specify the format of Levant's logs. Valid values are HUMAN or JSON. The
     default is HUMAN.
 
+  -max-plan-depth=<num>
+    The maximum depth of nested objects walked when logging the changes of
+    the plan. Deeper changes are not logged and a warning is shown. The
+    default is 32.
+
+  -no-changes-exit-code=<code>
+    The exit code to use when the plan does not detect any changes, taking
+    precedence over -ignore-no-changes. The default is 1.
+
+  -nomad-addrs=<addrs>
+    A comma separated list of Nomad HTTP API addresses. The job is rendered
+    once and then planned against each cluster in turn, with a summary of the
+    results. It can not be used with the -address flag.
+
+  -plan-diff-against-file=<file>
+    Write a unified diff between the specification of the running job and
+    the rendered job, as canonical JSON, to the file before the plan is run.
+    A job which is not registered is diffed against an empty specification.
+    Fields matching the -redact patterns are redacted.
+
+  -priority=<num>
+    Override the priority of the rendered job. Valid values are between 1 and
+    100.
+
+  -redact=<pattern>
+    Replace the old and new values of changed fields matching the glob
+    pattern, such as *_PASSWORD, with *** in the plan output while still
+    showing that the field changed. Patterns are matched case insensitively
+    against the field name, map key and objName:fieldName. Matching fields
+    are also redacted from the job logged by -show-job. You can repeat this
+    flag multiple times to redact multiple patterns.
+
+  -remote-header=<key=value>
+    Add an HTTP header, such as Authorization, to the requests made when the
+    template or a var-file is an http(s) URL. You can repeat this flag
+    multiple times to add multiple headers.
+
+  -remote-timeout=<duration>
+    The time allowed to fetch each remote template or var-file, such as 10s.
+    The default is 30s.
+
+  -show-job
+    Log the rendered job as JSON before the plan is run, so the final job
+    specification is visible without a separate render step. Fields matching
+    the -redact patterns, and the Vault token, are redacted.
+
+  -since=<duration>
+    Also log the changes between the rendered job and the most recent
+    version of the job submitted before the duration ago, such as 24h, to
+    review the changes accumulated over a time range.
+
+  -summary=<mode>
+    Limit the plan output to a summary of the changes. The only valid mode is
+    additions, which reports the task groups and tasks added to the job while
+    ignoring changes to existing fields, and exits with a status 2 if there
+    are any additions.
+
   -var-file=<file>
     Used in conjunction with the -job-file will plan a templated job against your
     Nomad cluster. You can repeat this flag multiple times to supply multiple var-files.
     [default: levant.(json|yaml|yml|tf)]
+
+  -explain-vars
+    Log each template variable with the source which provided its final
+    value, such as the var-file, env or flag, and the sources it overrode.
+    The variables are logged before the template is rendered.
+
+  -var-precedence=<sources>
+    A comma separated list of the variable sources to merge, lowest precedence
+    first. Valid sources are file, env and flag; env variables are read from
+    the environment with the LEVANT_VAR_ prefix removed. Sources not listed
+    are not used. [default: file,flag]
 `
 	return strings.TrimSpace(helpText)
 }
@@ -78,38 +241,114 @@ func (c *PlanCommand) Synopsis() string {
 }
 
 // Run triggers a run of the Levant template and plan functions.
-func (c *PlanCommand) Run(args []string) int {
+func (c *PlanCommand) Run(args []string) (exitCode int) {
 
 	var err error
 	var level, format string
+	var canary, hclVersion, noChangesExitCode int
+	var failFast bool
+	var nomadAddrs string
+	var deadline time.Duration
 	config := &levant.PlanConfig{
 		Client:   &structs.ClientConfig{},
 		Plan:     &structs.PlanConfig{},
 		Template: &structs.TemplateConfig{},
 	}
 
-	flags := c.Meta.FlagSet("plan", FlagSetVars)
+	flags := c.Meta.FlagSet("plan", FlagSetVars|FlagSetNomad)
 	flags.Usage = func() { c.UI.Output(c.Help()) }
 
+	flags.BoolVar(&config.Plan.AcceptNoDiff, "accept-no-diff", false, "")
 	flags.StringVar(&config.Client.Addr, "address", "", "")
 	flags.BoolVar(&config.Client.AllowStale, "allow-stale", false, "")
+	flags.IntVar(&canary, "canary", 0, "")
 	flags.StringVar(&config.Client.ConsulAddr, "consul-address", "", "")
+	flags.BoolVar(&config.Plan.CountFromRunning, "count-from-running", false, "")
+	flags.DurationVar(&deadline, "deadline", 0, "")
+	flags.BoolVar(&config.Plan.DiffContext, "diff-context", false, "")
+	flags.BoolVar(&config.Plan.FailOnDestructive, "fail-on-destructive", false, "")
+	flags.BoolVar(&config.Plan.FailOnPlacementFailure, "fail-on-placement-failure", false, "")
+	flags.BoolVar(&failFast, "fail-fast", false, "")
+	flags.StringVar(&config.Plan.Format, "format", structs.PlanFormatLog, "")
+	flags.IntVar(&hclVersion, "hcl-version", template.HCLVersionAuto, "")
 	flags.BoolVar(&config.Plan.IgnoreNoChanges, "ignore-no-changes", false, "")
+	flags.BoolVar(&config.Plan.IgnoreCountChanges, "ignore-count-changes", false, "")
+	flags.Var((*helper.FlagStringSlice)(&config.Plan.IgnoreFields), "ignore-field", "")
+	flags.IntVar(&config.Plan.MaxPlanDepth, "max-plan-depth", levant.DefaultMaxPlanDepth, "")
+	flags.IntVar(&noChangesExitCode, "no-changes-exit-code", 1, "")
+	flags.StringVar(&nomadAddrs, "nomad-addrs", "", "")
 	flags.StringVar(&level, "log-level", "INFO", "")
+	flags.Var((*helper.Flag)(&config.Template.Images), "image", "")
+	flags.StringVar(&config.Plan.SpecDiffFile, "plan-diff-against-file", "", "")
+	flags.IntVar(&config.Template.Priority, "priority", 0, "")
+	flags.Var((*helper.FlagStringSlice)(&config.Plan.Redact), "redact", "")
 	flags.StringVar(&format, "log-format", "HUMAN", "")
+	flags.BoolVar(&config.Plan.ShowJob, "show-job", false, "")
+	flags.DurationVar(&config.Plan.Since, "since", 0, "")
+	flags.StringVar(&config.Plan.Summary, "summary", "", "")
 	flags.Var((*helper.FlagStringSlice)(&config.Template.VariableFiles), "var-file", "")
 
 	if err = flags.Parse(args); err != nil {
 		return 1
 	}
+	config.Client.Headers = c.Meta.nomadHeaders
+
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "canary":
+			config.Template.Canary = &canary
+		case "no-changes-exit-code":
+			config.Plan.NoChangesExitCode = &noChangesExitCode
+		}
+	})
 
 	args = flags.Args()
 
+	if err = validateNoChangesExitCode(config.Plan); err != nil {
+		c.UI.Error(fmt.Sprintf("[ERROR] levant/command: %v", err))
+		return 1
+	}
+
+	if err = validatePlanFormat(config.Plan.Format); err != nil {
+		c.UI.Error(fmt.Sprintf("[ERROR] levant/command: %v", err))
+		return 1
+	}
+
+	if err = validateRedactPatterns(config.Plan.Redact); err != nil {
+		c.UI.Error(fmt.Sprintf("[ERROR] levant/command: %v", err))
+		return 1
+	}
+
+	if err = validatePlanSummary(config.Plan.Summary); err != nil {
+		c.UI.Error(fmt.Sprintf("[ERROR] levant/command: %v", err))
+		return 1
+	}
+
+	addrs := parseNomadAddrs(nomadAddrs)
+	if len(addrs) > 0 && config.Client.Addr != "" {
+		c.UI.Error(c.Help())
+		c.UI.Error("\nERROR: Can not use -address and -nomad-addrs flag at the same time")
+		return 1
+	}
+
 	if err = logging.SetupLogger(level, format); err != nil {
 		c.UI.Error(err.Error())
 		return 1
 	}
 
+	// Bound the whole command, from rendering through to watching the
+	// deployment, by the deadline.
+	if deadline > 0 {
+		ctx, stop := startDeadline(deadline)
+		config.Client.Context = ctx
+		defer func() {
+			if stop() {
+				c.UI.Error(fmt.Sprintf("[ERROR] levant/command: deadline of %v reached", deadline))
+				exitCode = deadlineExitCode
+			}
+		}()
+	}
+
 	if len(args) == 1 {
 		config.Template.TemplateFile = args[0]
 	} else if len(args) == 0 {
@@ -123,20 +362,173 @@ func (c *PlanCommand) Run(args []string) int {
 		return 1
 	}
 
-	config.Template.Job, err = template.RenderJob(config.Template.TemplateFile,
-		config.Template.VariableFiles, config.Client.ConsulAddr, &c.Meta.flagVars)
+	renderOpts, err := c.Meta.renderOptions()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("[ERROR] levant/command: %v", err))
+		return 1
+	}
+	renderOpts.Context = config.Client.Context
+	renderOpts.NomadAddr = renderNomadAddr(config.Client.Addr, addrs)
+	renderOpts.NomadRegion = config.Client.Region
+	renderOpts.NomadNamespace = config.Client.Namespace
+	renderOpts.HCLVersion = hclVersion
 
+	jobs, err := template.RenderJobs(config.Template.TemplateFile,
+		config.Template.VariableFiles, config.Client.ConsulAddr, &c.Meta.flagVars, renderOpts)
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("[ERROR] levant/command: %v", err))
 		return 1
 	}
 
-	success, changes := levant.TriggerPlan(config)
-	if !success {
+	if len(jobs) == 1 {
+		config.Template.Job = jobs[0]
+		return c.planJob(config, addrs, failFast)
+	}
+
+	// Templates holding multiple jobs, such as the output of Nomad Pack, have
+	// each job planned in turn with its own copy of the config.
+	ids := make([]string, len(jobs))
+	byID := make(map[string]*nomad.Job, len(jobs))
+	for i, job := range jobs {
+		ids[i] = *job.ID
+		byID[*job.ID] = job
+	}
+
+	return runInTurn(c.UI, "Planning job", "Job results", ids, failFast, func(id string) int {
+		tmplConfig := *config.Template
+		tmplConfig.Job = byID[id]
+
+		return c.planJob(&levant.PlanConfig{
+			Client:   config.Client,
+			Plan:     config.Plan,
+			Template: &tmplConfig,
+			Logger:   config.Logger,
+		}, addrs, failFast)
+	})
+}
+
+// planJob plans a single rendered job, against each cluster in turn when
+// planning against multiple clusters, and returns the exit code.
+func (c *PlanCommand) planJob(config *levant.PlanConfig, addrs []string, failFast bool) int {
+
+	if err := applyJobOverrides(config.Template); err != nil {
+		c.UI.Error(fmt.Sprintf("[ERROR] levant/command: %v", err))
 		return 1
-	} else if !changes && config.Plan.IgnoreNoChanges {
+	}
+
+	if len(addrs) == 0 {
+		if err := levant.TriggerPlan(config); err != nil {
+			return planErrorExitCode(err, config.Plan)
+		}
 		return 0
 	}
 
-	return 0
+	return c.planClusters(config, addrs, failFast)
+}
+
+// planClusters plans the rendered job against each cluster in turn. Each
+// cluster is given its own copy of the job, as the plan updates the job with
+// the counts of the running job when -count-from-running is set.
+func (c *PlanCommand) planClusters(config *levant.PlanConfig, addrs []string, failFast bool) int {
+	return runOnClusters(c.UI, addrs, failFast, func(addr string) int {
+		tmplConfig, err := clusterTemplateConfig(config.Template)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("[ERROR] levant/command: %v", err))
+			return 1
+		}
+
+		p := &levant.PlanConfig{
+			Client:   clusterClientConfig(config.Client, addr),
+			Plan:     config.Plan,
+			Template: tmplConfig,
+			Logger:   config.Logger,
+		}
+
+		if err := levant.TriggerPlan(p); err != nil {
+			return planErrorExitCode(err, p.Plan)
+		}
+		return 0
+	})
+}
+
+// validateNoChangesExitCode checks the no changes exit code, if set, is a
+// valid process exit code.
+func validateNoChangesExitCode(config *structs.PlanConfig) error {
+	if c := config.NoChangesExitCode; c != nil && (*c < 0 || *c > 255) {
+		return fmt.Errorf("no-changes-exit-code %v is invalid; must be between 0 and 255", *c)
+	}
+	return nil
+}
+
+// validatePlanFormat checks the passed plan output format is supported.
+func validatePlanFormat(format string) error {
+	switch strings.ToLower(format) {
+	case structs.PlanFormatLog, structs.PlanFormatTree, structs.PlanFormatGrouped:
+		return nil
+	default:
+		return fmt.Errorf("unsupported plan format: %q (supported formats: %s %s %s)",
+			format, structs.PlanFormatLog, structs.PlanFormatTree, structs.PlanFormatGrouped)
+	}
+}
+
+// validateRedactPatterns checks each of the redact patterns is a valid glob
+// pattern.
+func validateRedactPatterns(patterns []string) error {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("redact pattern %q is invalid: %v", p, err)
+		}
+	}
+	return nil
+}
+
+// validatePlanSummary checks the passed plan summary mode, if set, is
+// supported.
+func validatePlanSummary(summary string) error {
+	switch strings.ToLower(summary) {
+	case "", structs.PlanSummaryAdditions:
+		return nil
+	default:
+		return fmt.Errorf("unsupported plan summary: %q (supported summaries: %s)",
+			summary, structs.PlanSummaryAdditions)
+	}
+}
+
+// planOnlyExitCode returns the exit code for a deploy run with -plan-only. As
+// with terraform plan -detailed-exitcode, the exit code is 0 when there are
+// no changes, 1 on error and 2 when there are changes to deploy.
+func planOnlyExitCode(err error, config *structs.PlanConfig) int {
+	switch {
+	case err == nil:
+		return 2
+	case errors.Is(err, levant.ErrPlanNoChanges):
+		if config.NoChangesExitCode != nil {
+			return *config.NoChangesExitCode
+		}
+		return 0
+	default:
+		return 1
+	}
+}
+
+// planErrorExitCode translates the error returned from a plan into the exit
+// code of the command. A plan without changes exits with the configured no
+// changes exit code, or cleanly if told to ignore or accept no changes. A plan
+// summarising additions which found any exits with planAdditionsExitCode.
+func planErrorExitCode(err error, config *structs.PlanConfig) int {
+	if errors.Is(err, levant.ErrPlanAdditions) {
+		return planAdditionsExitCode
+	}
+	if !errors.Is(err, levant.ErrPlanNoChanges) {
+		return 1
+	}
+
+	switch {
+	case config.NoChangesExitCode != nil:
+		return *config.NoChangesExitCode
+	case config.IgnoreNoChanges || config.AcceptNoDiff:
+		return 0
+	default:
+		return 1
+	}
 }
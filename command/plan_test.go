@@ -0,0 +1,192 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/jrasell/levant/levant"
+	"github.com/jrasell/levant/levant/structs"
+	"github.com/mitchellh/cli"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func TestPlan_planErrorExitCode(t *testing.T) {
+
+	zero, three := 0, 3
+
+	cases := []struct {
+		Err      error
+		Config   *structs.PlanConfig
+		Expected int
+	}{
+		{levant.ErrPlanNoChanges, &structs.PlanConfig{}, 1},
+		{levant.ErrPlanNoChanges, &structs.PlanConfig{IgnoreNoChanges: true}, 0},
+		{levant.ErrPlanNoChanges, &structs.PlanConfig{AcceptNoDiff: true}, 0},
+		{levant.ErrPlanNoChanges, &structs.PlanConfig{NoChangesExitCode: &three}, 3},
+		{levant.ErrPlanNoChanges, &structs.PlanConfig{IgnoreNoChanges: true, NoChangesExitCode: &three}, 3},
+		{levant.ErrPlanNoChanges, &structs.PlanConfig{NoChangesExitCode: &zero}, 0},
+		{fmt.Errorf("%w: unable to plan", levant.ErrPlanFailed), &structs.PlanConfig{NoChangesExitCode: &zero}, 1},
+		{fmt.Errorf("%w: group cache", levant.ErrPlanAdditions), &structs.PlanConfig{Summary: structs.PlanSummaryAdditions}, 2},
+	}
+
+	for i, tc := range cases {
+		if code := planErrorExitCode(tc.Err, tc.Config); code != tc.Expected {
+			t.Fatalf("case %d: got exit code %d, expected %d", i, code, tc.Expected)
+		}
+	}
+
+	invalid := 256
+	if err := validateNoChangesExitCode(&structs.PlanConfig{NoChangesExitCode: &invalid}); err == nil {
+		t.Fatal("expected error for invalid exit code")
+	}
+
+	if err := validateRedactPatterns([]string{"*_TOKEN", "[-"}); err == nil {
+		t.Fatal("expected error for invalid redact pattern")
+	}
+
+	if err := validatePlanSummary("fields"); err == nil {
+		t.Fatal("expected error for invalid plan summary")
+	}
+}
+
+func TestPlan_validatePlanFormat(t *testing.T) {
+
+	for _, f := range []string{"log", "tree", "TREE"} {
+		if err := validatePlanFormat(f); err != nil {
+			t.Fatalf("unexpected error for format %q: %v", f, err)
+		}
+	}
+
+	if err := validatePlanFormat("table"); err == nil {
+		t.Fatal("expected error for unsupported format")
+	}
+}
+
+func TestPlan_planOnlyExitCode(t *testing.T) {
+
+	three := 3
+
+	cases := []struct {
+		Err      error
+		Config   *structs.PlanConfig
+		Expected int
+	}{
+		{nil, &structs.PlanConfig{}, 2},
+		{levant.ErrPlanNoChanges, &structs.PlanConfig{}, 0},
+		{levant.ErrPlanNoChanges, &structs.PlanConfig{NoChangesExitCode: &three}, 3},
+		{fmt.Errorf("%w: unable to plan", levant.ErrPlanFailed), &structs.PlanConfig{}, 1},
+	}
+
+	for i, tc := range cases {
+		if code := planOnlyExitCode(tc.Err, tc.Config); code != tc.Expected {
+			t.Fatalf("case %d: got exit code %d, expected %d", i, code, tc.Expected)
+		}
+	}
+}
+
+func TestPlan_planClusters(t *testing.T) {
+
+	log.Logger = zerolog.New(ioutil.Discard)
+
+	// planCluster serves a Nomad cluster running the job with the count, or
+	// without the job when the count is 0, recording the count of the group
+	// of each planned job.
+	planCluster := func(running int, planned *[]int) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/v1/job/example":
+				if running == 0 {
+					http.Error(w, "job not found", http.StatusNotFound)
+					return
+				}
+				json.NewEncoder(w).Encode(&nomad.Job{
+					ID:         stringToPtr("example"),
+					Status:     stringToPtr("running"),
+					TaskGroups: []*nomad.TaskGroup{{Name: stringToPtr("cache"), Count: &running}},
+				})
+			case "/v1/job/example/plan":
+				req := &nomad.JobPlanRequest{}
+				if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+					t.Errorf("unable to decode plan request: %v", err)
+				}
+				*planned = append(*planned, *req.Job.TaskGroups[0].Count)
+				json.NewEncoder(w).Encode(&nomad.JobPlanResponse{Diff: &nomad.JobDiff{Type: "None"}})
+			default:
+				http.Error(w, "Invalid URL", http.StatusNotFound)
+			}
+		}))
+	}
+
+	var planned []int
+	first, second := planCluster(5, &planned), planCluster(0, &planned)
+	defer first.Close()
+	defer second.Close()
+
+	count := 2
+	config := &levant.PlanConfig{
+		Client: &structs.ClientConfig{},
+		Plan:   &structs.PlanConfig{CountFromRunning: true, IgnoreNoChanges: true},
+		Template: &structs.TemplateConfig{Job: &nomad.Job{
+			ID:         stringToPtr("example"),
+			Name:       stringToPtr("example"),
+			TaskGroups: []*nomad.TaskGroup{{Name: stringToPtr("cache"), Count: &count}},
+		}},
+	}
+
+	c := &PlanCommand{Meta: Meta{UI: cli.NewMockUi()}}
+	if code := c.planClusters(config, []string{first.URL, second.URL}, false); code != 0 {
+		t.Fatalf("expected exit code 0, got %d", code)
+	}
+
+	// The second cluster does not run the job, so the template count is
+	// planned rather than the count copied from the first cluster.
+	if len(planned) != 2 || planned[0] != 5 || planned[1] != 2 {
+		t.Fatalf("expected planned counts [5 2], got %v", planned)
+	}
+	if *config.Template.Job.TaskGroups[0].Count != 2 {
+		t.Fatalf("expected the rendered job not to be modified, got count %d", *config.Template.Job.TaskGroups[0].Count)
+	}
+}
+
+func TestPlan_RunMultipleJobs(t *testing.T) {
+
+	var planned []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/job/cache/plan", "/v1/job/web/plan":
+			req := &nomad.JobPlanRequest{}
+			if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+				t.Errorf("unable to decode plan request: %v", err)
+			}
+			planned = append(planned, *req.Job.ID)
+			json.NewEncoder(w).Encode(&nomad.JobPlanResponse{Diff: &nomad.JobDiff{Type: "None"}})
+		default:
+			http.Error(w, "Invalid URL", http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	ui := cli.NewMockUi()
+	c := &PlanCommand{Meta: Meta{UI: ui}}
+
+	// Each job without changes exits with 1, which is the combined exit code.
+	args := []string{"-address=" + srv.URL, "-hcl-version=1", "-log-level=ERROR", "test-fixtures/multi_job.nomad"}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("expected exit code 1, got %d: %s", code, ui.ErrorWriter.String())
+	}
+
+	if len(planned) != 2 || planned[0] != "cache" || planned[1] != "web" {
+		t.Fatalf("expected jobs cache and web to be planned in turn, got %v", planned)
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, "cache: failed with exit code 1") ||
+		!strings.Contains(out, "web: failed with exit code 1") {
+		t.Fatalf("expected the result of each job, got %s", out)
+	}
+}
@@ -0,0 +1,98 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	flaghelper "github.com/hashicorp/nomad/helper/flag-helpers"
+	"github.com/jrasell/levant/levant"
+	"github.com/jrasell/levant/logging"
+)
+
+// PromoteCommand is the command implementation that allows users to promote
+// the canaries of a running Nomad deployment.
+type PromoteCommand struct {
+	Meta
+}
+
+// Help provides the help information for the promote command.
+func (c *PromoteCommand) Help() string {
+	helpText := `
+Usage: levant promote [options] <job>
+
+  Promote the canaries of the latest deployment of a Nomad job. By default the
+  canaries of all task groups are promoted; the promote-group flag can be used
+  to promote only the named groups while holding the others.
+
+General Options:
+
+  -address=<http_address>
+    The Nomad HTTP API address including port which Levant will use to make
+    calls.
+
+  -allow-stale
+    Allow stale consistency mode for requests into nomad.
+
+  -header=<key=value>
+    Add a custom HTTP header to every Nomad API request, such as an
+    Authorization header required by a proxy in front of Nomad. You can
+    repeat this flag multiple times to add multiple headers.
+
+  -log-level=<level>
+    Specify the verbosity level of Levant's logs. Valid values include DEBUG,
+    INFO, and WARN, in decreasing order of verbosity. The default is INFO.
+
+  -log-format=<format>
+    Specify the format of Levant's logs. Valid values are HUMAN or JSON. The
+    default is HUMAN.
+
+Promote Options:
+
+  -promote-group=<name>
+    The name of a task group whose canaries should be promoted. The flag can
+    be provided more than once to promote multiple groups.
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis is provides a brief summary of the promote command.
+func (c *PromoteCommand) Synopsis() string {
+	return "Promote the canaries of a Nomad deployment"
+}
+
+// Run triggers a run of the Levant promote functions.
+func (c *PromoteCommand) Run(args []string) int {
+
+	var groups []string
+	var addr, logLevel, logFormat string
+	var allowStale bool
+
+	flags := c.Meta.FlagSet("promote", FlagSetNomad)
+	flags.Usage = func() { c.UI.Output(c.Help()) }
+	flags.Var((*flaghelper.StringFlag)(&groups), "promote-group", "")
+	flags.StringVar(&addr, "address", "", "")
+	flags.BoolVar(&allowStale, "allow-stale", false, "")
+	flags.StringVar(&logLevel, "log-level", "INFO", "")
+	flags.StringVar(&logFormat, "log-format", "human", "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = flags.Args()
+	if len(args) != 1 {
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	err := logging.SetupLogger(logLevel, logFormat)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error setting up logging: %v", err))
+	}
+
+	if success := levant.TriggerPromote(args[0], groups, c.Meta.clientConfig(addr, allowStale)); !success {
+		return 1
+	}
+
+	return 0
+}
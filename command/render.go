@@ -1,9 +1,12 @@
 package command
 
 import (
-	"bytes"
+	"bufio"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/jrasell/levant/helper"
@@ -30,22 +33,75 @@ Usage: levant render [options] [TEMPLATE]
 Arguments:
 
   TEMPLATE  nomad job template
-    If no argument is given we look for a single *.nomad file
+    If no argument is given we look for a single *.nomad file. The template,
+    and any var-file, may also be an http(s) URL which is fetched before
+    rendering.
 
 General Options:
 
+  -allow-func=<name>
+    Restrict the template functions available when rendering to those listed.
+    You can repeat this flag multiple times to allow multiple functions.
+
   -consul-address=<addr>
     The Consul host and port to use when making Consul KeyValue lookups for
     template rendering.
 	
+  -deny-func=<name>
+    Disallow a template function when rendering, such as fileContents. You can
+    repeat this flag multiple times to deny multiple functions.
+
+  -hcl-version=<version>
+    The HCL version used to parse the rendered job with -out-json, either 1
+    or 2. HCL2 jobs are parsed by the Nomad API at NOMAD_ADDR and require
+    Nomad 1.0 or later; use 1 for legacy job specifications. By default,
+    HCL2 is used when the cluster is running Nomad 1.0 or later and HCL1
+    otherwise.
+
+  -matrix=<variable>
+    Render the template once for each environment listed within the named
+    variable of the var-files, writing each to -out-dir. The variable must be
+    a list of maps, each with a unique name, whose variables are merged over
+    those of the var-files when rendering the environment.
+
   -out=<file>
     Specify the path to write the rendered template out to, if a file exists at
     the specified path it will be truncated before rendering. The template will be
     rendered to stdout if this is not set.
 
+  -out-dir=<dir>
+    The directory the environments of the -matrix flag are rendered to, with
+    each named by the environment, such as prod.nomad, or prod.json when used
+    with -out-json. The directory is created if it does not exist.
+
+  -out-json
+    Parse the rendered template and output the resulting Nomad job as JSON,
+    in the format used by the Nomad API, rather than the rendered HCL. HCL
+    parse errors are reported when rendering.
+
+  -remote-header=<key=value>
+    Add an HTTP header, such as Authorization, to the requests made when the
+    template or a var-file is an http(s) URL. You can repeat this flag
+    multiple times to add multiple headers.
+
+  -remote-timeout=<duration>
+    The time allowed to fetch each remote template or var-file, such as 10s.
+    The default is 30s.
+
   -var-file=<file>
     The variables file to render the template with. You can repeat this flag multiple
     times to supply multiple var-files. [default: levant.(json|yaml|yml|tf)]
+
+  -explain-vars
+    Log each template variable with the source which provided its final
+    value, such as the var-file, env or flag, and the sources it overrode.
+    The variables are logged before the template is rendered.
+
+  -var-precedence=<sources>
+    A comma separated list of the variable sources to merge, lowest precedence
+    first. Valid sources are file, env and flag; env variables are read from
+    the environment with the LEVANT_VAR_ prefix removed. Sources not listed
+    are not used. [default: file,flag]
 `
 	return strings.TrimSpace(helpText)
 }
@@ -58,17 +114,22 @@ func (c *RenderCommand) Synopsis() string {
 // Run triggers a run of the Levant template functions.
 func (c *RenderCommand) Run(args []string) int {
 
-	var addr, outPath, templateFile string
+	var addr, outPath, outDir, matrix, templateFile string
 	var variables []string
+	var outJSON bool
+	var hclVersion int
 	var err error
-	var tpl *bytes.Buffer
 
 	flags := c.Meta.FlagSet("render", FlagSetVars)
 	flags.Usage = func() { c.UI.Output(c.Help()) }
 
 	flags.StringVar(&addr, "consul-address", "", "")
 	flags.Var((*helper.FlagStringSlice)(&variables), "var-file", "")
+	flags.IntVar(&hclVersion, "hcl-version", template.HCLVersionAuto, "")
+	flags.StringVar(&matrix, "matrix", "", "")
 	flags.StringVar(&outPath, "out", "", "")
+	flags.StringVar(&outDir, "out-dir", "", "")
+	flags.BoolVar(&outJSON, "out-json", false, "")
 
 	if err = flags.Parse(args); err != nil {
 		return 1
@@ -89,11 +150,28 @@ func (c *RenderCommand) Run(args []string) int {
 		return 1
 	}
 
-	tpl, err = template.RenderTemplate(templateFile, variables, addr, &c.Meta.flagVars)
+	if (matrix == "") != (outDir == "") {
+		c.UI.Error(c.Help())
+		c.UI.Error("\nERROR: The -matrix and -out-dir flags must be used together")
+		return 1
+	}
+
+	if matrix != "" && outPath != "" {
+		c.UI.Error(c.Help())
+		c.UI.Error("\nERROR: Can not use -matrix and -out flag at the same time")
+		return 1
+	}
+
+	renderOpts, err := c.Meta.renderOptions()
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("[ERROR] levant/command: %v", err))
 		return 1
 	}
+	renderOpts.HCLVersion = hclVersion
+
+	if matrix != "" {
+		return c.renderMatrix(templateFile, variables, addr, matrix, outDir, outJSON, renderOpts)
+	}
 
 	out := os.Stdout
 	if outPath != "" {
@@ -102,13 +180,121 @@ func (c *RenderCommand) Run(args []string) int {
 			c.UI.Error(fmt.Sprintf("[ERROR] levant/command: %v", err))
 			return 1
 		}
+		defer out.Close()
 	}
 
-	_, err = tpl.WriteTo(out)
+	// Stream the rendered template to the output rather than holding the
+	// entire document in memory.
+	w := bufio.NewWriter(out)
+
+	if outJSON {
+		err = renderJobJSON(w, templateFile, variables, addr, &c.Meta.flagVars, renderOpts)
+	} else {
+		err = template.RenderTemplateTo(w, templateFile, variables, addr, &c.Meta.flagVars, renderOpts)
+	}
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("[ERROR] levant/command: %v", err))
+
+		// Remove the partially rendered output so it is not mistaken for a
+		// complete job.
+		if outPath != "" {
+			os.Remove(outPath)
+		}
+		return 1
+	}
+
+	if err = w.Flush(); err != nil {
+		c.UI.Error(fmt.Sprintf("[ERROR] levant/command: %v", err))
 		return 1
 	}
 
 	return 0
 }
+
+// renderMatrix renders the template for each environment of the matrix
+// variable to a file within the output directory. Each environment is
+// rendered even if another fails, with the failed environments reported.
+func (c *RenderCommand) renderMatrix(templateFile string, variableFiles []string, addr, matrix, outDir string,
+	outJSON bool, opts *template.RenderOptions) int {
+
+	envs, err := template.MatrixEnvironments(variableFiles, matrix, opts)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("[ERROR] levant/command: %v", err))
+		return 1
+	}
+
+	if err = os.MkdirAll(outDir, 0755); err != nil {
+		c.UI.Error(fmt.Sprintf("[ERROR] levant/command: %v", err))
+		return 1
+	}
+
+	ext := ".nomad"
+	if outJSON {
+		ext = ".json"
+	}
+
+	code := 0
+	for _, env := range envs {
+		envOpts := *opts
+		envOpts.OverlayVariables = env.Variables
+
+		path := filepath.Join(outDir, env.Name+ext)
+		if err := renderFile(path, templateFile, variableFiles, addr, &c.Meta.flagVars, outJSON, &envOpts); err != nil {
+			c.UI.Error(fmt.Sprintf("[ERROR] levant/command: unable to render environment %s: %v", env.Name, err))
+			code = 1
+			continue
+		}
+		c.UI.Output(fmt.Sprintf("Rendered environment %s to %s", env.Name, path))
+	}
+
+	return code
+}
+
+// renderFile renders the template to the file at path, removing the file if
+// rendering fails so it is not mistaken for a complete job.
+func renderFile(path, templateFile string, variableFiles []string, addr string, flagVars *map[string]string,
+	outJSON bool, opts *template.RenderOptions) error {
+
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	w := bufio.NewWriter(out)
+
+	if outJSON {
+		err = renderJobJSON(w, templateFile, variableFiles, addr, flagVars, opts)
+	} else {
+		err = template.RenderTemplateTo(w, templateFile, variableFiles, addr, flagVars, opts)
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		out.Close()
+		os.Remove(path)
+		return err
+	}
+
+	return nil
+}
+
+// renderJobJSON renders and parses the template, writing the resulting Nomad
+// job to w as indented JSON.
+func renderJobJSON(w io.Writer, templateFile string, variableFiles []string, addr string,
+	flagVars *map[string]string, opts *template.RenderOptions) error {
+
+	job, err := template.RenderJob(templateFile, variableFiles, addr, flagVars, opts)
+	if err != nil {
+		return err
+	}
+
+	out, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "%s\n", out)
+	return err
+}
@@ -0,0 +1,110 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/jrasell/levant/template"
+	"github.com/mitchellh/cli"
+)
+
+func TestRender_renderJobJSON(t *testing.T) {
+
+	var buf bytes.Buffer
+	fVars := make(map[string]string)
+
+	if err := renderJobJSON(&buf, "test-fixtures/job_canary.nomad", []string{}, "", &fVars, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var job nomad.Job
+	if err := json.Unmarshal(buf.Bytes(), &job); err != nil {
+		t.Fatalf("expected JSON job, got error %v: %s", err, buf.String())
+	}
+	if job.ID == nil || *job.ID != "example" {
+		t.Fatalf("expected job example, got %s", buf.String())
+	}
+
+	// Templates which do not parse as a job are reported at render time.
+	dir, err := ioutil.TempDir("", "levant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	invalid := filepath.Join(dir, "invalid.nomad")
+	if err := ioutil.WriteFile(invalid, []byte(`job "example" { group = }`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := renderJobJSON(&buf, invalid, []string{}, "", &fVars, nil); err == nil {
+		t.Fatal("expected parse error")
+	}
+}
+
+func TestRender_renderMatrix(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "levant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := &RenderCommand{Meta: Meta{UI: cli.NewMockUi(), flagVars: map[string]string{}}}
+
+	code := c.renderMatrix("test-fixtures/matrix.nomad", []string{"test-fixtures/matrix.yaml"}, "",
+		"environments", dir, true, &template.RenderOptions{})
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, c.UI.(*cli.MockUi).ErrorWriter.String())
+	}
+
+	expected := map[string]struct {
+		Count      int
+		Datacenter string
+	}{
+		"staging": {1, "dc1"},
+		"prod":    {3, "dc2"},
+	}
+
+	for name, e := range expected {
+		raw, err := ioutil.ReadFile(filepath.Join(dir, name+".json"))
+		if err != nil {
+			t.Fatalf("expected environment %s to be rendered: %v", name, err)
+		}
+
+		var job nomad.Job
+		if err := json.Unmarshal(raw, &job); err != nil {
+			t.Fatal(err)
+		}
+		if *job.ID != "example-"+name || *job.TaskGroups[0].Count != e.Count || job.Datacenters[0] != e.Datacenter {
+			t.Fatalf("unexpected job rendered for environment %s: %s", name, raw)
+		}
+	}
+
+	// Failed environments are identified and their output removed.
+	code = c.renderMatrix("test-fixtures/matrix.nomad", []string{"test-fixtures/matrix.yaml"}, "",
+		"missing", dir, false, &template.RenderOptions{})
+	if code != 1 {
+		t.Fatalf("expected exit code 1 for missing matrix variable, got %d", code)
+	}
+
+	c.Meta.flagVars["count"] = "invalid"
+	code = c.renderMatrix("test-fixtures/matrix.nomad", []string{"test-fixtures/matrix.yaml"}, "",
+		"environments", dir, true, &template.RenderOptions{
+			VarPrecedence: []string{"file", "flag"},
+		})
+	if code != 1 {
+		t.Fatalf("expected exit code 1, got %d", code)
+	}
+	if out := c.UI.(*cli.MockUi).ErrorWriter.String(); !strings.Contains(out, "unable to render environment staging") {
+		t.Fatalf("expected failed environment to be identified, got %s", out)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "staging.json")); !os.IsNotExist(err) {
+		t.Fatal("expected output of failed environment to be removed")
+	}
+}
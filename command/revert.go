@@ -0,0 +1,110 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/jrasell/levant/levant"
+	"github.com/jrasell/levant/levant/structs"
+	"github.com/jrasell/levant/logging"
+)
+
+// RevertCommand is the command implementation that allows users to revert a
+// Nomad job to a previous version.
+type RevertCommand struct {
+	Meta
+}
+
+// Help provides the help information for the revert command.
+func (c *RevertCommand) Help() string {
+	helpText := `
+Usage: levant revert [options] <job>
+
+  Revert a Nomad job to a previous version and watch the resulting deployment
+  until it completes. By default the job is reverted to the latest stable
+  version older than the current version. The exit code is 0 when the revert
+  is successful, 1 when it fails and 130 when the watch is interrupted.
+
+General Options:
+
+  -address=<http_address>
+    The Nomad HTTP API address including port which Levant will use to make
+    calls.
+
+  -allow-stale
+    Allow stale consistency mode for requests into nomad.
+
+  -header=<key=value>
+    Add a custom HTTP header to every Nomad API request, such as an
+    Authorization header required by a proxy in front of Nomad. You can
+    repeat this flag multiple times to add multiple headers.
+
+  -log-level=<level>
+    Specify the verbosity level of Levant's logs. Valid values include DEBUG,
+    INFO, and WARN, in decreasing order of verbosity. The default is INFO.
+
+  -log-format=<format>
+    Specify the format of Levant's logs. Valid values are HUMAN or JSON. The
+    default is HUMAN.
+
+Revert Options:
+
+  -cancel-on-interrupt
+    Fail the revert deployment in Nomad when Levant receives an interrupt or
+    terminate signal while watching it.
+
+  -to-version=<version>
+    The job version to revert to. The versions command can be used to list
+    the available versions.
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis is provides a brief summary of the revert command.
+func (c *RevertCommand) Synopsis() string {
+	return "Revert a Nomad job to a previous version"
+}
+
+// Run triggers a run of the Levant revert functions.
+func (c *RevertCommand) Run(args []string) int {
+
+	var logLevel, logFormat string
+	var toVersion uint64
+	config := &levant.RevertConfig{
+		Client: &structs.ClientConfig{},
+	}
+
+	flags := c.Meta.FlagSet("revert", FlagSetNomad)
+	flags.Usage = func() { c.UI.Output(c.Help()) }
+	flags.StringVar(&config.Client.Addr, "address", "", "")
+	flags.BoolVar(&config.Client.AllowStale, "allow-stale", false, "")
+	flags.BoolVar(&config.CancelOnInterrupt, "cancel-on-interrupt", false, "")
+	flags.StringVar(&logLevel, "log-level", "INFO", "")
+	flags.StringVar(&logFormat, "log-format", "human", "")
+	flags.Uint64Var(&toVersion, "to-version", 0, "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	config.Client.Headers = c.Meta.nomadHeaders
+
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "to-version" {
+			config.ToVersion = &toVersion
+		}
+	})
+
+	args = flags.Args()
+	if len(args) != 1 {
+		c.UI.Error(c.Help())
+		return 1
+	}
+	config.Job = args[0]
+
+	if err := logging.SetupLogger(logLevel, logFormat); err != nil {
+		c.UI.Error(fmt.Sprintf("Error setting up logging: %v", err))
+	}
+
+	return deployErrorExitCode(levant.TriggerRevert(config))
+}
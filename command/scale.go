@@ -0,0 +1,59 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jrasell/levant/scale"
+	"github.com/mitchellh/cli"
+)
+
+const (
+	outputFormatHuman = "HUMAN"
+	outputFormatJSON  = "JSON"
+)
+
+// scalingResultOutput is the machine-readable representation of a single task
+// group count change emitted by the scale commands.
+type scalingResultOutput struct {
+	Job           string `json:"job"`
+	Group         string `json:"group"`
+	PreviousCount int    `json:"previous_count"`
+	NewCount      int    `json:"new_count"`
+	EvalID        string `json:"eval_id"`
+}
+
+// validateOutputFormat checks the passed output format is supported.
+func validateOutputFormat(format string) error {
+	switch strings.ToUpper(format) {
+	case outputFormatHuman, outputFormatJSON:
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format: %q (supported formats: %s %s)",
+			format, outputFormatHuman, outputFormatJSON)
+	}
+}
+
+// outputScalingResult writes a JSON line for each scaled task group when the
+// JSON output format has been requested. The human format relies on the logs.
+func outputScalingResult(ui cli.Ui, format string, res *scale.Result) error {
+	if res == nil || strings.ToUpper(format) != outputFormatJSON {
+		return nil
+	}
+
+	for _, g := range res.Groups {
+		out, err := json.Marshal(&scalingResultOutput{
+			Job:           res.JobID,
+			Group:         g.Group,
+			PreviousCount: g.PreviousCount,
+			NewCount:      g.NewCount,
+			EvalID:        res.EvalID,
+		})
+		if err != nil {
+			return err
+		}
+		ui.Output(string(out))
+	}
+	return nil
+}
@@ -29,7 +29,18 @@ General Options:
 
   -allow-stale
     Allow stale consistency mode for requests into nomad.
+
+  -format=<format>
+    Specify the format of the scaling result output. Valid values are HUMAN or
+    JSON. When JSON is used a line detailing the job, group, previous and new
+    counts, and evaluation ID is written for each scaled group. The default is
+    HUMAN.
   
+  -header=<key=value>
+    Add a custom HTTP header to every Nomad API request, such as an
+    Authorization header required by a proxy in front of Nomad. You can
+    repeat this flag multiple times to add multiple headers.
+
   -log-level=<level>
     Specify the verbosity level of Levant's logs. Valid values include DEBUG,
     INFO, and WARN, in decreasing order of verbosity. The default is INFO.
@@ -52,6 +63,11 @@ Scale Out Options:
   -task-group=<name>
     The name of the task group you wish to target for scaling. Is this is not
     specified all task groups within the job will be scaled.
+
+  -wait
+    Wait for the scaling evaluation to complete, reporting the number of
+    allocations placed and stopped. The command fails if the evaluation is
+    blocked due to the cluster having insufficient resources.
 `
 	return strings.TrimSpace(helpText)
 }
@@ -65,7 +81,7 @@ func (c *ScaleOutCommand) Synopsis() string {
 func (c *ScaleOutCommand) Run(args []string) int {
 
 	var err error
-	var logL, logF string
+	var logL, logF, format string
 
 	config := &scale.Config{
 		Client: &structs.ClientConfig{},
@@ -74,20 +90,23 @@ func (c *ScaleOutCommand) Run(args []string) int {
 		},
 	}
 
-	flags := c.Meta.FlagSet("scale-out", FlagSetVars)
+	flags := c.Meta.FlagSet("scale-out", FlagSetVars|FlagSetNomad)
 	flags.Usage = func() { c.UI.Output(c.Help()) }
 
 	flags.StringVar(&config.Client.Addr, "address", "", "")
 	flags.BoolVar(&config.Client.AllowStale, "allow-stale", false, "")
+	flags.StringVar(&format, "format", outputFormatHuman, "")
 	flags.StringVar(&logL, "log-level", "INFO", "")
 	flags.StringVar(&logF, "log-format", "HUMAN", "")
 	flags.IntVar(&config.Scale.Count, "count", 0, "")
 	flags.IntVar(&config.Scale.Percent, "percent", 0, "")
 	flags.StringVar(&config.Scale.TaskGroup, "task-group", "", "")
+	flags.BoolVar(&config.Scale.Wait, "wait", false, "")
 
 	if err = flags.Parse(args); err != nil {
 		return 1
 	}
+	config.Client.Headers = c.Meta.nomadHeaders
 
 	args = flags.Args()
 
@@ -111,15 +130,25 @@ func (c *ScaleOutCommand) Run(args []string) int {
 		config.Scale.DirectionType = structs.ScalingDirectionTypePercent
 	}
 
+	if err = validateOutputFormat(format); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
 	if err = logging.SetupLogger(logL, logF); err != nil {
 		c.UI.Error(err.Error())
 		return 1
 	}
 
-	success := scale.TriggerScalingEvent(config)
+	res, success := scale.TriggerScalingEvent(config)
 	if !success {
 		return 1
 	}
 
+	if err = outputScalingResult(c.UI, format, res); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
 	return 0
 }
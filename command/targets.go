@@ -0,0 +1,203 @@
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jrasell/levant/levant"
+	"github.com/jrasell/levant/levant/structs"
+	"github.com/jrasell/levant/template"
+	"github.com/mitchellh/cli"
+	"github.com/rs/zerolog/log"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// deployTarget is a single Nomad cluster, region and namespace combination
+// the rendered job is deployed to.
+type deployTarget struct {
+	Name      string `yaml:"name"`
+	Address   string `yaml:"address"`
+	Region    string `yaml:"region"`
+	Namespace string `yaml:"namespace"`
+}
+
+// deployTargetsFile is the targets config file, written as either YAML or
+// JSON.
+type deployTargetsFile struct {
+	Targets []*deployTarget `yaml:"targets"`
+}
+
+// loadDeployTargets reads the targets config file, returning the targets in
+// the order they are listed. Targets without a name are named from their
+// address, region and namespace, and every name must be unique.
+func loadDeployTargets(path string) ([]*deployTarget, error) {
+
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read targets file %s: %v", path, err)
+	}
+
+	f := &deployTargetsFile{}
+	if err := yaml.UnmarshalStrict(raw, f); err != nil {
+		return nil, fmt.Errorf("unable to parse targets file %s: %v", path, err)
+	}
+
+	if len(f.Targets) == 0 {
+		return nil, fmt.Errorf("targets file %s does not list any targets", path)
+	}
+
+	seen := make(map[string]bool, len(f.Targets))
+	for i, t := range f.Targets {
+		if t == nil || (t.Address == "" && t.Region == "" && t.Namespace == "") {
+			return nil, fmt.Errorf("target %d of %s must set at least one of address, region or namespace", i, path)
+		}
+		if t.Name == "" {
+			t.Name = t.defaultName()
+		}
+		if seen[t.Name] {
+			return nil, fmt.Errorf("target %s is listed more than once within %s", t.Name, path)
+		}
+		seen[t.Name] = true
+	}
+
+	return f.Targets, nil
+}
+
+// defaultName names the target from its address, region and namespace.
+func (t *deployTarget) defaultName() string {
+	var parts []string
+	for _, p := range []string{t.Address, t.Region, t.Namespace} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// applyTarget points the client config, and the copy of the rendered job
+// deployed to the target, at the address, region and namespace of the
+// target. Fields the target does not set are left unchanged.
+func (t *deployTarget) applyTarget(clientConfig *structs.ClientConfig, tmplConfig *structs.TemplateConfig) {
+	if t.Address != "" {
+		clientConfig.Addr = t.Address
+	}
+	if t.Region != "" {
+		clientConfig.Region = t.Region
+		tmplConfig.Job.Region = &t.Region
+	}
+	if t.Namespace != "" {
+		clientConfig.Namespace = t.Namespace
+		tmplConfig.Job.Namespace = &t.Namespace
+	}
+}
+
+// applyRenderTarget points the Nomad client used while rendering at the
+// address, region and namespace of the target. The job is rendered once, so
+// when deploying to multiple targets the first target is used.
+func (t *deployTarget) applyRenderTarget(opts *template.RenderOptions) {
+	opts.NomadAddr = t.Address
+	opts.NomadRegion = t.Region
+	opts.NomadNamespace = t.Namespace
+}
+
+// deployTargets plans and deploys the rendered job against each target, with
+// up to parallel targets at once, and outputs a table of the status of each.
+// Each target is given its own copy of the job as the deployment updates it.
+func (c *DeployCommand) deployTargets(config *levant.DeployConfig, opts deployOptions, targets []*deployTarget,
+	failFast bool, parallel int) int {
+
+	byName := make(map[string]*deployTarget, len(targets))
+	for _, t := range targets {
+		byName[t.Name] = t
+	}
+
+	if parallel > 1 {
+		defer func(ui cli.Ui) { c.UI = ui }(c.UI)
+		c.UI = &cli.ConcurrentUi{Ui: c.UI}
+	}
+
+	names := targetNames(targets)
+	codes := runConcurrently(c.UI, "Deploying to target", names, failFast, parallel, func(name string) int {
+		tmplConfig, err := clusterTemplateConfig(config.Template)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("[ERROR] levant/command: %v", err))
+			return 1
+		}
+
+		clientConfig := *config.Client
+		byName[name].applyTarget(&clientConfig, tmplConfig)
+
+		// Each target logs with its own logger so the log lines of targets
+		// deployed in parallel can be told apart.
+		logger := log.With().Str(structs.TargetContextField, name).Logger()
+
+		deploy := *config.Deploy
+		return c.deploy(&levant.DeployConfig{
+			Client:   &clientConfig,
+			Deploy:   &deploy,
+			Plan:     config.Plan,
+			Template: tmplConfig,
+			Logger:   &logger,
+		}, opts)
+	})
+
+	outputTargetResults(c.UI, targets, codes)
+	return firstExitCode(names, codes)
+}
+
+// targetAddrs returns the addresses set by the targets, in order.
+func targetAddrs(targets []*deployTarget) []string {
+	var addrs []string
+	for _, t := range targets {
+		if t.Address != "" {
+			addrs = append(addrs, t.Address)
+		}
+	}
+	return addrs
+}
+
+// targetNames returns the names of the targets in order.
+func targetNames(targets []*deployTarget) []string {
+	names := make([]string, len(targets))
+	for i, t := range targets {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// outputTargetResults outputs a table of the status of each target, in the
+// order the targets are listed.
+func outputTargetResults(ui cli.Ui, targets []*deployTarget, codes map[string]int) {
+
+	var buf strings.Builder
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "Target\tAddress\tRegion\tNamespace\tStatus")
+	for _, t := range targets {
+		status := "successful"
+		code, ok := codes[t.Name]
+		switch {
+		case !ok:
+			status = "skipped"
+		case code != 0:
+			status = fmt.Sprintf("failed with exit code %d", code)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", t.Name, targetField(t.Address), targetField(t.Region),
+			targetField(t.Namespace), status)
+	}
+	w.Flush()
+
+	ui.Output("==> Target results:")
+	ui.Output(strings.TrimSpace(buf.String()))
+}
+
+// targetField returns the value of a target field for the results table,
+// using a dash for fields the target does not set.
+func targetField(v string) string {
+	if v == "" {
+		return "-"
+	}
+	return v
+}
@@ -0,0 +1,92 @@
+package command
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/jrasell/levant/levant/structs"
+	"github.com/mitchellh/cli"
+)
+
+func TestTargets_loadDeployTargets(t *testing.T) {
+
+	targets, err := loadDeployTargets("test-fixtures/targets.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"us-east-prod", "http://nomad.eu-west:4646/eu-west", "us-east-staging"}
+	if names := targetNames(targets); !reflect.DeepEqual(names, expected) {
+		t.Fatalf("expected targets %v, got %v", expected, names)
+	}
+
+	expectedAddrs := []string{"http://nomad.us-east:4646", "http://nomad.eu-west:4646"}
+	if addrs := targetAddrs(targets); !reflect.DeepEqual(addrs, expectedAddrs) {
+		t.Fatalf("expected addresses %v, got %v", expectedAddrs, addrs)
+	}
+
+	if _, err := loadDeployTargets("test-fixtures/matrix.yaml"); err == nil {
+		t.Fatal("expected error for a file without targets")
+	}
+}
+
+func TestTargets_applyTarget(t *testing.T) {
+
+	clientConfig := &structs.ClientConfig{Addr: "http://127.0.0.1:4646", ConsulAddr: "127.0.0.1:8500"}
+	tmplConfig := &structs.TemplateConfig{Job: &nomad.Job{Region: stringToPtr("global")}}
+
+	target := &deployTarget{Name: "prod", Namespace: "prod"}
+	target.applyTarget(clientConfig, tmplConfig)
+
+	if clientConfig.Addr != "http://127.0.0.1:4646" || clientConfig.Namespace != "prod" || clientConfig.Region != "" {
+		t.Fatalf("unexpected client config %+v", clientConfig)
+	}
+	if *tmplConfig.Job.Namespace != "prod" || *tmplConfig.Job.Region != "global" {
+		t.Fatalf("unexpected job region %s and namespace %s", *tmplConfig.Job.Region, *tmplConfig.Job.Namespace)
+	}
+}
+
+func TestTargets_runConcurrently(t *testing.T) {
+
+	names := []string{"a", "b", "c", "d"}
+	codes := map[string]int{"a": 0, "b": 2, "c": 0, "d": 0}
+
+	ui := cli.NewMockUi()
+	var mu sync.Mutex
+	var ran []string
+
+	out := runConcurrently(&cli.ConcurrentUi{Ui: ui}, "Deploying to target", names, false, 3, func(name string) int {
+		mu.Lock()
+		ran = append(ran, name)
+		mu.Unlock()
+		return codes[name]
+	})
+
+	if len(ran) != len(names) || !reflect.DeepEqual(out, codes) {
+		t.Fatalf("expected every target to run, got %v", out)
+	}
+	if code := firstExitCode(names, out); code != 2 {
+		t.Fatalf("expected exit code 2 but got %d", code)
+	}
+
+	// Running in turn with fail fast skips the targets after the failure.
+	out = runConcurrently(ui, "Deploying to target", names, true, 1, func(name string) int {
+		return codes[name]
+	})
+	if !reflect.DeepEqual(out, map[string]int{"a": 0, "b": 2}) {
+		t.Fatalf("expected targets after the failure to be skipped, got %v", out)
+	}
+
+	targets := []*deployTarget{{Name: "a", Address: "http://a:4646"}, {Name: "b", Region: "eu"}, {Name: "c"}}
+	outputTargetResults(ui, targets, out)
+
+	output := ui.OutputWriter.String()
+	for _, s := range []string{"Target results", "http://a:4646", "failed with exit code 2", "skipped"} {
+		if !strings.Contains(output, s) {
+			t.Fatalf("expected output to contain %q, got:\n%s", s, output)
+		}
+	}
+}
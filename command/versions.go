@@ -0,0 +1,174 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jrasell/levant/levant"
+	"github.com/jrasell/levant/logging"
+	"github.com/mitchellh/cli"
+)
+
+// versionsSummaryLimit is the number of changes listed in the human output
+// before the remainder are summarised as a count.
+const versionsSummaryLimit = 3
+
+// VersionsCommand is the command implementation that allows users to list the
+// versions of a Nomad job.
+type VersionsCommand struct {
+	Meta
+}
+
+// jobVersionOutput is the machine-readable representation of a single job
+// version emitted by the versions command.
+type jobVersionOutput struct {
+	Version    uint64    `json:"version"`
+	Stable     bool      `json:"stable"`
+	SubmitTime time.Time `json:"submit_time"`
+	Changes    []string  `json:"changes"`
+}
+
+// Help provides the help information for the versions command.
+func (c *VersionsCommand) Help() string {
+	helpText := `
+Usage: levant versions [options] <job>
+
+  List all versions of a Nomad job along with their stability, submit time
+  and a summary of the changes from the previous version. This can be used
+  to identify a suitable version to revert to.
+
+General Options:
+
+  -address=<http_address>
+    The Nomad HTTP API address including port which Levant will use to make
+    calls.
+
+  -allow-stale
+    Allow stale consistency mode for requests into nomad.
+
+  -header=<key=value>
+    Add a custom HTTP header to every Nomad API request, such as an
+    Authorization header required by a proxy in front of Nomad. You can
+    repeat this flag multiple times to add multiple headers.
+
+  -log-level=<level>
+    Specify the verbosity level of Levant's logs. Valid values include DEBUG,
+    INFO, and WARN, in decreasing order of verbosity. The default is INFO.
+
+  -log-format=<format>
+    Specify the format of Levant's logs. Valid values are HUMAN or JSON. The
+    default is HUMAN.
+
+Versions Options:
+
+  -format=<format>
+    Specify the format of the versions output. Valid values are HUMAN or
+    JSON. The JSON format writes a single line for each version. The
+    default is HUMAN.
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis is provides a brief summary of the versions command.
+func (c *VersionsCommand) Synopsis() string {
+	return "List the versions of a Nomad job"
+}
+
+// Run triggers a run of the Levant versions functions.
+func (c *VersionsCommand) Run(args []string) int {
+
+	var addr, logLevel, logFormat, format string
+	var allowStale bool
+
+	flags := c.Meta.FlagSet("versions", FlagSetNomad)
+	flags.Usage = func() { c.UI.Output(c.Help()) }
+	flags.StringVar(&addr, "address", "", "")
+	flags.BoolVar(&allowStale, "allow-stale", false, "")
+	flags.StringVar(&format, "format", outputFormatHuman, "")
+	flags.StringVar(&logLevel, "log-level", "INFO", "")
+	flags.StringVar(&logFormat, "log-format", "human", "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = flags.Args()
+	if len(args) != 1 {
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	if err := validateOutputFormat(format); err != nil {
+		c.UI.Error(fmt.Sprintf("[ERROR] levant/command: %v", err))
+		return 1
+	}
+
+	if err := logging.SetupLogger(logLevel, logFormat); err != nil {
+		c.UI.Error(fmt.Sprintf("Error setting up logging: %v", err))
+	}
+
+	versions, err := levant.TriggerVersions(args[0], c.Meta.clientConfig(addr, allowStale))
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("[ERROR] levant/command: %v", err))
+		return 1
+	}
+
+	if err := outputJobVersions(c.UI, format, versions); err != nil {
+		c.UI.Error(fmt.Sprintf("[ERROR] levant/command: %v", err))
+		return 1
+	}
+
+	return 0
+}
+
+// outputJobVersions writes the job versions to the UI in the requested format.
+func outputJobVersions(ui cli.Ui, format string, versions []*levant.JobVersion) error {
+
+	if strings.ToUpper(format) == outputFormatJSON {
+		for _, v := range versions {
+			out, err := json.Marshal(&jobVersionOutput{
+				Version:    v.Version,
+				Stable:     v.Stable,
+				SubmitTime: v.SubmitTime,
+				Changes:    v.Changes,
+			})
+			if err != nil {
+				return err
+			}
+			ui.Output(string(out))
+		}
+		return nil
+	}
+
+	var buf strings.Builder
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "Version\tStable\tSubmit Date\tChanges")
+	for _, v := range versions {
+		fmt.Fprintf(w, "%d\t%t\t%s\t%s\n",
+			v.Version, v.Stable, v.SubmitTime.Format(time.RFC3339), summariseChanges(v.Changes))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	ui.Output(strings.TrimSpace(buf.String()))
+	return nil
+}
+
+// summariseChanges provides a short description of the changes made within a
+// job version for the human output.
+func summariseChanges(changes []string) string {
+	switch {
+	case len(changes) == 0:
+		return "-"
+	case len(changes) <= versionsSummaryLimit:
+		return strings.Join(changes, ", ")
+	default:
+		return fmt.Sprintf("%s and %d more", strings.Join(changes[:versionsSummaryLimit], ", "),
+			len(changes)-versionsSummaryLimit)
+	}
+}
@@ -0,0 +1,111 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jrasell/levant/levant"
+	"github.com/jrasell/levant/logging"
+)
+
+// interruptedExitCode is the exit code used when Levant stops watching a
+// deployment after receiving an interrupt or terminate signal, following the
+// shell convention for processes ended by SIGINT.
+const interruptedExitCode = 130
+
+// WatchCommand is the command implementation that allows users to watch an
+// existing Nomad deployment until it completes.
+type WatchCommand struct {
+	Meta
+}
+
+// Help provides the help information for the watch command.
+func (c *WatchCommand) Help() string {
+	helpText := `
+Usage: levant watch [options] <deployment-id>
+
+  Watch an existing Nomad deployment until it completes, such as one whose
+  watch was interrupted during levant deploy. The exit code is 0 when the
+  deployment is successful, 1 when it fails and 130 when the watch is
+  interrupted.
+
+General Options:
+
+  -address=<http_address>
+    The Nomad HTTP API address including port which Levant will use to make
+    calls.
+
+  -allow-stale
+    Allow stale consistency mode for requests into nomad.
+
+  -header=<key=value>
+    Add a custom HTTP header to every Nomad API request, such as an
+    Authorization header required by a proxy in front of Nomad. You can
+    repeat this flag multiple times to add multiple headers.
+
+  -log-level=<level>
+    Specify the verbosity level of Levant's logs. Valid values include DEBUG,
+    INFO, and WARN, in decreasing order of verbosity. The default is INFO.
+
+  -log-format=<format>
+    Specify the format of Levant's logs. Valid values are HUMAN or JSON. The
+    default is HUMAN.
+
+Watch Options:
+
+  -cancel-on-interrupt
+    Fail the deployment in Nomad when Levant receives an interrupt or
+    terminate signal while watching it.
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis is provides a brief summary of the watch command.
+func (c *WatchCommand) Synopsis() string {
+	return "Watch a Nomad deployment until it completes"
+}
+
+// Run triggers a run of the Levant watch functions.
+func (c *WatchCommand) Run(args []string) int {
+
+	var addr, logLevel, logFormat string
+	var allowStale, cancelOnInterrupt bool
+
+	flags := c.Meta.FlagSet("watch", FlagSetNomad)
+	flags.Usage = func() { c.UI.Output(c.Help()) }
+	flags.StringVar(&addr, "address", "", "")
+	flags.BoolVar(&allowStale, "allow-stale", false, "")
+	flags.BoolVar(&cancelOnInterrupt, "cancel-on-interrupt", false, "")
+	flags.StringVar(&logLevel, "log-level", "INFO", "")
+	flags.StringVar(&logFormat, "log-format", "human", "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = flags.Args()
+	if len(args) != 1 {
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	if err := logging.SetupLogger(logLevel, logFormat); err != nil {
+		c.UI.Error(fmt.Sprintf("Error setting up logging: %v", err))
+	}
+
+	return deployErrorExitCode(levant.TriggerWatch(args[0], c.Meta.clientConfig(addr, allowStale), cancelOnInterrupt))
+}
+
+// deployErrorExitCode returns the exit code for the error returned from a
+// deployment or watch.
+func deployErrorExitCode(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, levant.ErrDeployInterrupted):
+		return interruptedExitCode
+	default:
+		return 1
+	}
+}
@@ -0,0 +1,27 @@
+package command
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/jrasell/levant/levant"
+)
+
+func TestWatch_deployErrorExitCode(t *testing.T) {
+
+	cases := []struct {
+		Err      error
+		Expected int
+	}{
+		{nil, 0},
+		{fmt.Errorf("%w: deployment abc", levant.ErrDeployInterrupted), interruptedExitCode},
+		{fmt.Errorf("%w: deployment abc did not succeed", levant.ErrDeployFailed), 1},
+		{levant.ErrDeployTimeout, 1},
+	}
+
+	for i, tc := range cases {
+		if code := deployErrorExitCode(tc.Err); code != tc.Expected {
+			t.Fatalf("case %d: got exit code %d, expected %d", i, code, tc.Expected)
+		}
+	}
+}
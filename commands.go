@@ -26,6 +26,11 @@ func Commands(metaPtr *command.Meta) map[string]cli.CommandFactory {
 
 	return map[string]cli.CommandFactory{
 
+		"acl-policy-apply": func() (cli.Command, error) {
+			return &command.ACLPolicyApplyCommand{
+				Meta: meta,
+			}, nil
+		},
 		"deploy": func() (cli.Command, error) {
 			return &command.DeployCommand{
 				Meta: meta,
@@ -36,16 +41,31 @@ func Commands(metaPtr *command.Meta) map[string]cli.CommandFactory {
 				Meta: meta,
 			}, nil
 		},
+		"namespace-apply": func() (cli.Command, error) {
+			return &command.NamespaceApplyCommand{
+				Meta: meta,
+			}, nil
+		},
 		"plan": func() (cli.Command, error) {
 			return &command.PlanCommand{
 				Meta: meta,
 			}, nil
 		},
+		"promote": func() (cli.Command, error) {
+			return &command.PromoteCommand{
+				Meta: meta,
+			}, nil
+		},
 		"render": func() (cli.Command, error) {
 			return &command.RenderCommand{
 				Meta: meta,
 			}, nil
 		},
+		"revert": func() (cli.Command, error) {
+			return &command.RevertCommand{
+				Meta: meta,
+			}, nil
+		},
 		"scale-in": func() (cli.Command, error) {
 			return &command.ScaleInCommand{
 				Meta: meta,
@@ -56,6 +76,16 @@ func Commands(metaPtr *command.Meta) map[string]cli.CommandFactory {
 				Meta: meta,
 			}, nil
 		},
+		"versions": func() (cli.Command, error) {
+			return &command.VersionsCommand{
+				Meta: meta,
+			}, nil
+		},
+		"watch": func() (cli.Command, error) {
+			return &command.WatchCommand{
+				Meta: meta,
+			}, nil
+		},
 		"version": func() (cli.Command, error) {
 			ver := version.Version
 			rel := version.VersionPrerelease
@@ -5,18 +5,19 @@ go 1.13
 require (
 	github.com/apparentlymart/go-cidr v0.0.0-20170616213631-2bd8b58cf427 // indirect
 	github.com/armon/go-radix v0.0.0-20170727155443-1fca145dffbc // indirect
-	github.com/aws/aws-sdk-go v1.10.46 // indirect
+	github.com/aws/aws-sdk-go v1.10.46
 	github.com/bgentry/go-netrc v0.0.0-20140422174119-9fd32a8b3d3d // indirect
 	github.com/bgentry/speakeasy v0.1.0 // indirect
 	github.com/blang/semver v3.5.1+incompatible // indirect
 	github.com/go-ini/ini v1.28.2 // indirect
 	github.com/golang/protobuf v1.3.1 // indirect
 	github.com/hashicorp/consul v0.9.3
+	github.com/hashicorp/go-cleanhttp v0.5.1
 	github.com/hashicorp/go-getter v0.0.0-20170914154444-56c651a79a6e // indirect
 	github.com/hashicorp/go-hclog v0.8.0 // indirect
 	github.com/hashicorp/go-plugin v1.0.0 // indirect
-	github.com/hashicorp/go-version v0.0.0-20170914154128-fc61389e27c7 // indirect
-	github.com/hashicorp/hcl v0.0.0-20170914154624-68e816d1c783 // indirect
+	github.com/hashicorp/go-version v0.0.0-20170914154128-fc61389e27c7
+	github.com/hashicorp/hcl v0.0.0-20170914154624-68e816d1c783
 	github.com/hashicorp/hil v0.0.0-20170627220502-fa9f258a9250 // indirect
 	github.com/hashicorp/logutils v1.0.0 // indirect
 	github.com/hashicorp/memberlist v0.1.5 // indirect
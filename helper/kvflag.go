@@ -44,3 +44,36 @@ func (v *FlagStringSlice) Set(raw string) error {
 	*v = append(*v, raw)
 	return nil
 }
+
+// FlagOptionalString is a flag.Value implementation for flags which can be
+// passed either as a boolean switch or with a value, e.g. -keep-rendered or
+// -keep-rendered=path.
+type FlagOptionalString struct {
+	Enabled bool
+	Value   string
+}
+
+func (v *FlagOptionalString) String() string {
+	return v.Value
+}
+
+// IsBoolFlag allows the flag to be passed without a value.
+func (v *FlagOptionalString) IsBoolFlag() bool {
+	return true
+}
+
+// Set enables the flag and stores the passed value, if any.
+func (v *FlagOptionalString) Set(raw string) error {
+	switch raw {
+	case "true":
+		v.Enabled = true
+		v.Value = ""
+	case "false":
+		v.Enabled = false
+		v.Value = ""
+	default:
+		v.Enabled = true
+		v.Value = raw
+	}
+	return nil
+}
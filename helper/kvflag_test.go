@@ -1,6 +1,7 @@
 package helper
 
 import (
+	"flag"
 	"reflect"
 	"testing"
 )
@@ -49,3 +50,45 @@ func TestHelper_Set(t *testing.T) {
 		}
 	}
 }
+
+func TestHelper_FlagOptionalString(t *testing.T) {
+	cases := []struct {
+		Args    []string
+		Enabled bool
+		Value   string
+	}{
+		{
+			[]string{},
+			false,
+			"",
+		},
+		{
+			[]string{"-opt"},
+			true,
+			"",
+		},
+		{
+			[]string{"-opt=/tmp/job.json"},
+			true,
+			"/tmp/job.json",
+		},
+		{
+			[]string{"-opt=false"},
+			false,
+			"",
+		},
+	}
+
+	for _, tc := range cases {
+		f := &FlagOptionalString{}
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.Var(f, "opt", "")
+
+		if err := fs.Parse(tc.Args); err != nil {
+			t.Fatalf("unexpected error parsing %v: %v", tc.Args, err)
+		}
+		if f.Enabled != tc.Enabled || f.Value != tc.Value {
+			t.Fatalf("got: %#v, expected enabled %v and value %q", f, tc.Enabled, tc.Value)
+		}
+	}
+}
@@ -1,30 +1,115 @@
 package helper
 
 import (
+	"fmt"
+	"os"
+	"strings"
+
 	"github.com/rs/zerolog/log"
 )
 
+// The variable sources which can be ordered using the variable precedence.
+const (
+	VarSourceFile = "file"
+	VarSourceEnv  = "env"
+	VarSourceFlag = "flag"
+
+	// EnvVarPrefix is the prefix environment variables must use to be
+	// included as template variables; the prefix is removed from the key.
+	EnvVarPrefix = "LEVANT_VAR_"
+)
+
+// DefaultVarPrecedence is the order, lowest precedence first, in which the
+// variable sources are merged when no precedence is specified.
+var DefaultVarPrecedence = []string{VarSourceFile, VarSourceFlag}
+
 // VariableMerge merges the passed file variables with the flag variabes to
-// provide a single set of variables. The flagVars will always prevale over file
-// variables.
+// provide a single set of variables, using the DefaultVarPrecedence so the
+// flagVars will always prevale over file variables.
 func VariableMerge(fileVars *map[string]interface{}, flagVars *map[string]string) map[string]interface{} {
 
-	out := make(map[string]interface{})
-
+	flags := make(map[string]interface{}, len(*flagVars))
 	for k, v := range *flagVars {
-		log.Info().Msgf("helper/variable: using command line variable with key %s and value %s", k, v)
-		out[k] = v
+		flags[k] = v
+	}
+
+	out, _ := VariableMergeOrdered(DefaultVarPrecedence, map[string]map[string]interface{}{
+		VarSourceFile: *fileVars,
+		VarSourceFlag: flags,
+	})
+	return out
+}
+
+// ParseVarPrecedence parses a comma separated list of variable sources, lowest
+// precedence first. Sources not included in the list are not used.
+func ParseVarPrecedence(p string) ([]string, error) {
+
+	var out []string
+	seen := make(map[string]bool)
+
+	for _, s := range strings.Split(p, ",") {
+		s = strings.ToLower(strings.TrimSpace(s))
+
+		switch s {
+		case VarSourceFile, VarSourceEnv, VarSourceFlag:
+		default:
+			return nil, fmt.Errorf("variable source %q is invalid; must be one of %s, %s or %s",
+				s, VarSourceFile, VarSourceEnv, VarSourceFlag)
+		}
+
+		if seen[s] {
+			return nil, fmt.Errorf("variable source %q is listed more than once", s)
+		}
+		seen[s] = true
+		out = append(out, s)
 	}
 
-	for k, v := range *fileVars {
-		if _, ok := out[k]; ok {
-			log.Debug().Msgf("helper/variable: variable from file with key %s and value %s overridden by CLI var",
-				k, v)
+	return out, nil
+}
+
+// EnvVariables returns the environment variables which have the EnvVarPrefix
+// with the prefix removed from the key.
+func EnvVariables() map[string]interface{} {
+
+	out := make(map[string]interface{})
+
+	for _, e := range os.Environ() {
+		if !strings.HasPrefix(e, EnvVarPrefix) {
 			continue
 		}
-		log.Info().Msgf("helper/variable: using variable with key %s and value %v from file", k, v)
-		out[k] = v
+
+		split := strings.SplitN(strings.TrimPrefix(e, EnvVarPrefix), "=", 2)
+		if len(split) != 2 || split[0] == "" {
+			continue
+		}
+		out[split[0]] = split[1]
 	}
 
 	return out
 }
+
+// VariableMergeOrdered merges the variable sources in the order given by the
+// precedence; each source overrides the variables of the sources before it.
+// The origins of each variable are the sources which set it in the order
+// they were merged, the last of which provided the final value.
+func VariableMergeOrdered(precedence []string, sources map[string]map[string]interface{}) (map[string]interface{}, map[string][]string) {
+
+	out := make(map[string]interface{})
+	origins := make(map[string][]string)
+
+	for _, p := range precedence {
+		for k, v := range sources[p] {
+			if o, ok := origins[k]; ok {
+				log.Debug().Msgf("helper/variable: %s variable with key %s overridden by %s variable", o[len(o)-1], k, p)
+			}
+			out[k] = v
+			origins[k] = append(origins[k], p)
+		}
+	}
+
+	for k, o := range origins {
+		log.Info().Msgf("helper/variable: using %s variable with key %s and value %v", o[len(o)-1], k, out[k])
+	}
+
+	return out, origins
+}
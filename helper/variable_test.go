@@ -1,6 +1,7 @@
 package helper
 
 import (
+	"os"
 	"reflect"
 	"testing"
 )
@@ -26,3 +27,96 @@ func TestHelper_VariableMerge(t *testing.T) {
 		t.Fatalf("expected \n%#v\n\n, got \n\n%#v\n\n", expected, res)
 	}
 }
+
+func TestHelper_ParseVarPrecedence(t *testing.T) {
+
+	cases := []struct {
+		Input    string
+		Expected []string
+		Error    bool
+	}{
+		{"file,flag", []string{VarSourceFile, VarSourceFlag}, false},
+		{"flag, env ,FILE", []string{VarSourceFlag, VarSourceEnv, VarSourceFile}, false},
+		{"env", []string{VarSourceEnv}, false},
+		{"file,file", nil, true},
+		{"file,consul", nil, true},
+		{"", nil, true},
+	}
+
+	for i, tc := range cases {
+		res, err := ParseVarPrecedence(tc.Input)
+		if (err != nil) != tc.Error {
+			t.Fatalf("case %d: unexpected error result: %v", i, err)
+		}
+		if !reflect.DeepEqual(res, tc.Expected) {
+			t.Fatalf("case %d: got %v, expected %v", i, res, tc.Expected)
+		}
+	}
+}
+
+func TestHelper_VariableMergeOrdered(t *testing.T) {
+
+	sources := map[string]map[string]interface{}{
+		VarSourceFile: {"image": "file", "count": 1, "region": "eu"},
+		VarSourceEnv:  {"image": "env", "dc": "env"},
+		VarSourceFlag: {"image": "flag", "dc": "flag"},
+	}
+
+	cases := []struct {
+		Precedence []string
+		Expected   map[string]interface{}
+	}{
+		{
+			DefaultVarPrecedence,
+			map[string]interface{}{"image": "flag", "count": 1, "region": "eu", "dc": "flag"},
+		},
+		{
+			[]string{VarSourceFile, VarSourceEnv, VarSourceFlag},
+			map[string]interface{}{"image": "flag", "count": 1, "region": "eu", "dc": "flag"},
+		},
+		{
+			[]string{VarSourceFile, VarSourceFlag, VarSourceEnv},
+			map[string]interface{}{"image": "env", "count": 1, "region": "eu", "dc": "env"},
+		},
+		{
+			[]string{VarSourceEnv, VarSourceFlag, VarSourceFile},
+			map[string]interface{}{"image": "file", "count": 1, "region": "eu", "dc": "flag"},
+		},
+		{
+			[]string{VarSourceEnv},
+			map[string]interface{}{"image": "env", "dc": "env"},
+		},
+	}
+
+	for i, tc := range cases {
+		res, _ := VariableMergeOrdered(tc.Precedence, sources)
+		if !reflect.DeepEqual(res, tc.Expected) {
+			t.Fatalf("case %d: expected \n%#v\n\n, got \n\n%#v\n\n", i, tc.Expected, res)
+		}
+	}
+
+	_, origins := VariableMergeOrdered([]string{VarSourceFile, VarSourceEnv, VarSourceFlag}, sources)
+	if expected := []string{VarSourceFile, VarSourceEnv, VarSourceFlag}; !reflect.DeepEqual(origins["image"], expected) {
+		t.Fatalf("expected origins %v, got %v", expected, origins["image"])
+	}
+	if expected := []string{VarSourceFile}; !reflect.DeepEqual(origins["count"], expected) {
+		t.Fatalf("expected origins %v, got %v", expected, origins["count"])
+	}
+}
+
+func TestHelper_EnvVariables(t *testing.T) {
+
+	os.Setenv(EnvVarPrefix+"levant_test_image", "redis:4.0")
+	os.Setenv("levant_test_unprefixed", "ignored")
+	defer os.Unsetenv(EnvVarPrefix + "levant_test_image")
+	defer os.Unsetenv("levant_test_unprefixed")
+
+	res := EnvVariables()
+
+	if res["levant_test_image"] != "redis:4.0" {
+		t.Fatalf("expected env variable levant_test_image to be set, got %v", res)
+	}
+	if _, ok := res["levant_test_unprefixed"]; ok {
+		t.Fatalf("expected unprefixed env variable to be ignored, got %v", res)
+	}
+}
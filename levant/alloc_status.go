@@ -0,0 +1,156 @@
+package levant
+
+import (
+	"fmt"
+	"sort"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/jrasell/levant/client"
+	"github.com/jrasell/levant/levant/structs"
+	"github.com/rs/zerolog/log"
+)
+
+// The health reported for each allocation within the allocation status.
+const (
+	AllocHealthHealthy   = "healthy"
+	AllocHealthUnhealthy = "unhealthy"
+	AllocHealthPending   = "pending"
+)
+
+// GroupAllocStatus summarises the allocations of a task group once the
+// deployment has finished.
+type GroupAllocStatus struct {
+	Group       string
+	Healthy     int
+	Unhealthy   int
+	Pending     int
+	Allocations []*AllocStatus
+}
+
+// AllocStatus describes the client status and health of a single allocation.
+type AllocStatus struct {
+	ID           string
+	Node         string
+	ClientStatus string
+	Health       string
+}
+
+// TriggerAllocStatus queries Nomad for the allocations of the deployment, or
+// of the latest version of the job if the deployment ID is empty, and returns
+// their status grouped by task group. Allocations the scheduler has stopped
+// are not included. The allocations are queried within the region and
+// namespace of the client config.
+func TriggerAllocStatus(jobID, deploymentID string, clientConfig *structs.ClientConfig) ([]*GroupAllocStatus, error) {
+
+	c, err := client.NewNomadTargetClient(clientConfig)
+	if err != nil {
+		log.Error().Msgf("levant/alloc_status: unable to setup Levant allocation status: %v", err)
+		return nil, err
+	}
+
+	q := &nomad.QueryOptions{AllowStale: clientConfig.AllowStale}
+
+	var allocs []*nomad.AllocationListStub
+	if deploymentID != "" {
+		allocs, _, err = c.Deployments().Allocations(deploymentID, q)
+	} else {
+		allocs, _, err = c.Jobs().Allocations(jobID, false, q)
+		allocs = latestVersionAllocs(allocs)
+	}
+	if err != nil {
+		log.Warn().Err(err).Msgf("levant/alloc_status: unable to query allocations of job %s", jobID)
+		return nil, fmt.Errorf("unable to query allocations of job %s: %v", jobID, err)
+	}
+
+	return groupAllocStatus(allocs, deploymentID != ""), nil
+}
+
+// latestVersionAllocs filters the allocations to those of the latest job
+// version.
+func latestVersionAllocs(allocs []*nomad.AllocationListStub) []*nomad.AllocationListStub {
+
+	var latest uint64
+	for _, a := range allocs {
+		if a.JobVersion > latest {
+			latest = a.JobVersion
+		}
+	}
+
+	var out []*nomad.AllocationListStub
+	for _, a := range allocs {
+		if a.JobVersion == latest {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// groupAllocStatus groups the allocations which are desired to run by task
+// group, counting the health of each. The groups and allocations are sorted
+// by name so the output is stable.
+func groupAllocStatus(allocs []*nomad.AllocationListStub, deployment bool) []*GroupAllocStatus {
+
+	groups := make(map[string]*GroupAllocStatus)
+
+	for _, a := range allocs {
+		if a.DesiredStatus != nomad.AllocDesiredStatusRun {
+			continue
+		}
+
+		g, ok := groups[a.TaskGroup]
+		if !ok {
+			g = &GroupAllocStatus{Group: a.TaskGroup}
+			groups[a.TaskGroup] = g
+		}
+
+		health := allocHealth(a, deployment)
+		switch health {
+		case AllocHealthHealthy:
+			g.Healthy++
+		case AllocHealthUnhealthy:
+			g.Unhealthy++
+		default:
+			g.Pending++
+		}
+
+		g.Allocations = append(g.Allocations, &AllocStatus{
+			ID:           a.ID,
+			Node:         a.NodeName,
+			ClientStatus: a.ClientStatus,
+			Health:       health,
+		})
+	}
+
+	var out []*GroupAllocStatus
+	for _, g := range groups {
+		sort.Slice(g.Allocations, func(i, j int) bool { return g.Allocations[i].ID < g.Allocations[j].ID })
+		out = append(out, g)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Group < out[j].Group })
+	return out
+}
+
+// allocHealth returns the health of the allocation. Allocations placed by a
+// deployment use the health set by the deployment, and are pending until it
+// is set; otherwise the health is derived from the client status.
+func allocHealth(a *nomad.AllocationListStub, deployment bool) string {
+
+	switch a.ClientStatus {
+	case nomad.AllocClientStatusFailed, nomad.AllocClientStatusLost:
+		return AllocHealthUnhealthy
+	case nomad.AllocClientStatusPending:
+		return AllocHealthPending
+	}
+
+	if a.DeploymentStatus != nil && a.DeploymentStatus.Healthy != nil {
+		if *a.DeploymentStatus.Healthy {
+			return AllocHealthHealthy
+		}
+		return AllocHealthUnhealthy
+	}
+
+	if deployment {
+		return AllocHealthPending
+	}
+	return AllocHealthHealthy
+}
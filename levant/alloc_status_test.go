@@ -0,0 +1,115 @@
+package levant
+
+import (
+	"reflect"
+	"testing"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/helper"
+)
+
+func TestAllocStatus_groupAllocStatus(t *testing.T) {
+
+	allocs := []*nomad.AllocationListStub{
+		{
+			ID: "b", TaskGroup: "web", NodeName: "node-2", DesiredStatus: nomad.AllocDesiredStatusRun,
+			ClientStatus: nomad.AllocClientStatusRunning, DeploymentStatus: &nomad.AllocDeploymentStatus{Healthy: helper.BoolToPtr(true)},
+		},
+		{
+			ID: "a", TaskGroup: "web", NodeName: "node-1", DesiredStatus: nomad.AllocDesiredStatusRun,
+			ClientStatus: nomad.AllocClientStatusRunning,
+		},
+		{
+			ID: "c", TaskGroup: "cache", NodeName: "node-1", DesiredStatus: nomad.AllocDesiredStatusRun,
+			ClientStatus: nomad.AllocClientStatusFailed,
+		},
+		{
+			ID: "d", TaskGroup: "cache", NodeName: "node-2", DesiredStatus: nomad.AllocDesiredStatusStop,
+			ClientStatus: nomad.AllocClientStatusComplete,
+		},
+	}
+
+	expected := []*GroupAllocStatus{
+		{
+			Group:     "cache",
+			Unhealthy: 1,
+			Allocations: []*AllocStatus{
+				{ID: "c", Node: "node-1", ClientStatus: nomad.AllocClientStatusFailed, Health: AllocHealthUnhealthy},
+			},
+		},
+		{
+			Group:   "web",
+			Healthy: 1,
+			Pending: 1,
+			Allocations: []*AllocStatus{
+				{ID: "a", Node: "node-1", ClientStatus: nomad.AllocClientStatusRunning, Health: AllocHealthPending},
+				{ID: "b", Node: "node-2", ClientStatus: nomad.AllocClientStatusRunning, Health: AllocHealthHealthy},
+			},
+		},
+	}
+
+	if out := groupAllocStatus(allocs, true); !reflect.DeepEqual(out, expected) {
+		t.Fatalf("unexpected allocation status: %+v", out)
+	}
+}
+
+func TestAllocStatus_allocHealth(t *testing.T) {
+
+	cases := []struct {
+		Name       string
+		Alloc      *nomad.AllocationListStub
+		Deployment bool
+		Expected   string
+	}{
+		{
+			Name:     "lost",
+			Alloc:    &nomad.AllocationListStub{ClientStatus: nomad.AllocClientStatusLost},
+			Expected: AllocHealthUnhealthy,
+		},
+		{
+			Name:     "pending",
+			Alloc:    &nomad.AllocationListStub{ClientStatus: nomad.AllocClientStatusPending},
+			Expected: AllocHealthPending,
+		},
+		{
+			Name: "deployment unhealthy",
+			Alloc: &nomad.AllocationListStub{
+				ClientStatus:     nomad.AllocClientStatusRunning,
+				DeploymentStatus: &nomad.AllocDeploymentStatus{Healthy: helper.BoolToPtr(false)},
+			},
+			Deployment: true,
+			Expected:   AllocHealthUnhealthy,
+		},
+		{
+			Name:       "deployment health unset",
+			Alloc:      &nomad.AllocationListStub{ClientStatus: nomad.AllocClientStatusRunning},
+			Deployment: true,
+			Expected:   AllocHealthPending,
+		},
+		{
+			Name:     "running without deployment",
+			Alloc:    &nomad.AllocationListStub{ClientStatus: nomad.AllocClientStatusRunning},
+			Expected: AllocHealthHealthy,
+		},
+	}
+
+	for _, tc := range cases {
+		if out := allocHealth(tc.Alloc, tc.Deployment); out != tc.Expected {
+			t.Fatalf("case %s: expected %s, got %s", tc.Name, tc.Expected, out)
+		}
+	}
+}
+
+func TestAllocStatus_latestVersionAllocs(t *testing.T) {
+
+	allocs := []*nomad.AllocationListStub{
+		{ID: "a", JobVersion: 1},
+		{ID: "b", JobVersion: 2},
+		{ID: "c", JobVersion: 2},
+	}
+
+	out := latestVersionAllocs(allocs)
+	if len(out) != 2 || out[0].ID != "b" || out[1].ID != "c" {
+		t.Fatalf("expected allocations of version 2, got %+v", out)
+	}
+}
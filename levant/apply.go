@@ -0,0 +1,81 @@
+package levant
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl"
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/jrasell/levant/client"
+	"github.com/jrasell/levant/levant/structs"
+	"github.com/rs/zerolog/log"
+)
+
+// namespaceSpec is the HCL, or JSON, specification of a Nomad namespace.
+type namespaceSpec struct {
+	Name        string `hcl:"name"`
+	Description string `hcl:"description"`
+	Quota       string `hcl:"quota"`
+}
+
+// TriggerNamespaceApply parses the rendered namespace specification and
+// registers the namespace with Nomad, creating or updating it.
+func TriggerNamespaceApply(spec []byte, clientConfig *structs.ClientConfig) error {
+
+	ns, err := parseNamespaceSpec(spec)
+	if err != nil {
+		return err
+	}
+
+	c, err := client.NewNomadTargetClient(clientConfig)
+	if err != nil {
+		log.Error().Msgf("levant/apply: unable to setup Levant namespace apply: %v", err)
+		return err
+	}
+
+	if _, err = c.Namespaces().Register(ns, nil); err != nil {
+		return fmt.Errorf("unable to register namespace %s: %v", ns.Name, err)
+	}
+
+	log.Info().Msgf("levant/apply: namespace %s successfully applied", ns.Name)
+	return nil
+}
+
+// parseNamespaceSpec decodes the namespace specification, which may be either
+// HCL or JSON.
+func parseNamespaceSpec(spec []byte) (*nomad.Namespace, error) {
+
+	s := &namespaceSpec{}
+	if err := hcl.Decode(s, string(spec)); err != nil {
+		return nil, fmt.Errorf("unable to parse namespace specification: %v", err)
+	}
+
+	if s.Name == "" {
+		return nil, fmt.Errorf("namespace specification must include a name")
+	}
+
+	return &nomad.Namespace{Name: s.Name, Description: s.Description, Quota: s.Quota}, nil
+}
+
+// TriggerACLPolicyApply upserts the ACL policy with the rendered rules into
+// Nomad, creating or updating it. The rules are validated by Nomad.
+func TriggerACLPolicyApply(name, description string, rules []byte, clientConfig *structs.ClientConfig) error {
+
+	if name == "" {
+		return fmt.Errorf("ACL policy name must be set")
+	}
+
+	c, err := client.NewNomadTargetClient(clientConfig)
+	if err != nil {
+		log.Error().Msgf("levant/apply: unable to setup Levant ACL policy apply: %v", err)
+		return err
+	}
+
+	policy := &nomad.ACLPolicy{Name: name, Description: description, Rules: string(rules)}
+
+	if _, err = c.ACLPolicies().Upsert(policy, nil); err != nil {
+		return fmt.Errorf("unable to apply ACL policy %s: %v", name, err)
+	}
+
+	log.Info().Msgf("levant/apply: ACL policy %s successfully applied", name)
+	return nil
+}
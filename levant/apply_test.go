@@ -0,0 +1,44 @@
+package levant
+
+import (
+	"reflect"
+	"testing"
+
+	nomad "github.com/hashicorp/nomad/api"
+)
+
+func TestApply_parseNamespaceSpec(t *testing.T) {
+
+	cases := []struct {
+		Spec     string
+		Expected *nomad.Namespace
+		Error    bool
+	}{
+		{
+			Spec:     "name = \"team-a\"\ndescription = \"Team A services\"\n",
+			Expected: &nomad.Namespace{Name: "team-a", Description: "Team A services"},
+		},
+		{
+			Spec:     `{"name": "team-b", "quota": "small"}`,
+			Expected: &nomad.Namespace{Name: "team-b", Quota: "small"},
+		},
+		{
+			Spec:  `description = "missing name"`,
+			Error: true,
+		},
+		{
+			Spec:  `name = `,
+			Error: true,
+		},
+	}
+
+	for i, tc := range cases {
+		ns, err := parseNamespaceSpec([]byte(tc.Spec))
+		if (err != nil) != tc.Error {
+			t.Fatalf("case %d: expected error %t, got %v", i, tc.Error, err)
+		}
+		if !reflect.DeepEqual(ns, tc.Expected) {
+			t.Fatalf("case %d: expected %+v, got %+v", i, tc.Expected, ns)
+		}
+	}
+}
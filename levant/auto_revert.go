@@ -4,7 +4,6 @@ import (
 	"time"
 
 	nomad "github.com/hashicorp/nomad/api"
-	"github.com/rs/zerolog/log"
 )
 
 func (l *levantDeployment) autoRevert(jobID, depID *string) {
@@ -16,26 +15,28 @@ func (l *levantDeployment) autoRevert(jobID, depID *string) {
 
 		dep, _, err := l.nomad.Jobs().LatestDeployment(*jobID, nil)
 		if err != nil {
-			log.Error().Msgf("levant/auto_revert: unable to query latest deployment of job %s", *jobID)
+			l.logger().Error().Msgf("levant/auto_revert: unable to query latest deployment of job %s", *jobID)
 			return
 		}
 
 		// Check whether we have got the original deployment ID as a return from
 		// Nomad, and if so, continue the loop to try again.
 		if dep.ID == *depID {
-			log.Debug().Msgf("levant/auto_revert: auto-revert deployment not triggered for job %s, rechecking", *jobID)
+			l.logger().Debug().Msgf("levant/auto_revert: auto-revert deployment not triggered for job %s, rechecking", *jobID)
 			time.Sleep(1 * time.Second)
 			continue
 		}
 
-		log.Info().Msgf("levant/auto_revert: beginning deployment watcher for job %s", *jobID)
+		l.logger().Info().Msgf("levant/auto_revert: beginning deployment watcher for job %s", *jobID)
 		success := l.deploymentWatcher(dep.ID)
 
-		if success {
-			log.Info().Msgf("levant/auto_revert: auto-revert of job %s was successful", *jobID)
+		if l.interrupted {
+			break
+		} else if success {
+			l.logger().Info().Msgf("levant/auto_revert: auto-revert of job %s was successful", *jobID)
 			break
 		} else {
-			log.Error().Msgf("levant/auto_revert: auto-revert of job %s failed; POTENTIAL OUTAGE SITUATION", *jobID)
+			l.logger().Error().Msgf("levant/auto_revert: auto-revert of job %s failed; POTENTIAL OUTAGE SITUATION", *jobID)
 			l.checkFailedDeployment(&dep.ID)
 			break
 		}
@@ -44,7 +45,7 @@ func (l *levantDeployment) autoRevert(jobID, depID *string) {
 	// At this point we have not been able to get the latest deploymentID that
 	// is different from the original so we can't perform auto-revert checking.
 	if i == 5 {
-		log.Error().Msgf("levant/auto_revert: unable to check auto-revert of job %s", *jobID)
+		l.logger().Error().Msgf("levant/auto_revert: unable to check auto-revert of job %s", *jobID)
 	}
 }
 
@@ -64,12 +65,12 @@ func (l *levantDeployment) checkAutoRevert(dep *nomad.Deployment) {
 	}
 
 	if revert {
-		log.Info().Msgf("levant/auto_revert: job %v has entered auto-revert state; launching auto-revert checker",
+		l.logger().Info().Msgf("levant/auto_revert: job %v has entered auto-revert state; launching auto-revert checker",
 			dep.JobID)
 
 		// Run the levant autoRevert function.
 		l.autoRevert(&dep.JobID, &dep.ID)
 	} else {
-		log.Info().Msgf("levant/auto_revert: job %v is not in auto-revert; POTENTIAL OUTAGE SITUATION", dep.JobID)
+		l.logger().Info().Msgf("levant/auto_revert: job %v is not in auto-revert; POTENTIAL OUTAGE SITUATION", dep.JobID)
 	}
 }
@@ -0,0 +1,112 @@
+package levant
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	nomad "github.com/hashicorp/nomad/api"
+)
+
+// impliedConstraints are the task group constraints the Nomad servers add to
+// a job when it is registered, such as the Vault version constraint of groups
+// with tasks using Vault. They are matched on their target and operand as the
+// value depends on the version of Nomad.
+var impliedConstraints = []*nomad.Constraint{
+	{LTarget: "${attr.vault.version}", Operand: "semver"},
+	{LTarget: "${attr.os.signals}", Operand: "set_contains"},
+	{LTarget: "${attr.consul.version}", Operand: "semver"},
+	{LTarget: "${attr.nomad.service_discovery}", Operand: "="},
+}
+
+// canonicalizeJob returns the canonical JSON representation of the job
+// specification, used wherever jobs are hashed or compared so that each
+// feature agrees on whether a job has changed. The job defaults are applied,
+// the fields and constraints populated by the Nomad servers and the secret
+// fields are removed, and the keys of every object are sorted so the output is stable
+// regardless of how the job was built. The passed job is not modified.
+func canonicalizeJob(job *nomad.Job) ([]byte, error) {
+
+	raw, err := json.Marshal(job)
+	if err != nil {
+		return nil, err
+	}
+
+	j := &nomad.Job{}
+	if err = json.Unmarshal(raw, j); err != nil {
+		return nil, err
+	}
+
+	j.Canonicalize()
+
+	j.Status = nil
+	j.StatusDescription = nil
+	j.Stable = nil
+	j.Version = nil
+	j.SubmitTime = nil
+	j.CreateIndex = nil
+	j.ModifyIndex = nil
+	j.JobModifyIndex = nil
+	j.VaultToken = nil
+	j.ConsulToken = nil
+
+	for _, tg := range j.TaskGroups {
+		tg.Constraints = removeImpliedConstraints(tg.Constraints)
+	}
+
+	if raw, err = json.Marshal(j); err != nil {
+		return nil, err
+	}
+
+	// Decoding into generic values, whose maps are encoded with sorted keys,
+	// orders the struct fields as well. Numbers are kept as written so large
+	// integers do not lose precision.
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+
+	var v interface{}
+	if err = dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// removeImpliedConstraints returns the constraints without those implied by
+// the Nomad servers, or nil when none remain so that a group whose only
+// constraints are implied matches a group without constraints.
+func removeImpliedConstraints(constraints []*nomad.Constraint) []*nomad.Constraint {
+
+	var out []*nomad.Constraint
+	for _, c := range constraints {
+		if c != nil && !isImpliedConstraint(c) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// isImpliedConstraint returns whether the constraint is one the Nomad servers
+// add to a job when it is registered.
+func isImpliedConstraint(c *nomad.Constraint) bool {
+	for _, i := range impliedConstraints {
+		if c.LTarget == i.LTarget && c.Operand == i.Operand {
+			return true
+		}
+	}
+	return false
+}
+
+// jobSpecHash returns the hex encoded SHA-256 hash of the canonical JSON
+// representation of the job, which is equal for jobs that only differ by
+// their server populated fields.
+func jobSpecHash(job *nomad.Job) (string, error) {
+
+	raw, err := canonicalizeJob(job)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), nil
+}
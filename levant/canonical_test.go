@@ -0,0 +1,102 @@
+package levant
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/helper"
+)
+
+func TestCanonical_jobSpecHash(t *testing.T) {
+
+	buildJob := func() *nomad.Job {
+		return &nomad.Job{
+			ID:   helper.StringToPtr("example"),
+			Name: helper.StringToPtr("example"),
+			Meta: map[string]string{"team": "platform", "build": "1"},
+			TaskGroups: []*nomad.TaskGroup{
+				{
+					Name: helper.StringToPtr("cache"),
+					Tasks: []*nomad.Task{
+						{
+							Name:   "redis",
+							Driver: "docker",
+							Config: map[string]interface{}{"image": "redis:3.2", "port_map": []interface{}{map[string]interface{}{"db": 6379}}},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	rendered := buildJob()
+
+	running := buildJob()
+	running.Status = helper.StringToPtr("running")
+	running.Stable = helper.BoolToPtr(true)
+	running.Version = helper.Uint64ToPtr(4)
+	running.SubmitTime = helper.Int64ToPtr(1570000000000000000)
+	running.CreateIndex = helper.Uint64ToPtr(10)
+	running.ModifyIndex = helper.Uint64ToPtr(120)
+	running.JobModifyIndex = helper.Uint64ToPtr(118)
+	running.VaultToken = helper.StringToPtr("s.secret")
+
+	a, err := jobSpecHash(rendered)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := jobSpecHash(running)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if a != b {
+		t.Fatalf("expected jobs differing only by server metadata to hash equal, got %s and %s", a, b)
+	}
+
+	changed := buildJob()
+	changed.Meta["build"] = "2"
+	if c, _ := jobSpecHash(changed); c == a {
+		t.Fatal("expected jobs with different specifications to hash differently")
+	}
+}
+
+func TestCanonical_canonicalizeJob(t *testing.T) {
+
+	job := &nomad.Job{
+		ID:          helper.StringToPtr("example"),
+		Meta:        map[string]string{"b": "2", "a": "1"},
+		ModifyIndex: helper.Uint64ToPtr(120),
+		VaultToken:  helper.StringToPtr("s.secret"),
+	}
+
+	first, err := canonicalizeJob(job)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := canonicalizeJob(job)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Fatalf("expected stable output, got %s and %s", first, second)
+	}
+
+	out := string(first)
+	for _, s := range []string{`"ModifyIndex":120`, "s.secret"} {
+		if strings.Contains(out, s) {
+			t.Fatalf("expected %s to be removed from %s", s, out)
+		}
+	}
+
+	// The keys of every object, including the job fields, are sorted.
+	if strings.Index(out, `"Affinities"`) > strings.Index(out, `"ID"`) ||
+		strings.Index(out, `"ID"`) > strings.Index(out, `"Meta"`) {
+		t.Fatalf("expected sorted keys, got %s", out)
+	}
+
+	if job.ModifyIndex == nil || *job.ModifyIndex != 120 {
+		t.Fatal("expected the passed job not to be modified")
+	}
+}
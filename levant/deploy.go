@@ -1,15 +1,20 @@
 package levant
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	nomad "github.com/hashicorp/nomad/api"
 	"github.com/jrasell/levant/client"
 	"github.com/jrasell/levant/levant/structs"
-	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
@@ -17,11 +22,30 @@ const (
 	jobStatusRunning = "running"
 )
 
+// The strategies used to determine the success of a deployment, chosen from
+// the type and update configuration of the rendered job.
+const (
+	// watchStrategyDeployment watches the Nomad deployment created by the
+	// registration of service jobs configured with an update stanza.
+	watchStrategyDeployment = "deployment"
+
+	// watchStrategyJobStatus checks the status and allocations of the job,
+	// used for batch and system jobs and service jobs without deployments.
+	watchStrategyJobStatus = "job-status"
+)
+
 // levantDeployment is the all deployment related objects for this Levant
 // deployment invocation.
 type levantDeployment struct {
 	nomad  *nomad.Client
 	config *DeployConfig
+
+	// interrupted is set when the deployment watcher was stopped by a signal.
+	interrupted bool
+
+	// jobLogger is the logger of the deployment, carrying the job ID as a
+	// context field.
+	jobLogger *zerolog.Logger
 }
 
 // DeployConfig is the set of config structs required to run a Levant deploy.
@@ -30,6 +54,23 @@ type DeployConfig struct {
 	Client   *structs.ClientConfig
 	Plan     *structs.PlanConfig
 	Template *structs.TemplateConfig
+
+	// EvalID is populated with the ID of the evaluation created when the job
+	// was registered so callers can reference it once the deployment finishes.
+	EvalID string
+
+	// DeploymentID is populated with the ID of the Nomad deployment watched,
+	// if the job uses deployments.
+	DeploymentID string
+
+	// Logger, when set, is used for the logs of the deployment in place of
+	// the global logger, such as one identifying the target of a deployment
+	// running alongside others.
+	Logger *zerolog.Logger
+
+	// JobVersion is populated with the version of the job created by the
+	// registration when its stability is to be set.
+	JobVersion *uint64
 }
 
 // newLevantDeployment sets up the Levant deployment object and Nomad client
@@ -45,7 +86,7 @@ func newLevantDeployment(config *DeployConfig, nomadClient *nomad.Client) (*leva
 	dep.config = config
 
 	if nomadClient == nil {
-		dep.nomad, err = client.NewNomadClient(config.Client.Addr)
+		dep.nomad, err = client.NewNomadTargetClient(config.Client)
 		if err != nil {
 			return nil, err
 		}
@@ -53,57 +94,94 @@ func newLevantDeployment(config *DeployConfig, nomadClient *nomad.Client) (*leva
 		dep.nomad = nomadClient
 	}
 
-	// Add the JobID as a log context field.
-	log.Logger = log.With().Str(structs.JobIDContextField, *config.Template.Job.ID).Logger()
+	// Add the JobID as a log context field of the deployment logger, rather
+	// than the global logger, so concurrent deployments do not share it.
+	logger := config.logger().With().Str(structs.JobIDContextField, *config.Template.Job.ID).Logger()
+	dep.jobLogger = &logger
 
 	return dep, nil
 }
 
+// logger returns the logger configured for the deployment, falling back to
+// the global logger.
+func (c *DeployConfig) logger() *zerolog.Logger {
+	if c.Logger != nil {
+		return c.Logger
+	}
+	return &log.Logger
+}
+
+// logger returns the logger of the deployment, falling back to the global
+// logger for deployments not created by newLevantDeployment.
+func (l *levantDeployment) logger() *zerolog.Logger {
+	if l.jobLogger != nil {
+		return l.jobLogger
+	}
+	return &log.Logger
+}
+
 // TriggerDeployment provides the main entry point into a Levant deployment and
-// is used to setup the clients before triggering the deployment process.
-func TriggerDeployment(config *DeployConfig, nomadClient *nomad.Client) bool {
+// is used to setup the clients before triggering the deployment process. The
+// returned error wraps ErrDeployFailed or ErrDeployTimeout.
+func TriggerDeployment(config *DeployConfig, nomadClient *nomad.Client) (err error) {
+
+	// Persist the rendered job for later inspection if the operator has asked
+	// for this; by default only failed deployments are written.
+	defer func() {
+		if config.Deploy.KeepRendered && (err != nil || config.Deploy.KeepRenderedAlways) {
+			keepRenderedJob(config)
+		}
+	}()
 
 	// Create our new deployment object.
 	levantDep, err := newLevantDeployment(config, nomadClient)
 	if err != nil {
-		log.Error().Err(err).Msg("levant/deploy: unable to setup Levant deployment")
-		return false
+		config.logger().Error().Err(err).Msg("levant/deploy: unable to setup Levant deployment")
+		return fmt.Errorf("%w: %v", ErrDeployFailed, err)
+	}
+
+	// Keep the Vault token alive so that a long deployment watch does not
+	// outlive its lease.
+	if config.Deploy.VaultRenew && config.Deploy.VaultToken != "" {
+		defer startVaultTokenRenewal(config.Deploy.VaultToken)()
 	}
 
 	// Run the job validation steps and count updater.
 	preDepVal := levantDep.preDeployValidate()
 	if !preDepVal {
-		log.Error().Msg("levant/deploy: pre-deployment validation process failed")
-		return false
+		levantDep.logger().Error().Msg("levant/deploy: pre-deployment validation process failed")
+		return fmt.Errorf("%w: pre-deployment validation process failed", ErrDeployFailed)
 	}
 
 	// Start the main deployment function.
-	success := levantDep.deploy()
-	if !success {
-		log.Error().Msg("levant/deploy: job deployment failed")
-		return false
+	if err = levantDep.deploy(); err != nil {
+		levantDep.logger().Error().Msg("levant/deploy: job deployment failed")
+		return err
 	}
 
-	log.Info().Msg("levant/deploy: job deployment successful")
-	return true
+	levantDep.logger().Info().Msg("levant/deploy: job deployment successful")
+	return nil
 }
 
 func (l *levantDeployment) preDeployValidate() (success bool) {
 
 	// Validate the job to check it is syntactically correct.
 	if _, _, err := l.nomad.Jobs().Validate(l.config.Template.Job, nil); err != nil {
-		log.Error().Err(err).Msg("levant/deploy: job validation failed")
+		l.logger().Error().Err(err).Msg("levant/deploy: job validation failed")
 		return
 	}
 
-	// If job.Type isn't set we can't continue
+	// Nomad registers jobs without a type as service jobs, so set the type of
+	// the rendered job to match and ensure it is watched accordingly.
 	if l.config.Template.Job.Type == nil {
-		log.Error().Msgf("levant/deploy: Nomad job `type` is not set; should be set to `%s`, `%s` or `%s`",
-			nomad.JobTypeBatch, nomad.JobTypeSystem, nomad.JobTypeService)
-		return
+		l.logger().Info().Msgf("levant/deploy: Nomad job `type` is not set; defaulting to `%s`", nomad.JobTypeService)
+		jobType := nomad.JobTypeService
+		l.config.Template.Job.Type = &jobType
 	}
 
-	if !l.config.Deploy.ForceCount {
+	// System jobs place a single allocation per eligible node, so the group
+	// count of the running job is not relevant.
+	if !l.config.Deploy.ForceCount && *l.config.Template.Job.Type != nomad.JobTypeSystem {
 		if err := l.dynamicGroupCountUpdater(); err != nil {
 			return
 		}
@@ -114,22 +192,31 @@ func (l *levantDeployment) preDeployValidate() (success bool) {
 
 // deploy triggers a register of the job resulting in a Nomad deployment which
 // is monitored to determine the eventual state.
-func (l *levantDeployment) deploy() (success bool) {
+func (l *levantDeployment) deploy() error {
 
-	log.Info().Msgf("levant/deploy: triggering a deployment")
+	l.logger().Info().Msgf("levant/deploy: triggering a deployment")
 
 	l.config.Template.Job.VaultToken = &l.config.Deploy.VaultToken
 
 	eval, _, err := l.nomad.Jobs().Register(l.config.Template.Job, nil)
 	if err != nil {
-		log.Error().Err(err).Msg("levant/deploy: unable to register job with Nomad")
-		return
+		l.logger().Error().Err(err).Msg("levant/deploy: unable to register job with Nomad")
+		return fmt.Errorf("%w: unable to register job: %v", ErrDeployFailed, err)
+	}
+	l.config.EvalID = eval.EvalID
+
+	if l.config.Deploy.Message != "" {
+		l.tagJobVersion()
+	}
+
+	if l.config.Deploy.MarkStable != nil {
+		l.recordJobVersion()
 	}
 
 	if l.config.Deploy.ForceBatch {
 		if eval.EvalID, err = l.triggerPeriodic(l.config.Template.Job.ID); err != nil {
-			log.Error().Err(err).Msg("levant/deploy: unable to trigger periodic instance of job")
-			return
+			l.logger().Error().Err(err).Msg("levant/deploy: unable to trigger periodic instance of job")
+			return fmt.Errorf("%w: unable to trigger periodic instance of job: %v", ErrDeployFailed, err)
 		}
 	}
 
@@ -144,69 +231,153 @@ func (l *levantDeployment) deploy() (success bool) {
 		// failure in an evaluation means no allocs will be placed so we exit here.
 		err = l.evaluationInspector(&eval.EvalID)
 		if err != nil {
-			log.Error().Err(err).Msg("levant/deploy: something")
-			return
+			l.logger().Error().Err(err).Msg("levant/deploy: evaluation inspection failed")
+			return fmt.Errorf("%w: %v", ErrDeployFailed, err)
 		}
 	}
 
 	if l.isJobZeroCount() {
-		return true
+		return nil
 	}
 
-	switch *l.config.Template.Job.Type {
-	case nomad.JobTypeService:
-
-		// If the service job doesn't have an update stanza, the job will not use
-		// Nomad deployments.
-		if l.config.Template.Job.Update == nil {
-			log.Info().Msg("levant/deploy: job is not configured with update stanza, consider adding to use deployments")
-			return l.jobStatusChecker(&eval.EvalID)
-		}
-
-		log.Info().Msgf("levant/deploy: beginning deployment watcher for job")
+	switch watchStrategy(l.config.Template.Job) {
+	case watchStrategyDeployment:
+		l.logger().Info().Msgf("levant/deploy: beginning deployment watcher for job")
 
 		// Get the deploymentID from the evaluationID so that we can watch the
 		// deployment for end status.
 		depID, err := l.getDeploymentID(eval.EvalID)
 		if err != nil {
-			log.Error().Err(err).Msgf("levant/deploy: unable to get info of evaluation %s", eval.EvalID)
-			return
+			l.logger().Error().Err(err).Msgf("levant/deploy: unable to get info of evaluation %s", eval.EvalID)
+			if errors.Is(err, ErrDeployTimeout) {
+				return err
+			}
+			return fmt.Errorf("%w: %v", ErrDeployFailed, err)
 		}
-
-		// Get the success of the deployment and return if we have success.
-		if success = l.deploymentWatcher(depID); success {
-			return
+		l.config.DeploymentID = depID
+
+		// Get the success of the deployment and, once any healthy grace
+		// period has passed, return if we have success.
+		if l.deploymentWatcher(depID) {
+			if l.config.Deploy.HealthyGrace > 0 {
+				if err := l.healthyGraceWatcher(depID, l.config.Deploy.HealthyGrace); err != nil {
+					l.logger().Error().Err(err).Msg("levant/deploy: deployment did not remain healthy")
+					return fmt.Errorf("%w: %v", ErrDeployFailed, err)
+				}
+			}
+			return l.readinessCheck()
+		}
+		if l.interrupted {
+			return fmt.Errorf("%w: deployment %s", ErrDeployInterrupted, depID)
 		}
 
 		dep, _, err := l.nomad.Deployments().Info(depID, nil)
 		if err != nil {
-			log.Error().Err(err).Msgf("levant/deploy: unable to query deployment %s for auto-revert check", depID)
-			return
+			l.logger().Error().Err(err).Msgf("levant/deploy: unable to query deployment %s for auto-revert check", depID)
+			return fmt.Errorf("%w: deployment %s did not succeed", ErrDeployFailed, depID)
 		}
 
-		// If the job is not a canary job, then run the auto-revert checker, the
-		// current checking mechanism is slightly hacky and should be updated.
-		// The reason for this is currently the config.Job is populate from the
-		// rendered job and so a user could potentially not set canary meaning
-		// the field shows a null.
-		if l.config.Template.Job.Update.Canary == nil {
-			l.checkAutoRevert(dep)
-		} else if *l.config.Template.Job.Update.Canary == 0 {
+		// If the job is not a canary job, then run the auto-revert checker.
+		if !hasCanaries(l.config.Template.Job) {
 			l.checkAutoRevert(dep)
 		}
+		return fmt.Errorf("%w: deployment %s did not succeed", ErrDeployFailed, depID)
 
-	case nomad.JobTypeBatch:
-		return l.jobStatusChecker(&eval.EvalID)
-
-	case nomad.JobTypeSystem:
-		return l.jobStatusChecker(&eval.EvalID)
+	case watchStrategyJobStatus:
+		if *l.config.Template.Job.Type == nomad.JobTypeService {
+			l.logger().Info().Msg("levant/deploy: job is not configured with update stanza, consider adding to use deployments")
+		}
+		if err := jobStatusError(l.jobStatusChecker(&eval.EvalID)); err != nil {
+			return err
+		}
+		return l.readinessCheck()
 
 	default:
-		log.Debug().Msgf("levant/deploy: Levant does not support advanced deployments of job type %s",
+		l.logger().Debug().Msgf("levant/deploy: Levant does not support advanced deployments of job type %s",
 			*l.config.Template.Job.Type)
-		success = true
 	}
-	return
+	return nil
+}
+
+// watchStrategy returns the strategy used to determine the success of the
+// deployment of the job based on its type. Service jobs are only watched
+// using Nomad deployments when a task group is configured with an update
+// stanza, at either the job or group level, with a max_parallel above zero.
+// An empty strategy is returned for job types Levant does not watch.
+func watchStrategy(job *nomad.Job) string {
+
+	if job.Type == nil {
+		return ""
+	}
+
+	switch *job.Type {
+	case nomad.JobTypeService:
+		for _, tg := range job.TaskGroups {
+			if groupUsesDeployments(job.Update, tg.Update) {
+				return watchStrategyDeployment
+			}
+		}
+		return watchStrategyJobStatus
+
+	case nomad.JobTypeBatch, nomad.JobTypeSystem:
+		return watchStrategyJobStatus
+	}
+
+	return ""
+}
+
+// groupUsesDeployments checks whether the update stanza of a group, merged
+// with that of the job, results in the group using Nomad deployments. The
+// max_parallel of an update stanza defaults to 1 when unset.
+func groupUsesDeployments(jobUpdate, groupUpdate *nomad.UpdateStrategy) bool {
+
+	if jobUpdate == nil && groupUpdate == nil {
+		return false
+	}
+
+	switch {
+	case groupUpdate != nil && groupUpdate.MaxParallel != nil:
+		return *groupUpdate.MaxParallel > 0
+	case jobUpdate != nil && jobUpdate.MaxParallel != nil:
+		return *jobUpdate.MaxParallel > 0
+	}
+	return true
+}
+
+// hasCanaries checks whether the update stanza of the job or any of its task
+// groups configures canaries.
+func hasCanaries(job *nomad.Job) bool {
+	if job.Update != nil && job.Update.Canary != nil && *job.Update.Canary > 0 {
+		return true
+	}
+	for _, tg := range job.TaskGroups {
+		if tg.Update != nil && tg.Update.Canary != nil && *tg.Update.Canary > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// readinessCheck waits for the readiness endpoints of the tasks, if any are
+// configured, once the allocations of the job have been placed.
+func (l *levantDeployment) readinessCheck() error {
+	if len(l.config.Deploy.Readiness) == 0 {
+		return nil
+	}
+	if err := l.readinessWatcher(); err != nil {
+		l.logger().Error().Err(err).Msg("levant/deploy: tasks did not become ready")
+		return err
+	}
+	return nil
+}
+
+// jobStatusError converts the result of the job status checker into the error
+// returned from deploy.
+func jobStatusError(success bool) error {
+	if success {
+		return nil
+	}
+	return fmt.Errorf("%w: job did not reach the running state", ErrDeployFailed)
 }
 
 func (l *levantDeployment) evaluationInspector(evalID *string) error {
@@ -219,8 +390,12 @@ func (l *levantDeployment) evaluationInspector(evalID *string) error {
 
 		switch evalInfo.Status {
 		case "complete", "failed", "canceled":
+			if l.config.Deploy.FailOnBlockedEval {
+				l.evaluationPlacementReport(evalInfo.ID)
+			}
+
 			if len(evalInfo.FailedTGAllocs) == 0 {
-				log.Info().Msgf("levant/deploy: evaluation %s finished successfully", *evalID)
+				l.logger().Info().Msgf("levant/deploy: evaluation %s finished successfully", *evalID)
 				return nil
 			}
 
@@ -236,7 +411,7 @@ func (l *levantDeployment) evaluationInspector(evalID *string) error {
 					for d := range metrics.DimensionExhausted {
 						dimension = append(dimension, d)
 					}
-					log.Error().Msgf("levant/deploy: task group %s failed to place allocs, failed on %v and exhausted %v",
+					l.logger().Error().Msgf("levant/deploy: task group %s failed to place allocs, failed on %v and exhausted %v",
 						group, exhausted, dimension)
 				}
 
@@ -244,7 +419,7 @@ func (l *levantDeployment) evaluationInspector(evalID *string) error {
 				// failures.
 				if len(metrics.ClassFiltered) > 0 {
 					for f := range metrics.ClassFiltered {
-						log.Error().Msgf("levant/deploy: task group %s failed to place %v allocs as class \"%s\" was filtered",
+						l.logger().Error().Msgf("levant/deploy: task group %s failed to place %v allocs as class \"%s\" was filtered",
 							group, len(metrics.ClassFiltered), f)
 					}
 				}
@@ -253,12 +428,20 @@ func (l *levantDeployment) evaluationInspector(evalID *string) error {
 				// failures.
 				if len(metrics.ConstraintFiltered) > 0 {
 					for cf := range metrics.ConstraintFiltered {
-						log.Error().Msgf("levant/deploy: task group %s failed to place %v allocs as constraint \"%s\" was filtered",
+						l.logger().Error().Msgf("levant/deploy: task group %s failed to place %v allocs as constraint \"%s\" was filtered",
 							group, len(metrics.ConstraintFiltered), cf)
 					}
 				}
 			}
 
+			// If the operator has asked for it, fail when the scheduler was unable
+			// to place all allocations and has created a blocked evaluation to
+			// wait for cluster resources.
+			if l.config.Deploy.FailOnBlockedEval && evalInfo.BlockedEval != "" {
+				return fmt.Errorf("evaluation %s is blocked by evaluation %s as the cluster has insufficient resources",
+					*evalID, evalInfo.BlockedEval)
+			}
+
 			// Do not return an error here; there could well be information from
 			// Nomad detailing filtered nodes but the deployment will still be
 			// successful. GH-220.
@@ -271,6 +454,31 @@ func (l *levantDeployment) evaluationInspector(evalID *string) error {
 	}
 }
 
+// evaluationPlacementReport logs the number of allocations the evaluation has
+// placed and stopped, allowing operators to confirm the evaluation resulted in
+// changes to the running job.
+func (l *levantDeployment) evaluationPlacementReport(evalID string) {
+
+	allocs, _, err := l.nomad.Evaluations().Allocations(evalID, nil)
+	if err != nil {
+		l.logger().Error().Err(err).Msgf("levant/deploy: unable to query allocations of evaluation %s", evalID)
+		return
+	}
+
+	var placed, stopped int
+
+	for _, alloc := range allocs {
+		switch alloc.DesiredStatus {
+		case nomad.AllocDesiredStatusRun:
+			placed++
+		case nomad.AllocDesiredStatusStop, nomad.AllocDesiredStatusEvict:
+			stopped++
+		}
+	}
+
+	l.logger().Info().Msgf("levant/deploy: evaluation %s placed %v and stopped %v allocations", evalID, placed, stopped)
+}
+
 func (l *levantDeployment) deploymentWatcher(depID string) (success bool) {
 
 	var canaryChan chan interface{}
@@ -286,24 +494,36 @@ func (l *levantDeployment) deploymentWatcher(depID string) (success bool) {
 		go l.canaryAutoPromote(depID, l.config.Deploy.Canary, canaryChan, deploymentChan)
 	}
 
+	// Trap interrupt and terminate signals so the operator is left knowing the
+	// state of the deployment rather than Levant exiting abruptly.
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+
 	q := &nomad.QueryOptions{WaitIndex: 1, AllowStale: l.config.Client.AllowStale, WaitTime: wt}
 
 	for {
 
 		dep, meta, err := l.nomad.Deployments().Info(depID, q)
-		log.Debug().Msgf("levant/deploy: deployment %v running for %.2fs", depID, time.Since(t).Seconds())
+		l.logger().Debug().Msgf("levant/deploy: deployment %v running for %.2fs", depID, time.Since(t).Seconds())
 
 		// Listen for the deploymentChan closing which indicates Levant should exit
 		// the deployment watcher.
 		select {
 		case <-deploymentChan:
 			return false
+		case sig := <-sigChan:
+			if canaryChan != nil {
+				close(canaryChan)
+			}
+			l.interruptWatch(depID, sig)
+			return false
 		default:
 			break
 		}
 
 		if err != nil {
-			log.Error().Err(err).Msgf("levant/deploy: unable to get info of deployment %s", depID)
+			l.logger().Error().Err(err).Msgf("levant/deploy: unable to get info of deployment %s", depID)
 			return
 		}
 
@@ -330,17 +550,17 @@ func (l *levantDeployment) checkDeploymentStatus(dep *nomad.Deployment, shutdown
 
 	switch dep.Status {
 	case "successful":
-		log.Info().Msgf("levant/deploy: deployment %v has completed successfully", dep.ID)
+		l.logger().Info().Msgf("levant/deploy: deployment %v has completed successfully", dep.ID)
 		return false, nil
 	case jobStatusRunning:
 		return true, nil
 	default:
 		if shutdownChan != nil {
-			log.Debug().Msgf("levant/deploy: deployment %v meaning canary auto promote will shutdown", dep.Status)
+			l.logger().Debug().Msgf("levant/deploy: deployment %v meaning canary auto promote will shutdown", dep.Status)
 			close(shutdownChan)
 		}
 
-		log.Error().Msgf("levant/deploy: deployment %v has status %s", dep.ID, dep.Status)
+		l.logger().Error().Msgf("levant/deploy: deployment %v has status %s", dep.ID, dep.Status)
 
 		// Launch the failure inspector.
 		l.checkFailedDeployment(&dep.ID)
@@ -358,28 +578,28 @@ func (l *levantDeployment) canaryAutoPromote(depID string, waitTime int, shutdow
 	for {
 		select {
 		case <-autoPromote:
-			log.Info().Msgf("levant/deploy: auto-promote period %vs has been reached for deployment %s",
+			l.logger().Info().Msgf("levant/deploy: auto-promote period %vs has been reached for deployment %s",
 				waitTime, depID)
 
 			// Check the deployment is healthy before promoting.
 			if healthy := l.checkCanaryDeploymentHealth(depID); !healthy {
-				log.Error().Msgf("levant/deploy: the canary deployment %s has unhealthy allocations, unable to promote", depID)
+				l.logger().Error().Msgf("levant/deploy: the canary deployment %s has unhealthy allocations, unable to promote", depID)
 				close(deploymentChan)
 				return
 			}
 
-			log.Info().Msgf("levant/deploy: triggering auto promote of deployment %s", depID)
+			l.logger().Info().Msgf("levant/deploy: triggering auto promote of deployment %s", depID)
 
 			// Promote the deployment.
 			_, _, err := l.nomad.Deployments().PromoteAll(depID, nil)
 			if err != nil {
-				log.Error().Err(err).Msgf("levant/deploy: unable to promote deployment %s", depID)
+				l.logger().Error().Err(err).Msgf("levant/deploy: unable to promote deployment %s", depID)
 				close(deploymentChan)
 				return
 			}
 
 		case <-shutdownChan:
-			log.Info().Msg("levant/deploy: canary auto promote has been shutdown")
+			l.logger().Info().Msg("levant/deploy: canary auto promote has been shutdown")
 			return
 		}
 	}
@@ -393,7 +613,7 @@ func (l *levantDeployment) checkCanaryDeploymentHealth(depID string) (healthy bo
 
 	dep, _, err := l.nomad.Deployments().Info(depID, &nomad.QueryOptions{AllowStale: l.config.Client.AllowStale})
 	if err != nil {
-		log.Error().Err(err).Msgf("levant/deploy: unable to query deployment %s for health", depID)
+		l.logger().Error().Err(err).Msgf("levant/deploy: unable to query deployment %s for health", depID)
 		return
 	}
 
@@ -402,19 +622,19 @@ func (l *levantDeployment) checkCanaryDeploymentHealth(depID string) (healthy bo
 	for taskName, taskInfo := range dep.TaskGroups {
 		// skip any task groups which are not configured for canary deployments
 		if taskInfo.DesiredCanaries == 0 {
-			log.Debug().Msgf("levant/deploy: task %s has no desired canaries, skipping health checks in deployment %s", taskName, depID)
+			l.logger().Debug().Msgf("levant/deploy: task %s has no desired canaries, skipping health checks in deployment %s", taskName, depID)
 			continue
 		}
 
 		if taskInfo.DesiredCanaries != taskInfo.HealthyAllocs {
-			log.Error().Msgf("levant/deploy: task %s has unhealthy allocations in deployment %s", taskName, depID)
+			l.logger().Error().Msgf("levant/deploy: task %s has unhealthy allocations in deployment %s", taskName, depID)
 			unhealthy++
 		}
 	}
 
 	// If zero unhealthy tasks were found, continue with the auto promotion.
 	if unhealthy == 0 {
-		log.Debug().Msgf("levant/deploy: deployment %s has 0 unhealthy allocations", depID)
+		l.logger().Debug().Msgf("levant/deploy: deployment %s has 0 unhealthy allocations", depID)
 		healthy = true
 	}
 
@@ -426,7 +646,7 @@ func (l *levantDeployment) checkCanaryDeploymentHealth(depID string) (healthy bo
 // checked in the same fashion as other jobs.
 func (l *levantDeployment) triggerPeriodic(jobID *string) (evalID string, err error) {
 
-	log.Info().Msg("levant/deploy: triggering a run of periodic job")
+	l.logger().Info().Msg("levant/deploy: triggering a run of periodic job")
 
 	// Trigger the run if possible and just return both the evalID and the err.
 	// There is no need to check this here as the caller does this.
@@ -448,7 +668,7 @@ func (l *levantDeployment) getDeploymentID(evalID string) (depID string, err err
 	for {
 		select {
 		case <-timeout.C:
-			err = errors.New("timeout reached on attempting to find deployment ID")
+			err = fmt.Errorf("%w attempting to find deployment ID", ErrDeployTimeout)
 			return
 
 		default:
@@ -460,7 +680,7 @@ func (l *levantDeployment) getDeploymentID(evalID string) (depID string, err err
 				return evalInfo.DeploymentID, nil
 			}
 
-			log.Debug().Msgf("levant/deploy: Nomad returned an empty deployment for evaluation %v; retrying", evalID)
+			l.logger().Debug().Msgf("levant/deploy: Nomad returned an empty deployment for evaluation %v; retrying", evalID)
 			time.Sleep(2 * time.Second)
 			continue
 		}
@@ -479,10 +699,10 @@ func (l *levantDeployment) dynamicGroupCountUpdater() error {
 	// indicates the job is not running, not that there was an error in the API
 	// call.
 	if err != nil && strings.Contains(err.Error(), "404") {
-		log.Info().Msg("levant/deploy: job is not running, using template file group counts")
+		l.logger().Info().Msg("levant/deploy: job is not running, using template file group counts")
 		return nil
 	} else if err != nil {
-		log.Error().Err(err).Msg("levant/deploy: unable to perform job evaluation")
+		l.logger().Error().Err(err).Msg("levant/deploy: unable to perform job evaluation")
 		return err
 	}
 
@@ -492,14 +712,14 @@ func (l *levantDeployment) dynamicGroupCountUpdater() error {
 		return nil
 	}
 
-	log.Debug().Msgf("levant/deploy: running dynamic job count updater")
+	l.logger().Debug().Msgf("levant/deploy: running dynamic job count updater")
 
 	// Iterate over the templated job and the Nomad returned job and update group count
 	// based on matches.
 	for _, rGroup := range rJob.TaskGroups {
 		for _, group := range l.config.Template.Job.TaskGroups {
 			if *rGroup.Name == *group.Name {
-				log.Info().Msgf("levant/deploy: using dynamic count %v for group %s",
+				l.logger().Info().Msgf("levant/deploy: using dynamic count %v for group %s",
 					*rGroup.Count, *group.Name)
 				group.Count = rGroup.Count
 			}
@@ -519,3 +739,38 @@ func (l *levantDeployment) isJobZeroCount() bool {
 	}
 	return true
 }
+
+// keepRenderedJob writes the rendered job, as it was submitted to Nomad, to the
+// configured path or a temporary file if no path was set. The Vault token is
+// stripped from the written job so secrets are not persisted to disk.
+func keepRenderedJob(config *DeployConfig) {
+
+	job := *config.Template.Job
+	job.VaultToken = nil
+
+	out, err := json.MarshalIndent(&job, "", "  ")
+	if err != nil {
+		config.logger().Error().Err(err).Msg("levant/deploy: unable to marshal rendered job")
+		return
+	}
+
+	var f *os.File
+
+	if config.Deploy.KeepRenderedPath != "" {
+		f, err = os.Create(config.Deploy.KeepRenderedPath)
+	} else {
+		f, err = ioutil.TempFile("", fmt.Sprintf("levant-%s-*.json", *config.Template.Job.ID))
+	}
+	if err != nil {
+		config.logger().Error().Err(err).Msg("levant/deploy: unable to create file for rendered job")
+		return
+	}
+	defer f.Close()
+
+	if _, err = f.Write(out); err != nil {
+		config.logger().Error().Err(err).Msgf("levant/deploy: unable to write rendered job to %s", f.Name())
+		return
+	}
+
+	config.logger().Info().Msgf("levant/deploy: rendered job written to %s", f.Name())
+}
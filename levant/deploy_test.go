@@ -0,0 +1,97 @@
+package levant
+
+import (
+	"testing"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/helper"
+)
+
+func TestDeploy_watchStrategy(t *testing.T) {
+
+	cases := []struct {
+		Name     string
+		Job      *nomad.Job
+		Expected string
+	}{
+		{
+			Name: "service with job update",
+			Job: &nomad.Job{
+				Type:       helper.StringToPtr(nomad.JobTypeService),
+				Update:     &nomad.UpdateStrategy{},
+				TaskGroups: []*nomad.TaskGroup{{Name: helper.StringToPtr("web")}},
+			},
+			Expected: watchStrategyDeployment,
+		},
+		{
+			Name: "service with group update",
+			Job: &nomad.Job{
+				Type: helper.StringToPtr(nomad.JobTypeService),
+				TaskGroups: []*nomad.TaskGroup{
+					{Name: helper.StringToPtr("web"), Update: &nomad.UpdateStrategy{MaxParallel: helper.IntToPtr(2)}},
+				},
+			},
+			Expected: watchStrategyDeployment,
+		},
+		{
+			Name: "service with max parallel zero",
+			Job: &nomad.Job{
+				Type:       helper.StringToPtr(nomad.JobTypeService),
+				Update:     &nomad.UpdateStrategy{MaxParallel: helper.IntToPtr(0)},
+				TaskGroups: []*nomad.TaskGroup{{Name: helper.StringToPtr("web")}},
+			},
+			Expected: watchStrategyJobStatus,
+		},
+		{
+			Name: "service without update",
+			Job: &nomad.Job{
+				Type:       helper.StringToPtr(nomad.JobTypeService),
+				TaskGroups: []*nomad.TaskGroup{{Name: helper.StringToPtr("web")}},
+			},
+			Expected: watchStrategyJobStatus,
+		},
+		{
+			Name: "batch with update",
+			Job: &nomad.Job{
+				Type:       helper.StringToPtr(nomad.JobTypeBatch),
+				Update:     &nomad.UpdateStrategy{},
+				TaskGroups: []*nomad.TaskGroup{{Name: helper.StringToPtr("work")}},
+			},
+			Expected: watchStrategyJobStatus,
+		},
+		{
+			Name:     "system",
+			Job:      &nomad.Job{Type: helper.StringToPtr(nomad.JobTypeSystem)},
+			Expected: watchStrategyJobStatus,
+		},
+		{
+			Name:     "unknown",
+			Job:      &nomad.Job{Type: helper.StringToPtr("sysbatch")},
+			Expected: "",
+		},
+	}
+
+	for _, tc := range cases {
+		if got := watchStrategy(tc.Job); got != tc.Expected {
+			t.Fatalf("%s: got strategy %q, expected %q", tc.Name, got, tc.Expected)
+		}
+	}
+}
+
+func TestDeploy_hasCanaries(t *testing.T) {
+
+	job := &nomad.Job{
+		Update: &nomad.UpdateStrategy{Canary: helper.IntToPtr(0)},
+		TaskGroups: []*nomad.TaskGroup{
+			{Name: helper.StringToPtr("web")},
+		},
+	}
+	if hasCanaries(job) {
+		t.Fatal("expected job without canaries")
+	}
+
+	job.TaskGroups[0].Update = &nomad.UpdateStrategy{Canary: helper.IntToPtr(1)}
+	if !hasCanaries(job) {
+		t.Fatal("expected job with group canaries")
+	}
+}
@@ -1,16 +1,21 @@
 package levant
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+
 	nomad "github.com/hashicorp/nomad/api"
 	"github.com/jrasell/levant/client"
+	"github.com/jrasell/levant/levant/structs"
 	"github.com/rs/zerolog/log"
 )
 
 // TriggerDispatch provides the main entry point into a Levant dispatch and
 // is used to setup the clients before triggering the dispatch process.
-func TriggerDispatch(job string, metaMap map[string]string, payload []byte, address string) bool {
+func TriggerDispatch(job string, metaMap map[string]string, payload []byte, clientConfig *structs.ClientConfig) bool {
 
-	client, err := client.NewNomadClient(address)
+	client, err := client.NewNomadTargetClient(clientConfig)
 	if err != nil {
 		log.Error().Msgf("levant/dispatch: unable to setup Levant dispatch: %v", err)
 		return false
@@ -20,6 +25,11 @@ func TriggerDispatch(job string, metaMap map[string]string, payload []byte, addr
 	// levantDeployment object. Requires client refactor.
 	dep := &levantDeployment{}
 	dep.nomad = client
+	dep.config = &DeployConfig{
+		Client:   &structs.ClientConfig{},
+		Deploy:   &structs.DeployConfig{},
+		Template: &structs.TemplateConfig{},
+	}
 
 	success := dep.dispatch(job, metaMap, payload)
 	if !success {
@@ -36,18 +46,30 @@ func TriggerDispatch(job string, metaMap map[string]string, payload []byte, addr
 // state.
 func (l *levantDeployment) dispatch(job string, metaMap map[string]string, payload []byte) bool {
 
+	// Check the meta parameters against those the job accepts so a missing
+	// required key is reported before an instance is dispatched.
+	parent, _, err := l.nomad.Jobs().Info(job, nil)
+	if err != nil {
+		l.logger().Error().Msgf("levant/dispatch: unable to lookup job %s: %v", job, err)
+		return false
+	}
+	if err := validateDispatchMeta(job, parent.ParameterizedJob, metaMap); err != nil {
+		l.logger().Error().Msgf("levant/dispatch: %v", err)
+		return false
+	}
+
 	// Initiate the dispatch with the passed meta parameters.
 	eval, _, err := l.nomad.Jobs().Dispatch(job, metaMap, payload, nil)
 	if err != nil {
-		log.Error().Msgf("levant/dispatch: %v", err)
+		l.logger().Error().Msgf("levant/dispatch: %v", err)
 		return false
 	}
 
-	log.Info().Msgf("levant/dispatch: triggering dispatch against job %s", job)
+	l.logger().Info().Msgf("levant/dispatch: triggering dispatch against job %s", job)
 
 	// If we didn't get an EvaluationID then we cannot continue.
 	if eval.EvalID == "" {
-		log.Error().Msgf("levant/dispatch: dispatched job %s did not return evaluation", job)
+		l.logger().Error().Msgf("levant/dispatch: dispatched job %s did not return evaluation", job)
 		return false
 	}
 
@@ -61,9 +83,48 @@ func (l *levantDeployment) dispatch(job string, metaMap map[string]string, paylo
 	// errors in triggering the dispatch job.
 	err = l.evaluationInspector(&eval.EvalID)
 	if err != nil {
-		log.Error().Msgf("levant/dispatch: %v", err)
+		l.logger().Error().Msgf("levant/dispatch: %v", err)
 		return false
 	}
 
 	return l.jobStatusChecker(&eval.EvalID)
 }
+
+// validateDispatchMeta checks the meta parameters include each of the keys
+// required by the parameterized job and only keys it allows.
+func validateDispatchMeta(job string, params *nomad.ParameterizedJobConfig, metaMap map[string]string) error {
+	if params == nil {
+		return fmt.Errorf("job %s is not a parameterized job", job)
+	}
+
+	allowed := make(map[string]struct{}, len(params.MetaRequired)+len(params.MetaOptional))
+	var missing []string
+
+	for _, k := range params.MetaRequired {
+		allowed[k] = struct{}{}
+		if _, ok := metaMap[k]; !ok {
+			missing = append(missing, k)
+		}
+	}
+	for _, k := range params.MetaOptional {
+		allowed[k] = struct{}{}
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("job %s requires meta keys which were not provided: %s", job, strings.Join(missing, ", "))
+	}
+
+	var unknown []string
+	for k := range metaMap {
+		if _, ok := allowed[k]; !ok {
+			unknown = append(unknown, k)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("job %s does not allow meta keys: %s", job, strings.Join(unknown, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package levant
+
+import (
+	"testing"
+
+	nomad "github.com/hashicorp/nomad/api"
+)
+
+func TestDispatch_validateDispatchMeta(t *testing.T) {
+
+	params := &nomad.ParameterizedJobConfig{
+		MetaRequired: []string{"input", "output"},
+		MetaOptional: []string{"priority"},
+	}
+
+	cases := []struct {
+		Name   string
+		Params *nomad.ParameterizedJobConfig
+		Meta   map[string]string
+		Error  bool
+	}{
+		{Name: "required", Params: params, Meta: map[string]string{"input": "a", "output": "b"}},
+		{Name: "optional", Params: params, Meta: map[string]string{"input": "a", "output": "b", "priority": "high"}},
+		{Name: "missing required", Params: params, Meta: map[string]string{"input": "a"}, Error: true},
+		{Name: "unknown key", Params: params, Meta: map[string]string{"input": "a", "output": "b", "other": "c"}, Error: true},
+		{Name: "no meta", Params: &nomad.ParameterizedJobConfig{}, Meta: map[string]string{}},
+		{Name: "not parameterized", Meta: map[string]string{}, Error: true},
+	}
+
+	for _, tc := range cases {
+		err := validateDispatchMeta("example", tc.Params, tc.Meta)
+		if (err != nil) != tc.Error {
+			t.Fatalf("%s: expected error %t, got %v", tc.Name, tc.Error, err)
+		}
+	}
+}
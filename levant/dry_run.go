@@ -0,0 +1,125 @@
+package levant
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// dryRunReport is the combined result of validating and planning a job.
+type dryRunReport struct {
+	validationErrors []string
+	warnings         []string
+	changes          bool
+	destructive      []string
+	planErr          error
+}
+
+// TriggerDryRun validates and plans the rendered job without registering it,
+// logging a single report of the result. A nil error indicates the job is
+// valid and the plan identified changes. ErrPlanNoChanges is returned when
+// there are no changes, ErrValidateFailed when the job is invalid and errors
+// wrapping ErrPlanFailed when the plan could not be completed.
+func TriggerDryRun(config *PlanConfig) error {
+
+	lp, err := newPlan(config)
+	if err != nil {
+		config.logger().Error().Err(err).Msg("levant/dry_run: unable to setup Levant dry run")
+		return fmt.Errorf("%w: %v", ErrPlanFailed, err)
+	}
+
+	report := lp.dryRun()
+	report.log(lp.logger())
+
+	return report.err()
+}
+
+// dryRun validates the job and, if it is valid, runs the plan.
+func (lp *levantPlan) dryRun() *dryRunReport {
+
+	report := &dryRunReport{}
+
+	resp, _, err := lp.jobs.Validate(lp.config.Template.Job, nil)
+	switch {
+	case err != nil:
+		report.validationErrors = append(report.validationErrors, err.Error())
+	default:
+		report.validationErrors = append(report.validationErrors, resp.ValidationErrors...)
+		if resp.Error != "" && len(resp.ValidationErrors) == 0 {
+			report.validationErrors = append(report.validationErrors, resp.Error)
+		}
+		report.warnings = appendWarnings(report.warnings, "validate", resp.Warnings)
+	}
+
+	if len(report.validationErrors) > 0 {
+		return report
+	}
+
+	report.changes, report.planErr = lp.plan()
+	report.warnings = appendWarnings(report.warnings, "plan", lp.warnings)
+	report.destructive = lp.destructive
+
+	return report
+}
+
+// appendWarnings splits the multi-line warnings returned by Nomad, prefixing
+// each with the step which returned it.
+func appendWarnings(warnings []string, step, w string) []string {
+	for _, line := range strings.Split(w, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			warnings = append(warnings, fmt.Sprintf("%s: %s", step, line))
+		}
+	}
+	return warnings
+}
+
+// log writes the dry run report.
+func (r *dryRunReport) log(logger *zerolog.Logger) {
+
+	logger.Info().Msg("levant/dry_run: dry run complete, no changes have been made to the cluster")
+
+	if len(r.validationErrors) > 0 {
+		for _, e := range r.validationErrors {
+			logger.Error().Msgf("levant/dry_run: validation error: %s", e)
+		}
+	} else {
+		logger.Info().Msg("levant/dry_run: job validation passed")
+	}
+
+	for _, w := range r.warnings {
+		logger.Warn().Msgf("levant/dry_run: warning from %s", w)
+	}
+
+	if len(r.validationErrors) > 0 {
+		return
+	}
+
+	switch {
+	case r.planErr != nil:
+		logger.Error().Err(r.planErr).Msg("levant/dry_run: plan failed")
+	case r.changes:
+		logger.Info().Msg("levant/dry_run: plan detected changes which would be deployed")
+	default:
+		logger.Info().Msg("levant/dry_run: plan detected no changes")
+	}
+
+	if len(r.destructive) > 0 {
+		logger.Warn().Msgf("levant/dry_run: changes would force allocations to be destroyed and recreated: %s",
+			strings.Join(r.destructive, ", "))
+	}
+}
+
+// err returns the error describing the result of the dry run.
+func (r *dryRunReport) err() error {
+	switch {
+	case len(r.validationErrors) > 0:
+		return fmt.Errorf("%w: %s", ErrValidateFailed, strings.Join(r.validationErrors, ", "))
+	case r.planErr != nil:
+		return fmt.Errorf("%w: %v", ErrPlanFailed, r.planErr)
+	case !r.changes:
+		return ErrPlanNoChanges
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,100 @@
+package levant
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/helper"
+	"github.com/jrasell/levant/levant/structs"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func TestDryRun_dryRun(t *testing.T) {
+
+	log.Logger = zerolog.New(ioutil.Discard)
+
+	destructive := &nomad.JobDiff{
+		Type: diffTypeEdited,
+		TaskGroups: []*nomad.TaskGroupDiff{
+			{
+				Type: diffTypeEdited,
+				Name: "cache",
+				Tasks: []*nomad.TaskDiff{
+					{
+						Type:        diffTypeEdited,
+						Name:        "redis",
+						Annotations: []string{annotationForcesDestructiveUpdate},
+					},
+				},
+			},
+		},
+	}
+
+	cases := []struct {
+		Name     string
+		Jobs     *fakeJobs
+		Plan     *structs.PlanConfig
+		Warnings int
+		Expected error
+	}{
+		{
+			Name: "invalid",
+			Jobs: &fakeJobs{
+				validate: &nomad.JobValidateResponse{ValidationErrors: []string{"missing datacenters"}},
+			},
+			Plan:     &structs.PlanConfig{},
+			Expected: ErrValidateFailed,
+		},
+		{
+			Name: "changes with warnings",
+			Jobs: &fakeJobs{
+				validate: &nomad.JobValidateResponse{Warnings: "1 warning:\n\n* Group update stanza is deprecated"},
+				plan:     &nomad.JobPlanResponse{Diff: &nomad.JobDiff{Type: diffTypeAdded}, Warnings: "plan warning"},
+			},
+			Plan:     &structs.PlanConfig{},
+			Warnings: 3,
+		},
+		{
+			Name:     "no changes",
+			Jobs:     &fakeJobs{plan: &nomad.JobPlanResponse{Diff: &nomad.JobDiff{Type: diffTypeNone}}},
+			Plan:     &structs.PlanConfig{},
+			Expected: ErrPlanNoChanges,
+		},
+		{
+			Name:     "destructive",
+			Jobs:     &fakeJobs{plan: &nomad.JobPlanResponse{Diff: destructive}},
+			Plan:     &structs.PlanConfig{FailOnDestructive: true},
+			Expected: ErrPlanFailed,
+		},
+		{
+			Name:     "plan error",
+			Jobs:     &fakeJobs{planErr: fmt.Errorf("connection refused")},
+			Plan:     &structs.PlanConfig{},
+			Expected: ErrPlanFailed,
+		},
+	}
+
+	for _, tc := range cases {
+		lp := &levantPlan{
+			jobs: tc.Jobs,
+			config: &PlanConfig{
+				Plan:     tc.Plan,
+				Template: &structs.TemplateConfig{Job: &nomad.Job{ID: helper.StringToPtr("example")}},
+			},
+		}
+
+		report := lp.dryRun()
+		report.log(lp.logger())
+
+		if err := report.err(); !errors.Is(err, tc.Expected) || (tc.Expected == nil && err != nil) {
+			t.Fatalf("%s: expected %v, got %v", tc.Name, tc.Expected, err)
+		}
+		if len(report.warnings) != tc.Warnings {
+			t.Fatalf("%s: expected %d warnings, got %v", tc.Name, tc.Warnings, report.warnings)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package levant
+
+import "errors"
+
+// The errors returned from the Levant plan and deployment entry points. The
+// errors returned wrap these so callers can use errors.Is to determine why a
+// run did not succeed.
+var (
+	// ErrPlanNoChanges is returned when the Nomad plan does not detect any
+	// changes between the rendered and the running job.
+	ErrPlanNoChanges = errors.New("plan detected no changes")
+
+	// ErrPlanFailed is returned when the Nomad plan could not be run or
+	// identified changes which are not allowed.
+	ErrPlanFailed = errors.New("plan failed")
+
+	// ErrPlanAdditions is returned when the plan is summarising additions
+	// and has identified task groups or tasks added to the job.
+	ErrPlanAdditions = errors.New("plan contains additions")
+
+	// ErrValidateFailed is returned when Nomad rejects the rendered job as
+	// invalid.
+	ErrValidateFailed = errors.New("job validation failed")
+
+	// ErrDeployFailed is returned when the job could not be registered or did
+	// not reach a healthy state.
+	ErrDeployFailed = errors.New("deployment failed")
+
+	// ErrDeployTimeout is returned when Levant gave up waiting on Nomad during
+	// the deployment.
+	ErrDeployTimeout = errors.New("deployment timeout reached")
+
+	// ErrDeployInterrupted is returned when Levant stopped watching the
+	// deployment after receiving an interrupt or terminate signal. The
+	// deployment itself continues unless it was cancelled.
+	ErrDeployInterrupted = errors.New("deployment watch interrupted")
+)
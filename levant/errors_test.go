@@ -0,0 +1,21 @@
+package levant
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestErrors_jobStatusError(t *testing.T) {
+
+	if err := jobStatusError(true); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	err := jobStatusError(false)
+	if !errors.Is(err, ErrDeployFailed) {
+		t.Fatalf("expected error to wrap ErrDeployFailed, got %v", err)
+	}
+	if errors.Is(err, ErrDeployTimeout) {
+		t.Fatalf("expected error not to wrap ErrDeployTimeout, got %v", err)
+	}
+}
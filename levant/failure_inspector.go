@@ -6,7 +6,6 @@ import (
 	"sync"
 
 	nomad "github.com/hashicorp/nomad/api"
-	"github.com/rs/zerolog/log"
 )
 
 // checkFailedDeployment helps log information about deployment failures.
@@ -16,7 +15,7 @@ func (l *levantDeployment) checkFailedDeployment(depID *string) {
 
 	allocs, _, err := l.nomad.Deployments().Allocations(*depID, nil)
 	if err != nil {
-		log.Error().Msgf("levant/failure_inspector: unable to query deployment allocations for deployment %v",
+		l.logger().Error().Msgf("levant/failure_inspector: unable to query deployment allocations for deployment %v",
 			depID)
 	}
 
@@ -40,7 +39,7 @@ func (l *levantDeployment) checkFailedDeployment(depID *string) {
 
 	// Inspect each allocation.
 	for _, id := range allocIDS {
-		log.Debug().Msgf("levant/failure_inspector: launching allocation inspector for alloc %v", id)
+		l.logger().Debug().Msgf("levant/failure_inspector: launching allocation inspector for alloc %v", id)
 		go l.allocInspector(id, &wg)
 	}
 
@@ -56,7 +55,7 @@ func (l *levantDeployment) allocInspector(allocID string, wg *sync.WaitGroup) {
 
 	resp, _, err := l.nomad.Allocations().Info(allocID, nil)
 	if err != nil {
-		log.Error().Msgf("levant/failure_inspector: unable to query alloc %v: %v", allocID, err)
+		l.logger().Error().Msgf("levant/failure_inspector: unable to query alloc %v: %v", allocID, err)
 		return
 	}
 
@@ -137,10 +136,10 @@ func (l *levantDeployment) allocInspector(allocID string, wg *sync.WaitGroup) {
 			// If we have matched and have an updated desc then log the appropriate
 			// information.
 			if desc != "" {
-				log.Error().Msgf("levant/failure_inspector: alloc %s incurred event %s because %s",
+				l.logger().Error().Msgf("levant/failure_inspector: alloc %s incurred event %s because %s",
 					allocID, strings.ToLower(event.Type), strings.TrimSpace(desc))
 			} else {
-				log.Error().Msgf("levant/failure_inspector: alloc %s logged for failure; event_type: %s; message: %s",
+				l.logger().Error().Msgf("levant/failure_inspector: alloc %s logged for failure; event_type: %s; message: %s",
 					allocID,
 					strings.ToLower(event.Type),
 					strings.ToLower(event.DisplayMessage))
@@ -0,0 +1,87 @@
+package levant
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	nomad "github.com/hashicorp/nomad/api"
+)
+
+// healthyGraceWatcher continues to watch the allocations of a successful
+// deployment for the grace period, returning an error if any of them regress
+// by failing, being marked unhealthy, or having a task restart.
+func (l *levantDeployment) healthyGraceWatcher(depID string, grace time.Duration) error {
+
+	l.logger().Info().Msgf("levant/healthy_grace: watching allocations of deployment %s remain healthy for %v", depID, grace)
+
+	deadline := time.Now().Add(grace)
+	q := &nomad.QueryOptions{WaitIndex: 1, AllowStale: l.config.Client.AllowStale}
+
+	// The task restarts of each allocation when first seen, so restarts
+	// during the grace period can be identified.
+	restarts := make(map[string]uint64)
+
+	for {
+		allocs, meta, err := l.nomad.Deployments().Allocations(depID, q)
+		if err != nil {
+			return fmt.Errorf("unable to query allocations of deployment %s: %v", depID, err)
+		}
+
+		if regressed := allocRegressions(allocs, restarts); len(regressed) > 0 {
+			return fmt.Errorf("deployment %s regressed within the healthy grace period of %v: %s",
+				depID, grace, strings.Join(regressed, ", "))
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			l.logger().Info().Msgf("levant/healthy_grace: allocations of deployment %s remained healthy for %v", depID, grace)
+			return nil
+		}
+
+		q.WaitIndex = meta.LastIndex
+		q.WaitTime = remaining
+	}
+}
+
+// allocRegressions returns a description of each running allocation which has
+// failed, been marked unhealthy or had a task restart since it was first seen.
+// The restarts map is updated with the restarts of allocations seen for the
+// first time. Allocations the scheduler has stopped, such as those replaced
+// by the deployment, are not checked.
+func allocRegressions(allocs []*nomad.AllocationListStub, restarts map[string]uint64) []string {
+
+	var regressed []string
+
+	for _, a := range allocs {
+		if a.DesiredStatus != nomad.AllocDesiredStatusRun {
+			continue
+		}
+
+		var count uint64
+		for _, ts := range a.TaskStates {
+			if ts != nil {
+				count += ts.Restarts
+			}
+		}
+
+		seen, ok := restarts[a.ID]
+		if !ok {
+			restarts[a.ID] = count
+			seen = count
+		}
+
+		switch {
+		case a.ClientStatus == nomad.AllocClientStatusFailed || a.ClientStatus == nomad.AllocClientStatusLost:
+			regressed = append(regressed, fmt.Sprintf("allocation %s is %s", a.ID, a.ClientStatus))
+		case a.DeploymentStatus != nil && a.DeploymentStatus.Healthy != nil && !*a.DeploymentStatus.Healthy:
+			regressed = append(regressed, fmt.Sprintf("allocation %s is unhealthy", a.ID))
+		case count > seen:
+			regressed = append(regressed, fmt.Sprintf("allocation %s restarted %d time(s)", a.ID, count-seen))
+		}
+	}
+
+	sort.Strings(regressed)
+	return regressed
+}
@@ -0,0 +1,51 @@
+package levant
+
+import (
+	"reflect"
+	"testing"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/helper"
+)
+
+func TestHealthyGrace_allocRegressions(t *testing.T) {
+
+	alloc := func(id, client string, healthy bool, restarts uint64) *nomad.AllocationListStub {
+		return &nomad.AllocationListStub{
+			ID:               id,
+			DesiredStatus:    nomad.AllocDesiredStatusRun,
+			ClientStatus:     client,
+			DeploymentStatus: &nomad.AllocDeploymentStatus{Healthy: helper.BoolToPtr(healthy)},
+			TaskStates:       map[string]*nomad.TaskState{"redis": {Restarts: restarts}},
+		}
+	}
+
+	restarts := make(map[string]uint64)
+
+	// Restarts before the grace period are the baseline for each allocation.
+	allocs := []*nomad.AllocationListStub{
+		alloc("a1", nomad.AllocClientStatusRunning, true, 1),
+		alloc("a2", nomad.AllocClientStatusRunning, true, 0),
+	}
+	if regressed := allocRegressions(allocs, restarts); len(regressed) != 0 {
+		t.Fatalf("expected no regressions, got %v", regressed)
+	}
+
+	stopped := alloc("a3", nomad.AllocClientStatusFailed, false, 4)
+	stopped.DesiredStatus = nomad.AllocDesiredStatusStop
+
+	allocs = []*nomad.AllocationListStub{
+		alloc("a1", nomad.AllocClientStatusRunning, true, 3),
+		alloc("a2", nomad.AllocClientStatusFailed, true, 0),
+		stopped,
+		alloc("a4", nomad.AllocClientStatusRunning, false, 0),
+	}
+	expected := []string{
+		"allocation a1 restarted 2 time(s)",
+		"allocation a2 is failed",
+		"allocation a4 is unhealthy",
+	}
+	if regressed := allocRegressions(allocs, restarts); !reflect.DeepEqual(regressed, expected) {
+		t.Fatalf("expected %v, got %v", expected, regressed)
+	}
+}
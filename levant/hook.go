@@ -0,0 +1,180 @@
+package levant
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+)
+
+// The environment variables set when running the deploy hooks.
+const (
+	hookEnvJobID        = "LEVANT_JOB_ID"
+	hookEnvNomadAddr    = "LEVANT_NOMAD_ADDR"
+	hookEnvPlanChanges  = "LEVANT_PLAN_CHANGES"
+	hookEnvDeploymentID = "LEVANT_DEPLOYMENT_ID"
+	hookEnvStatus       = "LEVANT_DEPLOY_STATUS"
+)
+
+// The final deployment statuses passed to the post-deploy and on-failure
+// hooks.
+const (
+	DeployStatusSuccessful  = "successful"
+	DeployStatusFailed      = "failed"
+	DeployStatusTimeout     = "timeout"
+	DeployStatusInterrupted = "interrupted"
+)
+
+// DeployHook describes the job being deployed, which is passed to the hook
+// command within its environment.
+type DeployHook struct {
+	// Command is run using the shell.
+	Command string
+
+	// Context, when set, kills the command once done, such as when the
+	// deadline of the whole command is reached.
+	Context context.Context
+
+	JobID     string
+	NomadAddr string
+
+	// PlanChanges is the number of changes identified by the plan, or nil if
+	// the plan was not run.
+	PlanChanges *int
+
+	// DeploymentID and Status are set once the deployment has finished. The
+	// deployment ID is empty for jobs which do not use Nomad deployments.
+	DeploymentID string
+	Status       string
+}
+
+// RunPreDeployHook runs the pre-deploy hook command before the job is
+// registered. An error wrapping ErrDeployFailed is returned if the command
+// could not be run or exited with a nonzero status, in which case the
+// deployment should not continue.
+func RunPreDeployHook(hook *DeployHook) error {
+	if err := runHook("pre-deploy", hook); err != nil {
+		log.Error().Err(err).Msg("levant/hook: pre-deploy hook failed; the job will not be registered")
+		return fmt.Errorf("%w: pre-deploy hook failed: %v", ErrDeployFailed, err)
+	}
+	return nil
+}
+
+// RunPostDeployHook runs the post-deploy hook command after a successful
+// deployment. Failures are logged and returned so the caller can decide
+// whether they affect the result of the deployment.
+func RunPostDeployHook(hook *DeployHook) error {
+	if err := runHook("post-deploy", hook); err != nil {
+		log.Error().Err(err).Msg("levant/hook: post-deploy hook failed")
+		return fmt.Errorf("post-deploy hook failed: %v", err)
+	}
+	return nil
+}
+
+// RunOnFailureHook runs the on-failure hook command after a deployment has
+// failed. Failures are logged and returned.
+func RunOnFailureHook(hook *DeployHook) error {
+	if err := runHook("on-failure", hook); err != nil {
+		log.Error().Err(err).Msg("levant/hook: on-failure hook failed")
+		return fmt.Errorf("on-failure hook failed: %v", err)
+	}
+	return nil
+}
+
+// DeployStatus returns the final deployment status passed to the hooks for
+// the error returned from the deployment.
+func DeployStatus(err error) string {
+	switch {
+	case err == nil:
+		return DeployStatusSuccessful
+	case errors.Is(err, ErrDeployTimeout):
+		return DeployStatusTimeout
+	case errors.Is(err, ErrDeployInterrupted):
+		return DeployStatusInterrupted
+	default:
+		return DeployStatusFailed
+	}
+}
+
+// runHook runs the hook command, logging each line of its output.
+func runHook(name string, hook *DeployHook) error {
+
+	log.Info().Msgf("levant/hook: running %s hook %q", name, hook.Command)
+
+	stdout := &hookLogWriter{stream: "stdout"}
+	stderr := &hookLogWriter{stream: "stderr"}
+
+	// The hook is killed if the overall deadline of the command is reached.
+	ctx := hook.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", hook.Command)
+	cmd.Env = append(os.Environ(), hook.env()...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err := cmd.Run()
+	stdout.Flush()
+	stderr.Flush()
+
+	if err != nil {
+		return err
+	}
+
+	log.Info().Msgf("levant/hook: %s hook completed successfully", name)
+	return nil
+}
+
+// env returns the environment variables describing the job passed to the
+// hook command.
+func (h *DeployHook) env() []string {
+	env := []string{
+		hookEnvJobID + "=" + h.JobID,
+		hookEnvNomadAddr + "=" + h.NomadAddr,
+	}
+	if h.PlanChanges != nil {
+		env = append(env, hookEnvPlanChanges+"="+strconv.Itoa(*h.PlanChanges))
+	}
+	if h.Status != "" {
+		env = append(env, hookEnvDeploymentID+"="+h.DeploymentID, hookEnvStatus+"="+h.Status)
+	}
+	return env
+}
+
+// hookLogWriter logs each line written by the hook command.
+type hookLogWriter struct {
+	stream string
+	buf    bytes.Buffer
+}
+
+func (w *hookLogWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+
+	for {
+		i := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if i < 0 {
+			break
+		}
+		line := w.buf.Next(i + 1)
+		w.log(string(bytes.TrimRight(line, "\r\n")))
+	}
+	return len(p), nil
+}
+
+// Flush logs any remaining output not terminated by a newline.
+func (w *hookLogWriter) Flush() {
+	if w.buf.Len() > 0 {
+		w.log(w.buf.String())
+		w.buf.Reset()
+	}
+}
+
+func (w *hookLogWriter) log(line string) {
+	log.Info().Str("stream", w.stream).Msgf("levant/hook: %s", line)
+}
@@ -0,0 +1,106 @@
+package levant
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func TestHook_RunPreDeployHook(t *testing.T) {
+
+	var buf bytes.Buffer
+	log.Logger = zerolog.New(&buf)
+
+	changes := 3
+	hook := &DeployHook{
+		Command:     `echo "job $LEVANT_JOB_ID has $LEVANT_PLAN_CHANGES changes"; printf 'warning' >&2`,
+		JobID:       "example",
+		NomadAddr:   "http://127.0.0.1:4646",
+		PlanChanges: &changes,
+	}
+
+	if err := RunPreDeployHook(hook); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, e := range []string{
+		`"stream":"stdout","message":"levant/hook: job example has 3 changes"`,
+		`"stream":"stderr","message":"levant/hook: warning"`,
+	} {
+		if !strings.Contains(buf.String(), e) {
+			t.Fatalf("expected hook output to contain %q, got %s", e, buf.String())
+		}
+	}
+
+	hook.Command = "exit 3"
+	if err := RunPreDeployHook(hook); !errors.Is(err, ErrDeployFailed) {
+		t.Fatalf("expected error wrapping ErrDeployFailed, got %v", err)
+	}
+}
+
+func TestHook_RunPostDeployHook(t *testing.T) {
+
+	var buf bytes.Buffer
+	log.Logger = zerolog.New(&buf)
+
+	hook := &DeployHook{
+		Command:      `echo "$LEVANT_DEPLOYMENT_ID $LEVANT_DEPLOY_STATUS"`,
+		JobID:        "example",
+		DeploymentID: "d3e1b5c2",
+		Status:       DeployStatus(nil),
+	}
+
+	if err := RunPostDeployHook(hook); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e := `"message":"levant/hook: d3e1b5c2 successful"`; !strings.Contains(buf.String(), e) {
+		t.Fatalf("expected hook output to contain %q, got %s", e, buf.String())
+	}
+
+	hook.Command = "exit 1"
+	if err := RunOnFailureHook(hook); err == nil || errors.Is(err, ErrDeployFailed) {
+		t.Fatalf("expected hook error not wrapping ErrDeployFailed, got %v", err)
+	}
+}
+
+func TestHook_runHookDeadline(t *testing.T) {
+
+	log.Logger = zerolog.New(ioutil.Discard)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := RunPostDeployHook(&DeployHook{Command: "exec sleep 5", Context: ctx, JobID: "example"}); err == nil {
+		t.Fatal("expected hook to be killed once the deadline was reached")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("expected hook to be killed by the deadline, took %v", elapsed)
+	}
+}
+
+func TestHook_DeployStatus(t *testing.T) {
+	cases := []struct {
+		err      error
+		expected string
+	}{
+		{nil, DeployStatusSuccessful},
+		{fmt.Errorf("%w: boom", ErrDeployFailed), DeployStatusFailed},
+		{fmt.Errorf("%w: 5m", ErrDeployTimeout), DeployStatusTimeout},
+		{fmt.Errorf("%w: signal", ErrDeployInterrupted), DeployStatusInterrupted},
+	}
+
+	for _, tc := range cases {
+		if actual := DeployStatus(tc.err); actual != tc.expected {
+			t.Fatalf("expected status %q for %v, got %q", tc.expected, tc.err, actual)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package levant
+
+import (
+	"fmt"
+	"strings"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/jrasell/levant/client"
+	"github.com/rs/zerolog"
+)
+
+// TriggerImageCheck compares the images of the rendered job against those of
+// the running job and returns true if they are unchanged, in which case the
+// deployment can be skipped. If task is not empty, in the form group.task,
+// only the image of that task is compared. A job which is not running is
+// always considered changed.
+func TriggerImageCheck(config *DeployConfig, task string) (bool, error) {
+
+	c, err := client.NewNomadTargetClient(config.Client)
+	if err != nil {
+		config.logger().Error().Msgf("levant/image_check: unable to setup Levant image check: %v", err)
+		return false, err
+	}
+
+	job := config.Template.Job
+
+	running, _, err := c.Jobs().Info(*job.ID, &nomad.QueryOptions{AllowStale: config.Client.AllowStale})
+	if err != nil && strings.Contains(err.Error(), "404") {
+		config.logger().Info().Msg("levant/image_check: job is not running, continuing with deployment")
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("unable to query running job %s: %v", *job.ID, err)
+	}
+
+	if running.Stop != nil && *running.Stop {
+		config.logger().Info().Msg("levant/image_check: job is stopped, continuing with deployment")
+		return false, nil
+	}
+
+	unchanged, err := imagesUnchanged(config.logger(), job, running, task)
+	if err != nil {
+		return false, err
+	}
+
+	if unchanged {
+		config.logger().Info().Msg("levant/image_check: images are unchanged from the running job, skipping deployment")
+	} else {
+		config.logger().Info().Msg("levant/image_check: images differ from the running job, continuing with deployment")
+	}
+	return unchanged, nil
+}
+
+// imagesUnchanged compares the image config field of the tasks of the
+// rendered job against the matching tasks of the running job. Tasks without
+// an image, such as those not using the Docker driver, are ignored unless
+// named. Images are compared as written so the check is reliable when images
+// are referenced by digest.
+func imagesUnchanged(logger *zerolog.Logger, job, running *nomad.Job, task string) (bool, error) {
+
+	rendered := jobImages(job)
+	current := jobImages(running)
+
+	if task != "" {
+		image, ok := rendered[task]
+		if !ok {
+			return false, fmt.Errorf("unable to compare image of %s as no task with an image matches the group.task name", task)
+		}
+		return current[task] == image, nil
+	}
+
+	if len(rendered) == 0 {
+		return false, nil
+	}
+
+	for name, image := range rendered {
+		if current[name] != image {
+			logger.Debug().Msgf("levant/image_check: image of %s changed from %q to %q", name, current[name], image)
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// jobImages returns the image config field of each task of the job keyed on
+// the group and task name in the form group.task.
+func jobImages(job *nomad.Job) map[string]string {
+
+	images := make(map[string]string)
+
+	for _, group := range job.TaskGroups {
+		if group.Name == nil {
+			continue
+		}
+		for _, task := range group.Tasks {
+			if image, ok := task.Config["image"].(string); ok {
+				images[*group.Name+"."+task.Name] = image
+			}
+		}
+	}
+	return images
+}
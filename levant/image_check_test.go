@@ -0,0 +1,104 @@
+package levant
+
+import (
+	"testing"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/helper"
+	"github.com/rs/zerolog/log"
+)
+
+func imageCheckJob(images map[string]string) *nomad.Job {
+
+	group := &nomad.TaskGroup{Name: helper.StringToPtr("cache")}
+	for name, image := range images {
+		group.Tasks = append(group.Tasks, &nomad.Task{
+			Name:   name,
+			Driver: "docker",
+			Config: map[string]interface{}{"image": image},
+		})
+	}
+	group.Tasks = append(group.Tasks, &nomad.Task{
+		Name:   "script",
+		Driver: "exec",
+		Config: map[string]interface{}{"command": "/bin/true"},
+	})
+
+	return &nomad.Job{ID: helper.StringToPtr("example"), TaskGroups: []*nomad.TaskGroup{group}}
+}
+
+func TestImageCheck_imagesUnchanged(t *testing.T) {
+
+	digest := "redis@sha256:0ed5d5928d4737458944eb604cc8509e245c3e19d02ad83935398bc4b991aac7"
+
+	cases := []struct {
+		Name     string
+		Rendered map[string]string
+		Running  map[string]string
+		Task     string
+		Expected bool
+		Error    bool
+	}{
+		{
+			Name:     "all unchanged",
+			Rendered: map[string]string{"redis": digest, "proxy": "envoy:1.13"},
+			Running:  map[string]string{"redis": digest, "proxy": "envoy:1.13"},
+			Expected: true,
+		},
+		{
+			Name:     "one changed",
+			Rendered: map[string]string{"redis": digest, "proxy": "envoy:1.14"},
+			Running:  map[string]string{"redis": digest, "proxy": "envoy:1.13"},
+			Expected: false,
+		},
+		{
+			Name:     "task added",
+			Rendered: map[string]string{"redis": digest, "proxy": "envoy:1.13"},
+			Running:  map[string]string{"redis": digest},
+			Expected: false,
+		},
+		{
+			Name:     "named task unchanged",
+			Rendered: map[string]string{"redis": digest, "proxy": "envoy:1.14"},
+			Running:  map[string]string{"redis": digest, "proxy": "envoy:1.13"},
+			Task:     "cache.redis",
+			Expected: true,
+		},
+		{
+			Name:     "named task changed",
+			Rendered: map[string]string{"redis": "redis:4.0"},
+			Running:  map[string]string{"redis": digest},
+			Task:     "cache.redis",
+			Expected: false,
+		},
+		{
+			Name:     "named task without image",
+			Rendered: map[string]string{"redis": digest},
+			Running:  map[string]string{"redis": digest},
+			Task:     "cache.script",
+			Error:    true,
+		},
+		{
+			Name:     "no images",
+			Rendered: map[string]string{},
+			Running:  map[string]string{},
+			Expected: false,
+		},
+	}
+
+	for _, tc := range cases {
+		out, err := imagesUnchanged(&log.Logger, imageCheckJob(tc.Rendered), imageCheckJob(tc.Running), tc.Task)
+		if tc.Error {
+			if err == nil {
+				t.Fatalf("case %s: expected error", tc.Name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("case %s: unexpected error: %v", tc.Name, err)
+		}
+		if out != tc.Expected {
+			t.Fatalf("case %s: expected %t, got %t", tc.Name, tc.Expected, out)
+		}
+	}
+}
@@ -1,8 +1,12 @@
 package levant
 
 import (
+	"fmt"
+	"sort"
+	"time"
+
 	nomad "github.com/hashicorp/nomad/api"
-	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog"
 )
 
 // TaskCoordinate is a coordinate for an allocation/task combination
@@ -16,7 +20,7 @@ type TaskCoordinate struct {
 // more checks.
 func (l *levantDeployment) jobStatusChecker(evalID *string) bool {
 
-	log.Debug().Msgf("levant/job_status_checker: running job status checker for job")
+	l.logger().Debug().Msgf("levant/job_status_checker: running job status checker for job")
 
 	// Run the initial job status check to ensure the job reaches a state of
 	// running.
@@ -28,6 +32,18 @@ func (l *levantDeployment) jobStatusChecker(evalID *string) bool {
 		return jStatus
 	}
 
+	// Batch jobs run to completion so are checked until all allocations reach
+	// a terminal state.
+	if jStatus && l.config.Template.Job.Type != nil && *l.config.Template.Job.Type == nomad.JobTypeBatch {
+		return l.batchJobCompletionChecker(*evalID)
+	}
+
+	// System jobs place an allocation on every eligible node so are checked
+	// until each node is running the job.
+	if jStatus && l.config.Template.Job.Type != nil && *l.config.Template.Job.Type == nomad.JobTypeSystem {
+		return l.systemJobCoverageChecker(*evalID)
+	}
+
 	// Job registrations that produce an evaluation can be more thoroughly
 	// checked even if they don't support Nomad deployments.
 	if jStatus {
@@ -47,7 +63,7 @@ func (l *levantDeployment) simpleJobStatusChecker() bool {
 
 		job, meta, err := l.nomad.Jobs().Info(*l.config.Template.Job.Name, q)
 		if err != nil {
-			log.Error().Err(err).Msg("levant/job_status_checker: unable to query job information from Nomad")
+			l.logger().Error().Err(err).Msg("levant/job_status_checker: unable to query job information from Nomad")
 			return false
 		}
 
@@ -60,14 +76,20 @@ func (l *levantDeployment) simpleJobStatusChecker() bool {
 		// Checks the status of the job and proceed as expected depending on this.
 		switch *job.Status {
 		case "running":
-			log.Info().Msgf("levant/job_status_checker: job has status %s", *job.Status)
+			l.logger().Info().Msgf("levant/job_status_checker: job has status %s", *job.Status)
 			return true
 		case "pending":
-			log.Debug().Msgf("levant/job_status_checker: job has status %s", *job.Status)
+			l.logger().Debug().Msgf("levant/job_status_checker: job has status %s", *job.Status)
 			q.WaitIndex = meta.LastIndex
 			continue
 		case "dead":
-			log.Error().Msgf("levant/job_status_checker: job has status %s", *job.Status)
+			// Batch jobs which complete quickly may already be dead, the
+			// completion checker determines whether they were successful.
+			if job.Type != nil && *job.Type == nomad.JobTypeBatch {
+				l.logger().Info().Msgf("levant/job_status_checker: batch job has status %s", *job.Status)
+				return true
+			}
+			l.logger().Error().Msgf("levant/job_status_checker: job has status %s", *job.Status)
 			return false
 		}
 	}
@@ -86,7 +108,7 @@ func (l *levantDeployment) jobAllocationChecker(evalID *string) bool {
 
 		allocs, meta, err := l.nomad.Evaluations().Allocations(*evalID, q)
 		if err != nil {
-			log.Error().Err(err).Msg("levant/job_status_checker: unable to query allocs of job from Nomad")
+			l.logger().Error().Err(err).Msg("levant/job_status_checker: unable to query allocs of job from Nomad")
 			return false
 		}
 
@@ -99,13 +121,13 @@ func (l *levantDeployment) jobAllocationChecker(evalID *string) bool {
 		// If we get here, set the wi to the latest Index.
 		q.WaitIndex = meta.LastIndex
 
-		complete, deadTasks := allocationStatusChecker(levantTasks, allocs)
+		complete, deadTasks := allocationStatusChecker(l.logger(), levantTasks, allocs)
 
 		// depending on how we finished up we report our status
 		// If we have no allocations left to track then we can exit and log
 		// information depending on the success.
 		if complete && deadTasks == 0 {
-			log.Info().Msg("levant/job_status_checker: all allocations in deployment of job are running")
+			l.logger().Info().Msg("levant/job_status_checker: all allocations in deployment of job are running")
 			return true
 		} else if complete && deadTasks > 0 {
 			return false
@@ -117,7 +139,7 @@ func (l *levantDeployment) jobAllocationChecker(evalID *string) bool {
 // job deployment, an update Levants internal tracking on task status based on
 // this. This functionality exists as Nomad does not currently support
 // deployments across all job types.
-func allocationStatusChecker(levantTasks map[TaskCoordinate]string, allocs []*nomad.AllocationListStub) (bool, int) {
+func allocationStatusChecker(logger *zerolog.Logger, levantTasks map[TaskCoordinate]string, allocs []*nomad.AllocationListStub) (bool, int) {
 
 	complete := true
 	deadTasks := 0
@@ -126,7 +148,7 @@ func allocationStatusChecker(levantTasks map[TaskCoordinate]string, allocs []*no
 		for taskName, task := range alloc.TaskStates {
 			// if the state is one we haven't seen yet then we print a message
 			if levantTasks[TaskCoordinate{alloc.ID, taskName}] != task.State {
-				log.Info().Msgf("levant/job_status_checker: task %s in allocation %s now in %s state",
+				logger.Info().Msgf("levant/job_status_checker: task %s in allocation %s now in %s state",
 					taskName, alloc.ID, task.State)
 				// then we record the new state
 				levantTasks[TaskCoordinate{alloc.ID, taskName}] = task.State
@@ -145,3 +167,324 @@ func allocationStatusChecker(levantTasks map[TaskCoordinate]string, allocs []*no
 	}
 	return complete, deadTasks
 }
+
+// batchEvalAPI is the subset of the Nomad evaluations API used when waiting
+// for the allocations of a batch job to complete.
+type batchEvalAPI interface {
+	Info(evalID string, q *nomad.QueryOptions) (*nomad.Evaluation, *nomad.QueryMeta, error)
+	Allocations(evalID string, q *nomad.QueryOptions) ([]*nomad.AllocationListStub, *nomad.QueryMeta, error)
+}
+
+// batchJobCompletionChecker waits for all allocations created by the batch job
+// evaluation to reach a terminal state, failing if any allocation failed or
+// any task exited with a nonzero exit code.
+func (l *levantDeployment) batchJobCompletionChecker(evalID string) bool {
+
+	l.logger().Info().Msg("levant/job_status_checker: waiting for allocations of batch job to complete")
+
+	var deadline time.Time
+	if l.config.Deploy.BatchTimeout > 0 {
+		deadline = time.Now().Add(l.config.Deploy.BatchTimeout)
+	}
+
+	q := &nomad.QueryOptions{WaitIndex: 1, WaitTime: 5 * time.Second, AllowStale: l.config.Client.AllowStale}
+
+	for {
+
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			l.logger().Error().Msgf("levant/job_status_checker: batch timeout of %v reached before all allocations completed",
+				l.config.Deploy.BatchTimeout)
+			return false
+		}
+
+		allocs, pending, err := batchEvalAllocations(l.nomad.Evaluations(), evalID, q)
+		if err != nil {
+			l.logger().Error().Err(err).Msg("levant/job_status_checker: unable to query allocs of job from Nomad")
+			return false
+		}
+		if pending {
+			continue
+		}
+
+		// An evaluation which completed without placing or blocking any
+		// allocations, such as one for a job with no work to perform, leaves
+		// nothing to wait for.
+		if len(allocs) == 0 {
+			l.logger().Info().Msg("levant/job_status_checker: evaluation of batch job placed no allocations")
+			return true
+		}
+
+		complete, failures := batchAllocationStatus(allocs)
+		if !complete {
+			continue
+		}
+
+		if len(failures) > 0 {
+			for _, f := range failures {
+				l.logger().Error().Msgf("levant/job_status_checker: %s", f)
+			}
+			return false
+		}
+
+		l.logger().Info().Msgf("levant/job_status_checker: all %d allocations of batch job completed successfully", len(allocs))
+		return true
+	}
+}
+
+// batchEvalAllocations returns the allocations created by the evaluation and
+// by the blocked evaluations which follow it when not all allocations could
+// be placed, along with whether any of those evaluations is yet to complete.
+// The query of the allocations of the first evaluation blocks until they
+// change, advancing the wait index of the query options. Evaluations which
+// failed or were cancelled return an error.
+func batchEvalAllocations(evals batchEvalAPI, evalID string, q *nomad.QueryOptions) ([]*nomad.AllocationListStub, bool, error) {
+
+	var (
+		allocs  []*nomad.AllocationListStub
+		pending bool
+	)
+
+	for id := evalID; id != ""; {
+		eval, _, err := evals.Info(id, &nomad.QueryOptions{AllowStale: q.AllowStale})
+		if err != nil {
+			return nil, false, err
+		}
+
+		switch eval.Status {
+		case "failed", "canceled":
+			return nil, false, fmt.Errorf("evaluation %s has status %s: %s", eval.ID, eval.Status, eval.StatusDescription)
+		case "complete":
+		default:
+			pending = true
+		}
+
+		eq := &nomad.QueryOptions{AllowStale: q.AllowStale}
+		if id == evalID {
+			eq = q
+		}
+		evalAllocs, meta, err := evals.Allocations(id, eq)
+		if err != nil {
+			return nil, false, err
+		}
+		if id == evalID && meta.LastIndex > q.WaitIndex {
+			q.WaitIndex = meta.LastIndex
+		}
+		allocs = append(allocs, evalAllocs...)
+
+		id = eval.BlockedEval
+	}
+
+	return allocs, pending, nil
+}
+
+// batchAllocationStatus checks whether all the passed allocations have reached
+// a terminal client status and describes any which did not succeed. An empty
+// list is not complete, as the allocations are yet to be placed.
+func batchAllocationStatus(allocs []*nomad.AllocationListStub) (bool, []string) {
+
+	if len(allocs) == 0 {
+		return false, nil
+	}
+
+	var failures []string
+
+	for _, alloc := range allocs {
+		switch alloc.ClientStatus {
+		case nomad.AllocClientStatusComplete, nomad.AllocClientStatusFailed, nomad.AllocClientStatusLost:
+		default:
+			return false, nil
+		}
+
+		if alloc.ClientStatus != nomad.AllocClientStatusComplete {
+			failures = append(failures, fmt.Sprintf("allocation %s has client status %s", alloc.ID, alloc.ClientStatus))
+		}
+
+		for taskName, task := range alloc.TaskStates {
+			if code := taskExitCode(task); code != 0 {
+				failures = append(failures, fmt.Sprintf("task %s in allocation %s exited with code %d",
+					taskName, alloc.ID, code))
+			} else if task.Failed {
+				failures = append(failures, fmt.Sprintf("task %s in allocation %s failed", taskName, alloc.ID))
+			}
+		}
+	}
+
+	return true, failures
+}
+
+// taskExitCode returns the exit code of the last termination event of the
+// task, or zero if the task has not terminated.
+func taskExitCode(task *nomad.TaskState) int {
+	for i := len(task.Events) - 1; i >= 0; i-- {
+		if task.Events[i].Type == nomad.TaskTerminated {
+			return task.Events[i].ExitCode
+		}
+	}
+	return 0
+}
+
+// systemCoverage describes the placement of a system job across the eligible
+// nodes of the cluster, with each list holding node names.
+type systemCoverage struct {
+	healthy []string
+	pending []string
+	failed  []string
+	missing []string
+}
+
+// systemJobCoverageChecker waits for the allocations of the system job to be
+// running on all eligible nodes, reporting any nodes where placement failed.
+func (l *levantDeployment) systemJobCoverageChecker(evalID string) bool {
+
+	l.logger().Info().Msg("levant/job_status_checker: waiting for system job to be running on all eligible nodes")
+
+	var deadline time.Time
+	if l.config.Deploy.SystemTimeout > 0 {
+		deadline = time.Now().Add(l.config.Deploy.SystemTimeout)
+	}
+
+	q := &nomad.QueryOptions{AllowStale: l.config.Client.AllowStale}
+
+	job, _, err := l.nomad.Jobs().Info(*l.config.Template.Job.ID, q)
+	if err != nil {
+		l.logger().Error().Err(err).Msg("levant/job_status_checker: unable to query job information from Nomad")
+		return false
+	}
+
+	eval, _, err := l.nomad.Evaluations().Info(evalID, q)
+	if err != nil {
+		l.logger().Error().Err(err).Msgf("levant/job_status_checker: unable to query evaluation %s", evalID)
+		return false
+	}
+
+	for {
+		nodes, _, err := l.nomad.Nodes().List(q)
+		if err != nil {
+			l.logger().Error().Err(err).Msg("levant/job_status_checker: unable to list Nomad nodes")
+			return false
+		}
+
+		allocs, _, err := l.nomad.Jobs().Allocations(*job.ID, false, q)
+		if err != nil {
+			l.logger().Error().Err(err).Msg("levant/job_status_checker: unable to query allocs of job from Nomad")
+			return false
+		}
+
+		c := systemJobCoverage(job, nodes, allocs)
+
+		if len(c.failed) > 0 {
+			l.logger().Error().Msgf("levant/job_status_checker: allocations failed on nodes: %v", c.failed)
+			return false
+		}
+
+		if len(c.pending) == 0 {
+			return reportSystemCoverage(l.logger(), c, len(eval.FailedTGAllocs) > 0)
+		}
+
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			l.logger().Error().Msgf("levant/job_status_checker: system timeout of %v reached with allocations pending on nodes: %v",
+				l.config.Deploy.SystemTimeout, c.pending)
+			return false
+		}
+
+		l.logger().Debug().Msgf("levant/job_status_checker: system job running on %d node(s), pending on %d node(s)",
+			len(c.healthy), len(c.pending))
+		time.Sleep(2 * time.Second)
+	}
+}
+
+// reportSystemCoverage logs the final placement of the system job. Nodes
+// without an allocation are only treated as failures when the evaluation
+// reported failed placements; otherwise they are assumed to have been filtered
+// by the job constraints.
+func reportSystemCoverage(logger *zerolog.Logger, c *systemCoverage, placementFailed bool) bool {
+
+	if len(c.missing) > 0 {
+		if placementFailed {
+			logger.Error().Msgf("levant/job_status_checker: unable to place allocations on nodes: %v", c.missing)
+			return false
+		}
+		logger.Info().Msgf("levant/job_status_checker: no allocations on nodes %v which are likely filtered by job constraints",
+			c.missing)
+	}
+
+	logger.Info().Msgf("levant/job_status_checker: system job is running on %d eligible node(s)", len(c.healthy))
+	return true
+}
+
+// systemJobCoverage compares the allocations of the current job version
+// against the ready and eligible nodes within the job datacenters.
+func systemJobCoverage(job *nomad.Job, nodes []*nomad.NodeListStub, allocs []*nomad.AllocationListStub) *systemCoverage {
+
+	dcs := make(map[string]bool)
+	for _, dc := range job.Datacenters {
+		dcs[dc] = true
+	}
+
+	byNode := make(map[string][]*nomad.AllocationListStub)
+	for _, alloc := range allocs {
+		if alloc.DesiredStatus != nomad.AllocDesiredStatusRun {
+			continue
+		}
+		if job.Version != nil && alloc.JobVersion != *job.Version {
+			continue
+		}
+		byNode[alloc.NodeID] = append(byNode[alloc.NodeID], alloc)
+	}
+
+	c := &systemCoverage{}
+
+	for _, node := range nodes {
+		if node.Status != nomad.NodeStatusReady || node.SchedulingEligibility != nomad.NodeSchedulingEligible ||
+			node.Drain || !dcs[node.Datacenter] {
+			continue
+		}
+
+		nodeAllocs, ok := byNode[node.ID]
+		if !ok {
+			c.missing = append(c.missing, node.Name)
+			continue
+		}
+
+		switch systemNodeStatus(nodeAllocs) {
+		case nomad.AllocClientStatusRunning:
+			c.healthy = append(c.healthy, node.Name)
+		case nomad.AllocClientStatusFailed:
+			c.failed = append(c.failed, node.Name)
+		default:
+			c.pending = append(c.pending, node.Name)
+		}
+	}
+
+	sort.Strings(c.healthy)
+	sort.Strings(c.pending)
+	sort.Strings(c.failed)
+	sort.Strings(c.missing)
+
+	return c
+}
+
+// systemNodeStatus summarises the allocations on a single node as running
+// when all tasks are running, failed when any allocation failed and pending
+// otherwise.
+func systemNodeStatus(allocs []*nomad.AllocationListStub) string {
+
+	status := nomad.AllocClientStatusRunning
+
+	for _, alloc := range allocs {
+		switch alloc.ClientStatus {
+		case nomad.AllocClientStatusFailed, nomad.AllocClientStatusLost:
+			return nomad.AllocClientStatusFailed
+		case nomad.AllocClientStatusRunning:
+			for _, task := range alloc.TaskStates {
+				if task.State != "running" {
+					status = nomad.AllocClientStatusPending
+				}
+			}
+		default:
+			status = nomad.AllocClientStatusPending
+		}
+	}
+
+	return status
+}
@@ -1,9 +1,12 @@
 package levant
 
 import (
+	"fmt"
+	"reflect"
 	"testing"
 
 	nomad "github.com/hashicorp/nomad/api"
+	"github.com/rs/zerolog/log"
 )
 
 func TestJobStatusChecker_allocationStatusChecker(t *testing.T) {
@@ -70,7 +73,7 @@ func TestJobStatusChecker_allocationStatusChecker(t *testing.T) {
 	}
 
 	for _, tc := range cases {
-		complete, dead := allocationStatusChecker(tc.levantTasks, tc.allocs)
+		complete, dead := allocationStatusChecker(&log.Logger, tc.levantTasks, tc.allocs)
 
 		if complete != tc.expectedComplete {
 			t.Fatalf("expected complete to be %v but got %v", tc.expectedComplete, complete)
@@ -80,3 +83,213 @@ func TestJobStatusChecker_allocationStatusChecker(t *testing.T) {
 		}
 	}
 }
+
+func TestJobStatusChecker_batchAllocationStatus(t *testing.T) {
+
+	terminated := func(code int) *nomad.TaskState {
+		return &nomad.TaskState{
+			State:  "dead",
+			Events: []*nomad.TaskEvent{{Type: nomad.TaskStarted}, {Type: nomad.TaskTerminated, ExitCode: code}},
+		}
+	}
+
+	cases := []struct {
+		Allocs   []*nomad.AllocationListStub
+		Complete bool
+		Failures int
+	}{
+		{
+			Allocs: []*nomad.AllocationListStub{
+				{ID: "a1", ClientStatus: nomad.AllocClientStatusComplete, TaskStates: map[string]*nomad.TaskState{"task1": terminated(0)}},
+				{ID: "a2", ClientStatus: nomad.AllocClientStatusRunning, TaskStates: map[string]*nomad.TaskState{"task1": {State: "running"}}},
+			},
+			Complete: false,
+			Failures: 0,
+		},
+		{
+			Allocs: []*nomad.AllocationListStub{
+				{ID: "a1", ClientStatus: nomad.AllocClientStatusComplete, TaskStates: map[string]*nomad.TaskState{"task1": terminated(0)}},
+				{ID: "a2", ClientStatus: nomad.AllocClientStatusComplete, TaskStates: map[string]*nomad.TaskState{"task1": terminated(0)}},
+			},
+			Complete: true,
+			Failures: 0,
+		},
+		{
+			Allocs: []*nomad.AllocationListStub{
+				{ID: "a1", ClientStatus: nomad.AllocClientStatusComplete, TaskStates: map[string]*nomad.TaskState{"task1": terminated(0)}},
+				{ID: "a2", ClientStatus: nomad.AllocClientStatusFailed, TaskStates: map[string]*nomad.TaskState{"task1": terminated(2)}},
+			},
+			Complete: true,
+			Failures: 2,
+		},
+		{
+			Allocs: []*nomad.AllocationListStub{
+				{ID: "a1", ClientStatus: nomad.AllocClientStatusLost},
+			},
+			Complete: true,
+			Failures: 1,
+		},
+		{
+			Allocs:   nil,
+			Complete: false,
+			Failures: 0,
+		},
+	}
+
+	for i, tc := range cases {
+		complete, failures := batchAllocationStatus(tc.Allocs)
+		if complete != tc.Complete {
+			t.Fatalf("case %d: got complete %v, expected %v", i, complete, tc.Complete)
+		}
+		if len(failures) != tc.Failures {
+			t.Fatalf("case %d: got failures %v, expected %d", i, failures, tc.Failures)
+		}
+	}
+}
+
+// fakeEvals serves evaluations and their allocations keyed on evaluation ID.
+type fakeEvals struct {
+	evals  map[string]*nomad.Evaluation
+	allocs map[string][]*nomad.AllocationListStub
+}
+
+func (f *fakeEvals) Info(evalID string, q *nomad.QueryOptions) (*nomad.Evaluation, *nomad.QueryMeta, error) {
+	eval, ok := f.evals[evalID]
+	if !ok {
+		return nil, nil, fmt.Errorf("evaluation %s not found", evalID)
+	}
+	return eval, &nomad.QueryMeta{}, nil
+}
+
+func (f *fakeEvals) Allocations(evalID string, q *nomad.QueryOptions) ([]*nomad.AllocationListStub, *nomad.QueryMeta, error) {
+	return f.allocs[evalID], &nomad.QueryMeta{LastIndex: 10}, nil
+}
+
+func TestJobStatusChecker_batchEvalAllocations(t *testing.T) {
+
+	done := &nomad.AllocationListStub{ID: "a1", ClientStatus: nomad.AllocClientStatusComplete}
+	queued := &nomad.AllocationListStub{ID: "a2", ClientStatus: nomad.AllocClientStatusComplete}
+
+	cases := []struct {
+		Name    string
+		Evals   []*nomad.Evaluation
+		Allocs  map[string][]*nomad.AllocationListStub
+		Count   int
+		Pending bool
+		Error   bool
+	}{
+		{
+			Name:    "eval pending without allocs",
+			Evals:   []*nomad.Evaluation{{ID: "e1", Status: "pending"}},
+			Count:   0,
+			Pending: true,
+		},
+		{
+			Name:    "eval complete without allocs",
+			Evals:   []*nomad.Evaluation{{ID: "e1", Status: "complete"}},
+			Count:   0,
+			Pending: false,
+		},
+		{
+			Name: "blocked eval yet to place allocs",
+			Evals: []*nomad.Evaluation{
+				{ID: "e1", Status: "complete", BlockedEval: "e2"},
+				{ID: "e2", Status: "blocked"},
+			},
+			Allocs:  map[string][]*nomad.AllocationListStub{"e1": {done}},
+			Count:   1,
+			Pending: true,
+		},
+		{
+			Name: "blocked eval placed allocs",
+			Evals: []*nomad.Evaluation{
+				{ID: "e1", Status: "complete", BlockedEval: "e2"},
+				{ID: "e2", Status: "complete"},
+			},
+			Allocs:  map[string][]*nomad.AllocationListStub{"e1": {done}, "e2": {queued}},
+			Count:   2,
+			Pending: false,
+		},
+		{
+			Name:  "eval failed",
+			Evals: []*nomad.Evaluation{{ID: "e1", Status: "failed"}},
+			Error: true,
+		},
+	}
+
+	for _, tc := range cases {
+		f := &fakeEvals{evals: make(map[string]*nomad.Evaluation), allocs: tc.Allocs}
+		for _, e := range tc.Evals {
+			f.evals[e.ID] = e
+		}
+
+		q := &nomad.QueryOptions{WaitIndex: 1}
+		allocs, pending, err := batchEvalAllocations(f, "e1", q)
+		if tc.Error {
+			if err == nil {
+				t.Fatalf("%s: expected error", tc.Name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.Name, err)
+		}
+		if len(allocs) != tc.Count || pending != tc.Pending {
+			t.Fatalf("%s: got %d allocs pending %v, expected %d pending %v",
+				tc.Name, len(allocs), pending, tc.Count, tc.Pending)
+		}
+		if q.WaitIndex != 10 {
+			t.Fatalf("%s: got wait index %d, expected 10", tc.Name, q.WaitIndex)
+		}
+	}
+}
+
+func TestJobStatusChecker_systemJobCoverage(t *testing.T) {
+
+	version := uint64(2)
+	job := &nomad.Job{Datacenters: []string{"dc1"}, Version: &version}
+
+	node := func(id, dc, status, eligibility string) *nomad.NodeListStub {
+		return &nomad.NodeListStub{ID: id, Name: id, Datacenter: dc, Status: status, SchedulingEligibility: eligibility}
+	}
+	nodes := []*nomad.NodeListStub{
+		node("node1", "dc1", nomad.NodeStatusReady, nomad.NodeSchedulingEligible),
+		node("node2", "dc1", nomad.NodeStatusReady, nomad.NodeSchedulingEligible),
+		node("node3", "dc1", nomad.NodeStatusReady, nomad.NodeSchedulingEligible),
+		node("node4", "dc1", nomad.NodeStatusReady, nomad.NodeSchedulingEligible),
+		node("node5", "dc1", "down", nomad.NodeSchedulingEligible),
+		node("node6", "dc1", nomad.NodeStatusReady, nomad.NodeSchedulingIneligible),
+		node("node7", "dc2", nomad.NodeStatusReady, nomad.NodeSchedulingEligible),
+	}
+
+	running := map[string]*nomad.TaskState{"task1": {State: "running"}}
+	allocs := []*nomad.AllocationListStub{
+		{NodeID: "node1", JobVersion: 2, DesiredStatus: "run", ClientStatus: "running", TaskStates: running},
+		{NodeID: "node2", JobVersion: 2, DesiredStatus: "run", ClientStatus: "pending"},
+		{NodeID: "node3", JobVersion: 2, DesiredStatus: "run", ClientStatus: "failed"},
+		{NodeID: "node4", JobVersion: 1, DesiredStatus: "run", ClientStatus: "running", TaskStates: running},
+		{NodeID: "node6", JobVersion: 2, DesiredStatus: "run", ClientStatus: "running", TaskStates: running},
+	}
+
+	c := systemJobCoverage(job, nodes, allocs)
+
+	if !reflect.DeepEqual(c.healthy, []string{"node1"}) {
+		t.Fatalf("got healthy nodes %v", c.healthy)
+	}
+	if !reflect.DeepEqual(c.pending, []string{"node2"}) {
+		t.Fatalf("got pending nodes %v", c.pending)
+	}
+	if !reflect.DeepEqual(c.failed, []string{"node3"}) {
+		t.Fatalf("got failed nodes %v", c.failed)
+	}
+	if !reflect.DeepEqual(c.missing, []string{"node4"}) {
+		t.Fatalf("got missing nodes %v", c.missing)
+	}
+
+	if !reportSystemCoverage(&log.Logger, &systemCoverage{missing: []string{"node4"}}, false) {
+		t.Fatal("expected nodes filtered by constraints to be allowed")
+	}
+	if reportSystemCoverage(&log.Logger, &systemCoverage{missing: []string{"node4"}}, true) {
+		t.Fatal("expected missing nodes with failed placements to fail")
+	}
+}
@@ -0,0 +1,111 @@
+package levant
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	consul "github.com/hashicorp/consul/api"
+	"github.com/jrasell/levant/client"
+	"github.com/jrasell/levant/levant/structs"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultDeployLockPrefix is the Consul KV prefix under which the deploy lock
+// of each job is stored.
+const DefaultDeployLockPrefix = "levant/deploy-lock"
+
+// DeployLock is a Consul session lock, keyed on the job ID, held for the
+// duration of a deployment so that concurrent runs of Levant do not deploy
+// the same job at the same time.
+type DeployLock struct {
+	key      string
+	lock     *consul.Lock
+	released chan struct{}
+}
+
+// AcquireDeployLock acquires the deploy lock of the job, waiting up to the
+// timeout for any other holder to release it. An error is returned if the
+// lock could not be acquired within the timeout. The lock is held within the
+// Consul cluster of the client config.
+func AcquireDeployLock(clientConfig *structs.ClientConfig, prefix, jobID string, timeout time.Duration) (*DeployLock, error) {
+	c, err := client.NewConsulClient(clientConfig.Context, clientConfig.ConsulAddr)
+	if err != nil {
+		return nil, err
+	}
+	return acquireDeployLock(c, prefix, jobID, timeout)
+}
+
+func acquireDeployLock(c *consul.Client, prefix, jobID string, timeout time.Duration) (*DeployLock, error) {
+
+	key := deployLockKey(prefix, jobID)
+
+	lock, err := c.LockOpts(&consul.LockOptions{
+		Key:          key,
+		Value:        []byte(jobID),
+		SessionName:  fmt.Sprintf("levant deploy of %s", jobID),
+		LockWaitTime: timeout,
+		LockTryOnce:  true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to setup deploy lock %s: %v", key, err)
+	}
+
+	log.Info().Msgf("levant/lock: acquiring deploy lock %s", key)
+
+	lostCh, err := lock.Lock(nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to acquire deploy lock %s: %v", key, err)
+	}
+	if lostCh == nil {
+		return nil, fmt.Errorf("unable to acquire deploy lock %s within %v, another deployment of job %s is in progress",
+			key, timeout, jobID)
+	}
+
+	l := &DeployLock{key: key, lock: lock, released: make(chan struct{})}
+	go l.monitor(lostCh)
+
+	log.Info().Msgf("levant/lock: acquired deploy lock %s", key)
+	return l, nil
+}
+
+// Release releases the deploy lock. Failures are logged as the lock is
+// released by Consul regardless once the session expires.
+func (l *DeployLock) Release() {
+	close(l.released)
+
+	if err := l.lock.Unlock(); err != nil {
+		log.Warn().Err(err).Msgf("levant/lock: unable to release deploy lock %s", l.key)
+		return
+	}
+	if err := l.lock.Destroy(); err != nil {
+		log.Debug().Err(err).Msgf("levant/lock: unable to remove deploy lock %s", l.key)
+	}
+
+	log.Info().Msgf("levant/lock: released deploy lock %s", l.key)
+}
+
+// monitor logs a warning if the lock is lost before it was released, for
+// example due to the Consul session being invalidated.
+func (l *DeployLock) monitor(lostCh <-chan struct{}) {
+	select {
+	case <-l.released:
+		return
+	case <-lostCh:
+	}
+
+	select {
+	case <-l.released:
+	default:
+		log.Warn().Msgf("levant/lock: deploy lock %s was lost during the deployment", l.key)
+	}
+}
+
+// deployLockKey returns the Consul KV key of the deploy lock of the job.
+func deployLockKey(prefix, jobID string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return jobID
+	}
+	return prefix + "/" + jobID
+}
@@ -0,0 +1,58 @@
+package levant
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	consul "github.com/hashicorp/consul/api"
+)
+
+func TestLock_deployLockKey(t *testing.T) {
+
+	cases := []struct {
+		Prefix   string
+		Expected string
+	}{
+		{Prefix: DefaultDeployLockPrefix, Expected: "levant/deploy-lock/example"},
+		{Prefix: "/locks/", Expected: "locks/example"},
+		{Prefix: "", Expected: "example"},
+	}
+
+	for _, tc := range cases {
+		if got := deployLockKey(tc.Prefix, "example"); got != tc.Expected {
+			t.Fatalf("prefix %q: got %q, expected %q", tc.Prefix, got, tc.Expected)
+		}
+	}
+}
+
+func TestLock_acquireDeployLockHeld(t *testing.T) {
+
+	// Serve a lock already held by the session of another Levant run.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/v1/session/create"):
+			fmt.Fprint(w, `{"ID":"levant"}`)
+		case strings.HasPrefix(r.URL.Path, "/v1/kv/") && r.Method == http.MethodGet:
+			w.Header().Set("X-Consul-Index", "1")
+			fmt.Fprintf(w, `[{"Key":"levant/deploy-lock/example","Flags":%d,"Session":"other"}]`,
+				consul.LockFlagValue)
+		default:
+			fmt.Fprint(w, "true")
+		}
+	}))
+	defer srv.Close()
+
+	c, err := consul.NewClient(&consul.Config{Address: strings.TrimPrefix(srv.URL, "http://")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = acquireDeployLock(c, DefaultDeployLockPrefix, "example", 50*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "another deployment of job example is in progress") {
+		t.Fatalf("expected lock held error, got %v", err)
+	}
+}
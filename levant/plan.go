@@ -1,23 +1,127 @@
 package levant
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
 
 	nomad "github.com/hashicorp/nomad/api"
 	"github.com/jrasell/levant/client"
 	"github.com/jrasell/levant/levant/structs"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
 const (
-	diffTypeAdded  = "Added"
-	diffTypeEdited = "Edited"
-	diffTypeNone   = "None"
+	diffTypeAdded   = "Added"
+	diffTypeDeleted = "Deleted"
+	diffTypeEdited  = "Edited"
+	diffTypeNone    = "None"
+
+	// annotationForcesDestructiveUpdate is the Nomad plan annotation used to
+	// mark changes which require allocations to be destroyed and recreated.
+	annotationForcesDestructiveUpdate = "forces create/destroy update"
+
+	// annotationForcesInPlaceUpdate is the Nomad plan annotation used to mark
+	// changes which update the allocations in place.
+	annotationForcesInPlaceUpdate = "forces in-place update"
+
+	// planUpdateInPlace and planUpdateDestructive describe the impact of a
+	// field change on the allocations of the job, as derived from the plan
+	// annotations.
+	planUpdateInPlace     = "in-place"
+	planUpdateDestructive = "forces destroy"
 )
 
+// DefaultMaxPlanDepth is the maximum depth of nested objects walked within the
+// plan diff when not configured.
+const DefaultMaxPlanDepth = 32
+
+// planUnavailableCodes are the HTTP status codes returned when the plan
+// endpoint is restricted or not supported.
+var planUnavailableCodes = []int{
+	http.StatusForbidden,
+	http.StatusNotFound,
+	http.StatusMethodNotAllowed,
+	http.StatusNotImplemented,
+}
+
 type levantPlan struct {
-	nomad  *nomad.Client
+	jobs   jobsAPI
 	config *PlanConfig
+
+	// destructive tracks the changes identified during the plan diff which
+	// will force allocations to be destroyed and recreated.
+	destructive []string
+
+	// changes holds each field change identified during the plan diff in the
+	// order they were found. When diff context is enabled this also includes
+	// the unchanged fields of edited objects, which have the None type.
+	changes []*planChange
+
+	// additions holds the task groups and tasks added by the plan when only
+	// the additions are summarised.
+	additions []string
+
+	// ignored is the number of field changes skipped as they match one of
+	// the configured ignore fields.
+	ignored int
+
+	// summary counts the groups changed during the plan diff.
+	summary planSummary
+
+	// warnings holds any warnings returned by Nomad when planning the job.
+	warnings string
+
+	// diff is the job diff returned by the Nomad plan.
+	diff *nomad.JobDiff
+
+	// depthExceeded is set once the plan diff has exceeded the maximum depth
+	// so that the warning is only logged once.
+	depthExceeded bool
+
+	// jobLogger is the logger of the plan, carrying the job ID as a context
+	// field.
+	jobLogger *zerolog.Logger
+}
+
+// jobsAPI is the subset of the Nomad jobs API used when planning a job. It is
+// satisfied by *nomad.Jobs and allows tests to provide canned responses.
+type jobsAPI interface {
+	Info(jobID string, q *nomad.QueryOptions) (*nomad.Job, *nomad.QueryMeta, error)
+	Plan(job *nomad.Job, diff bool, q *nomad.WriteOptions) (*nomad.JobPlanResponse, *nomad.WriteMeta, error)
+	Register(job *nomad.Job, q *nomad.WriteOptions) (*nomad.JobRegisterResponse, *nomad.WriteMeta, error)
+	Validate(job *nomad.Job, q *nomad.WriteOptions) (*nomad.JobValidateResponse, *nomad.WriteMeta, error)
+	Versions(jobID string, diffs bool, q *nomad.QueryOptions) ([]*nomad.Job, []*nomad.JobDiff, *nomad.QueryMeta, error)
+}
+
+// planChange describes a single field change identified within a job diff.
+type planChange struct {
+	Group  string
+	Task   string
+	Type   string
+	Object string
+	Field  string
+	Old    string
+	New    string
+
+	// Update is the impact of the change on the allocations, either
+	// planUpdateInPlace or planUpdateDestructive, or empty when Nomad has not
+	// annotated the change.
+	Update string
+}
+
+// planSummary counts the task groups added, edited and deleted within the plan
+// diff. The field and destructive change counts are taken from the recorded
+// changes when the summary is logged.
+type planSummary struct {
+	groupsAdded   int
+	groupsEdited  int
+	groupsDeleted int
 }
 
 // PlanConfig is the set of config structs required to run a Levant plan.
@@ -25,45 +129,101 @@ type PlanConfig struct {
 	Client   *structs.ClientConfig
 	Plan     *structs.PlanConfig
 	Template *structs.TemplateConfig
+
+	// ChangeCount is populated with the number of field changes identified
+	// by the plan so callers can reference it once the plan finishes.
+	ChangeCount int
+
+	// Logger, when set, is used for the logs of the plan in place of the
+	// global logger.
+	Logger *zerolog.Logger
 }
 
 func newPlan(config *PlanConfig) (*levantPlan, error) {
 
-	var err error
-
 	plan := &levantPlan{}
 	plan.config = config
 
-	plan.nomad, err = client.NewNomadClient(config.Client.Addr)
+	nomadClient, err := client.NewNomadTargetClient(config.Client)
 	if err != nil {
 		return nil, err
 	}
+	plan.jobs = nomadClient.Jobs()
+
+	if config.Template != nil && config.Template.Job != nil && config.Template.Job.ID != nil {
+		logger := config.logger().With().Str(structs.JobIDContextField, *config.Template.Job.ID).Logger()
+		plan.jobLogger = &logger
+	}
+
 	return plan, nil
 }
 
-// TriggerPlan initiates a Levant plan run.
-func TriggerPlan(config *PlanConfig) (bool, bool) {
+// logger returns the logger configured for the plan, falling back to the
+// global logger.
+func (c *PlanConfig) logger() *zerolog.Logger {
+	if c.Logger != nil {
+		return c.Logger
+	}
+	return &log.Logger
+}
+
+// logger returns the logger of the plan, falling back to the global logger
+// for plans not created by newPlan.
+func (lp *levantPlan) logger() *zerolog.Logger {
+	if lp.jobLogger != nil {
+		return lp.jobLogger
+	}
+	return &log.Logger
+}
+
+// TriggerPlan initiates a Levant plan run. A nil error indicates the plan
+// identified changes to the job; ErrPlanNoChanges is returned when there are
+// none and errors wrapping ErrPlanFailed when the plan could not be completed.
+// When summarising additions, errors wrapping ErrPlanAdditions are returned if
+// the plan adds any task groups or tasks.
+func TriggerPlan(config *PlanConfig) error {
 
 	lp, err := newPlan(config)
 	if err != nil {
-		log.Error().Err(err).Msg("levant/plan: unable to setup Levant plan")
-		return false, false
+		config.logger().Error().Err(err).Msg("levant/plan: unable to setup Levant plan")
+		return fmt.Errorf("%w: %v", ErrPlanFailed, err)
+	}
+
+	if lp.config.Plan.ShowJob {
+		logRenderedJob(lp.logger(), lp.config.Template.Job, lp.config.Plan.Redact)
 	}
 
 	changes, err := lp.plan()
+	config.ChangeCount = lp.changeCount()
 	if err != nil {
-		log.Error().Err(err).Msg("levant/plan: error when running plan")
-		return false, changes
+		lp.logger().Error().Err(err).Msg("levant/plan: error when running plan")
+		return fmt.Errorf("%w: %v", ErrPlanFailed, err)
+	}
+
+	if lp.config.Plan.Since > 0 {
+		if err := lp.sinceDiff(); err != nil {
+			lp.logger().Error().Err(err).Msg("levant/plan: unable to diff job against previous version")
+			return fmt.Errorf("%w: %v", ErrPlanFailed, err)
+		}
 	}
 
-	if !changes && lp.config.Plan.IgnoreNoChanges {
-		log.Info().Msg("levant/plan: no changes found in job but ignore-changes flag set to true")
-	} else if !changes && !lp.config.Plan.IgnoreNoChanges {
-		log.Info().Msg("levant/plan: no changes found in job")
-		return false, changes
+	if changes && len(lp.additions) > 0 {
+		return fmt.Errorf("%w: %s", ErrPlanAdditions, strings.Join(lp.additions, ", "))
 	}
 
-	return true, changes
+	if changes {
+		return nil
+	}
+
+	if lp.config.Plan.IgnoreNoChanges {
+		lp.logger().Info().Msg("levant/plan: no changes found in job but ignore-changes flag set to true")
+	} else if lp.config.Plan.AcceptNoDiff {
+		lp.logger().Info().Msg("levant/plan: no changes found in job but accept-no-diff flag set to true")
+	} else {
+		lp.logger().Info().Msg("levant/plan: no changes found in job")
+	}
+
+	return ErrPlanNoChanges
 }
 
 // plan is the entry point into running the Levant plan function which logs all
@@ -71,96 +231,736 @@ func TriggerPlan(config *PlanConfig) (bool, bool) {
 // no planned changes here, return false to indicate we should stop the process.
 func (lp *levantPlan) plan() (bool, error) {
 
-	log.Debug().Msg("levant/plan: triggering Nomad plan")
+	if lp.config.Plan.CountFromRunning {
+		if err := lp.copyRunningCounts(); err != nil {
+			return false, err
+		}
+	}
+
+	if lp.config.Plan.SpecDiffFile != "" {
+		if err := lp.writeSpecDiff(); err != nil {
+			return false, err
+		}
+	}
+
+	lp.logger().Debug().Msg("levant/plan: triggering Nomad plan")
 
 	// Run a plan using the rendered job.
-	resp, _, err := lp.nomad.Jobs().Plan(lp.config.Template.Job, true, nil)
+	resp, _, err := lp.jobs.Plan(lp.config.Template.Job, true, nil)
+	if err != nil && lp.config.Plan.Optional && planUnavailable(err) {
+		lp.logger().Warn().Err(err).Msg("levant/plan: the Nomad plan endpoint is unavailable, skipping the plan as plan-optional is set")
+		return true, nil
+	}
 	if err != nil {
-		log.Error().Err(err).Msg("levant/plan: unable to run a job plan")
+		lp.logger().Error().Err(err).Msg("levant/plan: unable to run a job plan")
 		return false, err
 	}
+	lp.warnings = resp.Warnings
+	lp.diff = resp.Diff
+
+	// When only the additions are summarised the field changes are not
+	// walked, so a job with changes is reported by what it adds.
+	if lp.summaryAdditions() && resp.Diff.Type != diffTypeNone {
+		lp.planAdditions(resp.Diff)
+		return true, nil
+	}
 
 	switch resp.Diff.Type {
 
 	// If the job is new, then don't print the entire diff but just log that it
 	// is a new registration.
 	case diffTypeAdded:
-		log.Info().Msg("levant/plan: job is a new addition to the cluster")
-		return true, nil
+		lp.logger().Info().Msg("levant/plan: job is a new addition to the cluster")
+		lp.logNonDeploymentPlan(resp)
+		return true, lp.checkPlacement(resp)
 
 		// If there are no changes, log the message so the user can see this and
 		// exit the deployment.
 	case diffTypeNone:
-		log.Info().Msg("levant/plan: no changes detected for job")
+		lp.logger().Info().Msg("levant/plan: no changes detected for job")
+
+		// The scheduler diff does not include every field of the job, so if
+		// the operator has asked, compare the job specifications directly and
+		// continue with the registration if they differ.
+		if lp.config.Plan.AcceptNoDiff {
+			return lp.jobSpecChanged()
+		}
 		return false, nil
 
 		// If there are changes, run the planDiff function which is responsible for
 		// iterating through the plan and logging all the planned changes.
 	case diffTypeEdited:
-		planDiff(resp.Diff)
+		lp.planDiff(resp.Diff)
+
+		// If every change found was to an ignored field, then the job is
+		// effectively unchanged.
+		if lp.onlyIgnoredChanges(resp.Diff) {
+			lp.logger().Info().Msgf("levant/plan: all %d change(s) detected are to ignored fields", lp.ignored)
+			return false, nil
+		}
+		lp.logNonDeploymentPlan(resp)
+
+		if lp.config.Plan.FailOnDestructive && len(lp.destructive) > 0 {
+			return true, fmt.Errorf("plan contains destructive changes which are not allowed: %s",
+				strings.Join(lp.destructive, ", "))
+		}
+		return true, lp.checkPlacement(resp)
 	}
 
 	return true, nil
 }
 
-func planDiff(plan *nomad.JobDiff) {
+// planUnavailable identifies errors returned when the plan endpoint has been
+// restricted, either by ACLs or a proxy in front of Nomad, or is not
+// supported, rather than when the plan itself has failed.
+func planUnavailable(err error) bool {
+	for _, code := range planUnavailableCodes {
+		if strings.Contains(err.Error(), fmt.Sprintf("Unexpected response code: %d", code)) {
+			return true
+		}
+	}
+	return strings.Contains(strings.ToLower(err.Error()), "permission denied")
+}
+
+// checkPlacement logs the reasons Nomad gave for each task group the plan
+// indicates can not be placed. If the operator has asked, an error is
+// returned so the job is not registered.
+func (lp *levantPlan) checkPlacement(resp *nomad.JobPlanResponse) error {
+
+	if len(resp.FailedTGAllocs) == 0 {
+		return nil
+	}
+
+	var groups []string
+	for g := range resp.FailedTGAllocs {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+
+	for _, g := range groups {
+		m := resp.FailedTGAllocs[g]
+		if m == nil {
+			continue
+		}
+		lp.logger().Warn().Msgf("levant/plan: group %s plan indicates %d allocation(s) can not be placed: %s",
+			g, m.CoalescedFailures+1, strings.Join(placementReasons(m), ", "))
+	}
+
+	if lp.config.Plan.FailOnPlacementFailure {
+		return fmt.Errorf("plan indicates allocations of group(s) %s can not be placed", strings.Join(groups, ", "))
+	}
+	return nil
+}
+
+// placementReasons describes why the scheduler was unable to place the
+// allocations of a task group, in a stable order.
+func placementReasons(m *nomad.AllocationMetric) []string {
+
+	var reasons []string
+
+	if m.NodesEvaluated == 0 {
+		reasons = append(reasons, "no nodes were available")
+	}
+	for _, c := range sortedKeys(m.ConstraintFiltered) {
+		reasons = append(reasons, fmt.Sprintf("constraint %q filtered %d node(s)", c, m.ConstraintFiltered[c]))
+	}
+	for _, c := range sortedKeys(m.ClassFiltered) {
+		reasons = append(reasons, fmt.Sprintf("class %q filtered %d node(s)", c, m.ClassFiltered[c]))
+	}
+	if m.NodesExhausted > 0 {
+		reasons = append(reasons, fmt.Sprintf("resources exhausted on %d node(s)", m.NodesExhausted))
+	}
+	for _, d := range sortedKeys(m.DimensionExhausted) {
+		reasons = append(reasons, fmt.Sprintf("dimension %q exhausted on %d node(s)", d, m.DimensionExhausted[d]))
+	}
+	for _, q := range m.QuotaExhausted {
+		reasons = append(reasons, fmt.Sprintf("quota %q exhausted", q))
+	}
+
+	if len(reasons) == 0 {
+		reasons = append(reasons, "no reason given by Nomad")
+	}
+	return reasons
+}
+
+// sortedKeys returns the keys of the map in sorted order.
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// copyRunningCounts sets the count of each task group of the rendered job to
+// that of the matching group of the running job. The template counts are
+// used when the job is not running. System jobs place one allocation per
+// node so their counts are not copied.
+func (lp *levantPlan) copyRunningCounts() error {
+
+	job := lp.config.Template.Job
+	if job.Type != nil && *job.Type == nomad.JobTypeSystem {
+		return nil
+	}
+
+	rJob, _, err := lp.jobs.Info(*job.ID, nil)
+	if err != nil && strings.Contains(err.Error(), "404") {
+		lp.logger().Info().Msg("levant/plan: job is not running, using template file group counts")
+		return nil
+	} else if err != nil {
+		lp.logger().Error().Err(err).Msg("levant/plan: unable to query running job for group counts")
+		return err
+	}
+
+	if rJob == nil || rJob.Status == nil || *rJob.Status != jobStatusRunning {
+		lp.logger().Info().Msg("levant/plan: job is not running, using template file group counts")
+		return nil
+	}
+
+	for _, rGroup := range rJob.TaskGroups {
+		for _, group := range job.TaskGroups {
+			if *rGroup.Name == *group.Name && rGroup.Count != nil {
+				lp.logger().Info().Msgf("levant/plan: using count %v of the running job for group %s",
+					*rGroup.Count, *group.Name)
+				count := *rGroup.Count
+				group.Count = &count
+			}
+		}
+	}
+	return nil
+}
+
+// logNonDeploymentPlan logs the expected allocation changes of each group for
+// job types which do not use Nomad deployments. These jobs are tracked using
+// the job status checker once registered rather than the deployment watcher.
+func (lp *levantPlan) logNonDeploymentPlan(resp *nomad.JobPlanResponse) {
+
+	job := lp.config.Template.Job
+	if job.Type == nil || *job.Type == nomad.JobTypeService {
+		return
+	}
+
+	lp.logger().Info().Msgf("levant/plan: %s jobs do not use deployments; the job status will be checked after registration",
+		*job.Type)
+
+	if job.IsPeriodic() || job.IsParameterized() {
+		lp.logger().Info().Msg("levant/plan: job is periodic or parameterized so registration will not place allocations")
+		return
+	}
+
+	if resp.Annotations == nil {
+		return
+	}
+
+	var groups []string
+	for g := range resp.Annotations.DesiredTGUpdates {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+
+	for _, g := range groups {
+		u := resp.Annotations.DesiredTGUpdates[g]
+		if u == nil {
+			continue
+		}
+		lp.logger().Info().Msgf("levant/plan: group %s plan indicates %d allocation(s) to place, %d to update in-place, %d to update destructively and %d to stop",
+			g, u.Place, u.InPlaceUpdate, u.DestructiveUpdate, u.Stop)
+	}
+}
+
+// jobSpecChanged compares the rendered job against the job currently registered
+// with Nomad, ignoring server populated fields, to identify changes which are
+// not reflected within the scheduler plan diff.
+func (lp *levantPlan) jobSpecChanged() (bool, error) {
+
+	rJob, _, err := lp.jobs.Info(*lp.config.Template.Job.ID, nil)
+	if err != nil {
+		lp.logger().Error().Err(err).Msg("levant/plan: unable to query running job for spec comparison")
+		return false, err
+	}
+
+	changed, err := lp.jobSpecDiffers(lp.config.Template.Job, rJob)
+	if err != nil {
+		return false, err
+	}
+
+	if changed {
+		lp.logger().Info().Msg("levant/plan: job specification differs from the running job; registration will continue")
+	} else {
+		lp.logger().Info().Msg("levant/plan: job specification matches the running job")
+	}
+	return changed, nil
+}
+
+// jobSpecDiffers compares the hashes of the canonical JSON representations
+// of both jobs, which exclude the fields populated by the Nomad servers.
+func (lp *levantPlan) jobSpecDiffers(rendered, running *nomad.Job) (bool, error) {
+
+	a, err := jobSpecHash(rendered)
+	if err != nil {
+		return false, err
+	}
+
+	b, err := jobSpecHash(running)
+	if err != nil {
+		return false, err
+	}
+
+	lp.logger().Debug().Msgf("levant/plan: rendered job specification hash %s, running job specification hash %s", a, b)
+	return a != b, nil
+}
+
+// planDiff collects the changes within the job diff and logs each of them,
+// either as a line per change or as a tree when configured, followed by a
+// summary of the counts of changes.
+func (lp *levantPlan) planDiff(plan *nomad.JobDiff) {
+	lp.collectDiff(plan)
+	lp.logChanges(lp.changes)
+	lp.logSummary()
+}
+
+// logChanges logs each change either as a line per change, or as a tree or
+// grouped by impact when configured. The values of redacted fields are replaced.
+func (lp *levantPlan) logChanges(changes []*planChange) {
+	changes = lp.redactChanges(changes)
+
+	var format string
+	if lp.config != nil && lp.config.Plan != nil {
+		format = strings.ToLower(lp.config.Plan.Format)
+	}
+
+	switch {
+	case len(changes) > 0 && format == structs.PlanFormatTree:
+		lp.logger().Info().Msgf("levant/plan: plan indicates the following changes:\n%s", planTree(changes))
+		return
+	case format == structs.PlanFormatGrouped:
+		if out := planGrouped(changes, lp.diff); out != "" {
+			lp.logger().Info().Msgf("levant/plan: plan indicates the following changes:\n%s", out)
+		}
+		return
+	}
+
+	for _, c := range changes {
+		logDiffObj(lp.logger(), c.Group, c.Task, c.Type, c.Object, c.Field, c.Old, c.New, c.Update)
+	}
+}
+
+// logSummary logs the counts of the changes identified by the plan diff as a
+// single entry with numeric fields, so the plan can be tracked and alerted on
+// by log pipelines.
+func (lp *levantPlan) logSummary() {
+	fields := lp.changeCount()
+	destructive := len(lp.destructive)
+
+	lp.logger().Info().
+		Int("groups_added", lp.summary.groupsAdded).
+		Int("groups_edited", lp.summary.groupsEdited).
+		Int("groups_deleted", lp.summary.groupsDeleted).
+		Int("fields_changed", fields).
+		Int("destructive_changes", destructive).
+		Msgf("levant/plan: plan summary: %d group(s) added, %d edited and %d deleted; %d field(s) changed, %d destructive",
+			lp.summary.groupsAdded, lp.summary.groupsEdited, lp.summary.groupsDeleted, fields, destructive)
+}
+
+// planTree renders the changes as an indented tree mirroring the group, task,
+// object and field hierarchy of the job. The changes are expected in the
+// order they are collected, so those sharing a parent are adjacent.
+func planTree(changes []*planChange) string {
+
+	var b strings.Builder
+	var prev *planChange
+
+	for _, c := range changes {
+		depth := 0
+
+		if c.Group != "" {
+			if prev == nil || prev.Group != c.Group {
+				fmt.Fprintf(&b, "group %q\n", c.Group)
+				prev = nil
+			}
+			depth++
+		}
+
+		if c.Task != "" {
+			if prev == nil || prev.Task != c.Task {
+				fmt.Fprintf(&b, "%stask %q\n", treeIndent(depth), c.Task)
+				prev = nil
+			}
+			depth++
+		}
+
+		if prev == nil || prev.Task != c.Task || prev.Object != c.Object {
+			fmt.Fprintf(&b, "%s%s\n", treeIndent(depth), c.Object)
+		}
+		depth++
+
+		switch c.Type {
+		case diffTypeAdded:
+			fmt.Fprintf(&b, "%s+ %s: %q", treeIndent(depth), c.Field, c.New)
+		case diffTypeDeleted:
+			fmt.Fprintf(&b, "%s- %s: %q", treeIndent(depth), c.Field, c.Old)
+		case diffTypeNone:
+			fmt.Fprintf(&b, "%s  %s: %q", treeIndent(depth), c.Field, c.Old)
+		default:
+			fmt.Fprintf(&b, "%s~ %s: %q => %q", treeIndent(depth), c.Field, c.Old, c.New)
+		}
+		if c.Update != "" {
+			fmt.Fprintf(&b, " [%s]", c.Update)
+		}
+		b.WriteString("\n")
+
+		prev = c
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// treeIndent returns the indentation for the depth within the plan tree.
+func treeIndent(depth int) string {
+	return strings.Repeat("  ", depth)
+}
+
+// collectDiff walks the job diff recording each changed field along with any
+// changes which will force allocations to be destroyed and recreated. Groups,
+// tasks, objects and fields are walked in name order so the plan output is
+// the same between runs regardless of the order returned by Nomad.
+func (lp *levantPlan) collectDiff(plan *nomad.JobDiff) {
+
+	// Collect any changes to the job level fields and objects.
+	for _, f := range sortFieldDiffs(plan.Fields) {
+		if f.Type == diffTypeNone {
+			continue
+		}
+		lp.addChange("", "", "", "Job", f)
+	}
+	for _, o := range sortObjectDiffs(plan.Objects) {
+		lp.recurseObjDiff("", "", "", o, 1)
+	}
 
 	// Iterate through each TaskGroup.
-	for _, tg := range plan.TaskGroups {
+	for _, tg := range sortTaskGroupDiffs(plan.TaskGroups) {
+		switch tg.Type {
+		case diffTypeAdded:
+			lp.summary.groupsAdded++
+		case diffTypeDeleted:
+			lp.summary.groupsDeleted++
+		}
 		if tg.Type != diffTypeEdited {
 			continue
 		}
-		for _, tgo := range tg.Objects {
-			recurseObjDiff(tg.Name, "", tgo)
+
+		// The group is only counted as edited if it contains changes which
+		// are not to ignored fields.
+		changes, destructive := lp.changeCount(), len(lp.destructive)
+
+		// Group level fields, such as the count, are not part of the group
+		// objects and so are collected separately.
+		for _, f := range sortFieldDiffs(tg.Fields) {
+			if f.Type == diffTypeNone {
+				continue
+			}
+			lp.addChange(tg.Name, "", "", "TaskGroup", f)
+		}
+		for _, tgo := range sortObjectDiffs(tg.Objects) {
+			lp.recurseObjDiff(tg.Name, "", "", tgo, 1)
 		}
 
 		// Iterate through each Task.
-		for _, t := range tg.Tasks {
+		for _, t := range sortTaskDiffs(tg.Tasks) {
 			if t.Type != diffTypeEdited {
 				continue
 			}
-			if len(t.Objects) == 0 {
-				return
+
+			// Nomad annotates the task, rather than the individual fields, with
+			// whether the changes update the allocations in place or require
+			// them to be destroyed and recreated.
+			update := annotationUpdate("", t.Annotations)
+			found := len(lp.destructive)
+			ignored := lp.ignored
+
+			// Task level fields, such as the driver, are not part of the task
+			// objects and so are collected separately.
+			for _, f := range sortFieldDiffs(t.Fields) {
+				if f.Type == diffTypeNone {
+					continue
+				}
+				lp.addChange(tg.Name, t.Name, update, "Task", f)
 			}
-			for _, o := range t.Objects {
-				recurseObjDiff(tg.Name, t.Name, o)
+			for _, o := range sortObjectDiffs(t.Objects) {
+				lp.recurseObjDiff(tg.Name, t.Name, update, o, 1)
 			}
+
+			// If none of the task objects identified the changed fields, still
+			// record the task so that the destructive change is not lost. This
+			// is not done when the only changes were to ignored fields.
+			if update == planUpdateDestructive && len(lp.destructive) == found && lp.ignored == ignored {
+				lp.destructive = append(lp.destructive, fmt.Sprintf("group %s task %s", tg.Name, t.Name))
+			}
+		}
+
+		if lp.changeCount() > changes || len(lp.destructive) > destructive {
+			lp.summary.groupsEdited++
 		}
 	}
 }
 
-func recurseObjDiff(g, t string, objDiff *nomad.ObjectDiff) {
+// recurseObjDiff walks the object diff, at the given depth within the group or
+// task, recording the changed fields along with the update type of the parent
+// task. Objects nested deeper than the maximum
+// plan depth are not walked so a malformed diff cannot exhaust the stack.
+func (lp *levantPlan) recurseObjDiff(g, t, update string, objDiff *nomad.ObjectDiff, depth int) {
 
-	// If we have reached the end of the object tree, and have an edited type
-	// with field information then we can interate on the fields to find those
-	// which have changed.
-	if len(objDiff.Objects) == 0 && len(objDiff.Fields) > 0 && objDiff.Type == diffTypeEdited {
-		for _, f := range objDiff.Fields {
-			if f.Type != diffTypeEdited {
+	if max := lp.maxPlanDepth(); depth > max {
+		if !lp.depthExceeded {
+			lp.logger().Warn().Msgf("levant/plan: plan diff of object %s exceeds the maximum depth of %d; nested changes are not shown",
+				objDiff.Name, max)
+			lp.depthExceeded = true
+		}
+		return
+	}
+
+	// If the object has been newly added or deleted, all of its fields and
+	// nested objects are additions or deletions and should be logged as such.
+	if objDiff.Type == diffTypeAdded || objDiff.Type == diffTypeDeleted {
+		for _, f := range sortFieldDiffs(objDiff.Fields) {
+			if f.Type != objDiff.Type {
 				continue
 			}
-			logDiffObj(g, t, objDiff.Name, f.Name, f.Old, f.New)
-			continue
+			lp.addChange(g, t, update, objDiff.Name, f)
+		}
+		for _, o := range sortObjectDiffs(objDiff.Objects) {
+			lp.recurseObjDiff(g, t, update, o, depth+1)
 		}
+		return
+	}
 
-	} else {
-		// Continue to interate through the object diff objects until such time
-		// the above is triggered.
-		for _, o := range objDiff.Objects {
-			recurseObjDiff(g, t, o)
+	// Record the fields of an edited object which have been edited, added
+	// or deleted. The unchanged fields are only included, when diff context
+	// is enabled, for objects at the end of the object tree.
+	if objDiff.Type == diffTypeEdited {
+		for _, f := range sortFieldDiffs(objDiff.Fields) {
+			switch {
+			case f.Type != diffTypeNone:
+				lp.addChange(g, t, update, objDiff.Name, f)
+			case len(objDiff.Objects) == 0 && lp.diffContext():
+				lp.addContext(g, t, objDiff.Name, f)
+			}
+		}
+	}
+
+	// Continue to interate through the nested objects, which may hold
+	// further changes.
+	for _, o := range sortObjectDiffs(objDiff.Objects) {
+		lp.recurseObjDiff(g, t, update, o, depth+1)
+	}
+}
+
+// maxPlanDepth returns the configured maximum plan diff depth or the default
+// if none has been set.
+func (lp *levantPlan) maxPlanDepth() int {
+	if lp.config != nil && lp.config.Plan != nil && lp.config.Plan.MaxPlanDepth > 0 {
+		return lp.config.Plan.MaxPlanDepth
+	}
+	return DefaultMaxPlanDepth
+}
+
+// sortFieldDiffs returns a copy of the field diffs sorted by name.
+func sortFieldDiffs(in []*nomad.FieldDiff) []*nomad.FieldDiff {
+	out := append([]*nomad.FieldDiff(nil), in...)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// sortObjectDiffs returns a copy of the object diffs sorted by name.
+func sortObjectDiffs(in []*nomad.ObjectDiff) []*nomad.ObjectDiff {
+	out := append([]*nomad.ObjectDiff(nil), in...)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// sortTaskGroupDiffs returns a copy of the task group diffs sorted by name.
+func sortTaskGroupDiffs(in []*nomad.TaskGroupDiff) []*nomad.TaskGroupDiff {
+	out := append([]*nomad.TaskGroupDiff(nil), in...)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// sortTaskDiffs returns a copy of the task diffs sorted by name.
+func sortTaskDiffs(in []*nomad.TaskDiff) []*nomad.TaskDiff {
+	out := append([]*nomad.TaskDiff(nil), in...)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// addChange records the field change and tracks whether it is destructive.
+// The update type of the change is taken from the field annotations, falling
+// back to that of the parent task. Changes to ignored fields are skipped.
+func (lp *levantPlan) addChange(g, t, update string, objName string, f *nomad.FieldDiff) {
+	if lp.ignoreField(objName, f.Name) {
+		lp.logger().Debug().Msgf("levant/plan: ignoring change of %s:%s", objName, f.Name)
+		lp.ignored++
+		return
+	}
+
+	update = annotationUpdate(update, f.Annotations)
+
+	lp.trackDestructive(g, t, update, objName, f)
+	lp.changes = append(lp.changes, &planChange{
+		Group:  g,
+		Task:   t,
+		Type:   f.Type,
+		Object: objName,
+		Field:  f.Name,
+		Old:    f.Old,
+		New:    f.New,
+		Update: update,
+	})
+}
+
+// addContext records the unchanged field of an edited object so it can be
+// shown alongside the changes. Context fields are not counted as changes.
+func (lp *levantPlan) addContext(g, t, objName string, f *nomad.FieldDiff) {
+	if lp.ignoreField(objName, f.Name) {
+		return
+	}
+	lp.changes = append(lp.changes, &planChange{
+		Group:  g,
+		Task:   t,
+		Type:   diffTypeNone,
+		Object: objName,
+		Field:  f.Name,
+		Old:    f.Old,
+		New:    f.New,
+	})
+}
+
+// changeCount returns the number of changes identified by the plan diff,
+// excluding any unchanged context fields.
+func (lp *levantPlan) changeCount() int {
+	var n int
+	for _, c := range lp.changes {
+		if c.Type != diffTypeNone {
+			n++
+		}
+	}
+	return n
+}
+
+// onlyIgnoredChanges checks whether every change within the diff was to an
+// ignored field, in which case the job is effectively unchanged. Task groups
+// and tasks added or deleted by the diff are always changes.
+func (lp *levantPlan) onlyIgnoredChanges(diff *nomad.JobDiff) bool {
+	if lp.ignored == 0 || lp.changeCount() > 0 {
+		return false
+	}
+	return len(jobAdditions(diff)) == 0 && len(jobDeletions(diff)) == 0
+}
+
+// diffContext checks whether the unchanged fields of edited objects should be
+// included in the plan output.
+func (lp *levantPlan) diffContext() bool {
+	return lp.config != nil && lp.config.Plan != nil && lp.config.Plan.DiffContext
+}
+
+// ignoreField checks whether the field has been configured to be ignored
+// within the plan.
+func (lp *levantPlan) ignoreField(objName, fName string) bool {
+	if lp.config == nil || lp.config.Plan == nil {
+		return false
+	}
+
+	if lp.config.Plan.IgnoreCountChanges && objName == "TaskGroup" && fName == "Count" {
+		return true
+	}
+
+	name := objName + ":" + fName
+	for _, f := range lp.config.Plan.IgnoreFields {
+		if f == name {
+			return true
 		}
 	}
+	return false
+}
+
+// path returns the location of the changed field within the job.
+func (c *planChange) path() string {
+	var p string
+	if c.Group != "" {
+		p = fmt.Sprintf("group %s ", c.Group)
+	}
+	if c.Task != "" {
+		p = p + fmt.Sprintf("task %s ", c.Task)
+	}
+	return p + fmt.Sprintf("%s:%s", c.Object, c.Field)
+}
+
+// trackDestructive records the field as a destructive change if either the
+// parent task or the field itself has been annotated as such by Nomad.
+func (lp *levantPlan) trackDestructive(g, t, update string, objName string, f *nomad.FieldDiff) {
+	if update != planUpdateDestructive {
+		return
+	}
+
+	d := fmt.Sprintf("group %s", g)
+	if t != "" {
+		d = d + fmt.Sprintf(" task %s", t)
+	}
+	lp.destructive = append(lp.destructive, fmt.Sprintf("%s %s:%s", d, objName, f.Name))
+}
+
+// hasDestructiveAnnotation checks whether the Nomad plan annotations indicate
+// the change will force allocations to be destroyed and recreated.
+func hasDestructiveAnnotation(annotations []string) bool {
+	return hasAnnotation(annotations, annotationForcesDestructiveUpdate)
+}
+
+// annotationUpdate returns the update type indicated by the Nomad plan
+// annotations, or the passed default if the annotations do not indicate one.
+func annotationUpdate(def string, annotations []string) string {
+	switch {
+	case hasDestructiveAnnotation(annotations):
+		return planUpdateDestructive
+	case hasAnnotation(annotations, annotationForcesInPlaceUpdate):
+		return planUpdateInPlace
+	default:
+		return def
+	}
+}
+
+// hasAnnotation checks whether the Nomad plan annotations include the passed
+// annotation.
+func hasAnnotation(annotations []string, annotation string) bool {
+	for _, a := range annotations {
+		if a == annotation {
+			return true
+		}
+	}
+	return false
 }
 
 // logDiffObj is a helper function so Levant can log the most accurate and
-// useful plan output messages.
-func logDiffObj(g, t, objName, fName, fOld, fNew string) {
+// useful plan output messages. Changes annotated with their update type are
+// suffixed with it and include it as a log field.
+func logDiffObj(logger *zerolog.Logger, g, t, dType, objName, fName, fOld, fNew, update string) {
 
-	var lStart, l string
+	var lStart, lEnd, l string
 
 	// We will always have at least this information to log.
-	lEnd := fmt.Sprintf("plan indicates change of %s:%s from %s to %s",
-		objName, fName, fOld, fNew)
+	switch dType {
+	case diffTypeAdded:
+		lEnd = fmt.Sprintf("plan indicates addition of %s:%s with value %s",
+			objName, fName, fNew)
+	case diffTypeDeleted:
+		lEnd = fmt.Sprintf("plan indicates removal of %s:%s with value %s",
+			objName, fName, fOld)
+	case diffTypeNone:
+		lEnd = fmt.Sprintf("plan indicates no change of %s:%s with value %s",
+			objName, fName, fOld)
+	default:
+		lEnd = fmt.Sprintf("plan indicates change of %s:%s from %s to %s",
+			objName, fName, fOld, fNew)
+	}
 
 	// If we have been passed a group name, use this to start the log line.
 	if g != "" {
@@ -179,5 +979,134 @@ func logDiffObj(g, t, objName, fName, fOld, fNew string) {
 		l = lEnd
 	}
 
-	log.Info().Msgf("levant/plan: %s", l)
+	e := logger.Info()
+	if update != "" {
+		l = l + fmt.Sprintf(" [%s]", update)
+		e = e.Str("update", update)
+	}
+	e.Msgf("levant/plan: %s", l)
+}
+
+// redactedValue replaces the values of redacted fields within the plan
+// output, the rendered job logged by -show-job and the spec diff.
+const redactedValue = "***"
+
+// alwaysRedacted are the patterns of job fields which are redacted from the
+// logged job regardless of the configured redact patterns.
+var alwaysRedacted = []string{"VaultToken"}
+
+// logRenderedJob logs the rendered job as JSON with the values of any fields
+// matching the redact patterns, along with the Vault token, replaced.
+func logRenderedJob(logger *zerolog.Logger, job *nomad.Job, redact []string) {
+
+	out, err := redactJob(job, redact)
+	if err != nil {
+		logger.Error().Err(err).Msg("levant/plan: unable to marshal rendered job")
+		return
+	}
+
+	logger.Info().Msgf("levant/plan: rendered job:\n%s", out)
+}
+
+// redactJob returns the indented JSON representation of the job with the
+// values of the fields matching the redact patterns replaced wherever they
+// occur. Patterns are matched as by redactMatch against both job fields and
+// map keys, such as the keys of a task's env or meta, with the name of the
+// enclosing field as the object name.
+func redactJob(job *nomad.Job, redact []string) ([]byte, error) {
+
+	raw, err := json.Marshal(job)
+	if err != nil {
+		return nil, err
+	}
+	return redactJSON(raw, redact)
+}
+
+// redactJSON returns the JSON representation of a job indented, with the
+// values of the fields matching the redact patterns replaced. Numbers are
+// kept as written so large integers do not lose precision.
+func redactJSON(raw []byte, redact []string) ([]byte, error) {
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+
+	var obj interface{}
+	if err := dec.Decode(&obj); err != nil {
+		return nil, err
+	}
+
+	redactFields(obj, "Job", append(append([]string{}, alwaysRedacted...), redact...))
+
+	return json.MarshalIndent(obj, "", "  ")
+}
+
+// redactFields walks the decoded JSON object replacing the values of any keys
+// matching the patterns. The object name is the key of the enclosing field.
+func redactFields(obj interface{}, objName string, patterns []string) {
+	switch o := obj.(type) {
+	case map[string]interface{}:
+		for k, v := range o {
+			if v != nil && redactMatch(patterns, objName, k) {
+				o[k] = redactedValue
+				continue
+			}
+			redactFields(v, k, patterns)
+		}
+	case []interface{}:
+		for _, v := range o {
+			redactFields(v, objName, patterns)
+		}
+	}
+}
+
+// redactChanges returns the changes with the old and new values of fields
+// matching the configured redact patterns replaced, so the change is still
+// shown without revealing the values. The recorded changes are not modified.
+func (lp *levantPlan) redactChanges(changes []*planChange) []*planChange {
+	if lp.config == nil || lp.config.Plan == nil || len(lp.config.Plan.Redact) == 0 {
+		return changes
+	}
+
+	out := make([]*planChange, len(changes))
+	for i, c := range changes {
+		if !redactMatch(lp.config.Plan.Redact, c.Object, c.Field) {
+			out[i] = c
+			continue
+		}
+
+		r := *c
+		if r.Old != "" {
+			r.Old = redactedValue
+		}
+		if r.New != "" {
+			r.New = redactedValue
+		}
+		out[i] = &r
+	}
+	return out
+}
+
+// redactMatch checks whether the field matches any of the glob patterns. The
+// patterns are matched case insensitively against the field name, the map key
+// of fields such as Meta[key], and the objName:fieldName form of the field. As
+// brackets form character classes within a glob, a pattern exactly matching
+// the name also matches.
+func redactMatch(patterns []string, objName, fName string) bool {
+
+	names := []string{fName, objName + ":" + fName}
+	if i := strings.Index(fName, "["); i >= 0 && strings.HasSuffix(fName, "]") {
+		names = append(names, fName[i+1:len(fName)-1])
+	}
+
+	for _, p := range patterns {
+		for _, n := range names {
+			if strings.EqualFold(p, n) {
+				return true
+			}
+			if ok, _ := path.Match(strings.ToLower(p), strings.ToLower(n)); ok {
+				return true
+			}
+		}
+	}
+	return false
 }
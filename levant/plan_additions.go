@@ -0,0 +1,59 @@
+package levant
+
+import (
+	"fmt"
+	"strings"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/jrasell/levant/levant/structs"
+)
+
+// summaryAdditions checks whether the plan should report only the task groups
+// and tasks added to the job.
+func (lp *levantPlan) summaryAdditions() bool {
+	return lp.config != nil && lp.config.Plan != nil &&
+		strings.ToLower(lp.config.Plan.Summary) == structs.PlanSummaryAdditions
+}
+
+// planAdditions records and logs the task groups and tasks added within the
+// job diff. Changes to the fields of existing groups and tasks are not
+// logged.
+func (lp *levantPlan) planAdditions(diff *nomad.JobDiff) {
+	lp.additions = jobAdditions(diff)
+
+	if len(lp.additions) == 0 {
+		lp.logger().Info().Msg("levant/plan: plan does not add any task groups or tasks")
+		return
+	}
+	for _, a := range lp.additions {
+		lp.logger().Info().Msgf("levant/plan: plan adds %s", a)
+	}
+}
+
+// jobAdditions returns the task groups added to the job, along with the tasks
+// added to existing task groups, in name order. The tasks of an added group
+// are not listed separately as they are new along with the group.
+func jobAdditions(diff *nomad.JobDiff) []string {
+	return jobGroupTaskDiffs(diff, diffTypeAdded)
+}
+
+// jobGroupTaskDiffs returns the task groups of the job diff with the diff
+// type, along with the tasks of the type within edited task groups, in name
+// order.
+func jobGroupTaskDiffs(diff *nomad.JobDiff, dType string) []string {
+	var out []string
+
+	for _, tg := range sortTaskGroupDiffs(diff.TaskGroups) {
+		switch tg.Type {
+		case dType:
+			out = append(out, fmt.Sprintf("group %s", tg.Name))
+		case diffTypeEdited:
+			for _, t := range sortTaskDiffs(tg.Tasks) {
+				if t.Type == dType {
+					out = append(out, fmt.Sprintf("group %s task %s", tg.Name, t.Name))
+				}
+			}
+		}
+	}
+	return out
+}
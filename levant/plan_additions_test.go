@@ -0,0 +1,74 @@
+package levant
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/helper"
+	"github.com/jrasell/levant/levant/structs"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func TestPlan_planAdditions(t *testing.T) {
+
+	log.Logger = zerolog.New(ioutil.Discard)
+
+	diff := &nomad.JobDiff{
+		Type: diffTypeEdited,
+		TaskGroups: []*nomad.TaskGroupDiff{
+			{
+				Type: diffTypeEdited,
+				Name: "cache",
+				Fields: []*nomad.FieldDiff{
+					{Type: diffTypeEdited, Name: "Count", Old: "1", New: "3"},
+				},
+				Tasks: []*nomad.TaskDiff{
+					{Type: diffTypeEdited, Name: "redis"},
+					{Type: diffTypeAdded, Name: "sidecar"},
+				},
+			},
+			{
+				Type:  diffTypeAdded,
+				Name:  "api",
+				Tasks: []*nomad.TaskDiff{{Type: diffTypeAdded, Name: "server"}},
+			},
+			{Type: diffTypeDeleted, Name: "legacy"},
+		},
+	}
+
+	lp := &levantPlan{
+		jobs: &fakeJobs{plan: &nomad.JobPlanResponse{Diff: diff}},
+		config: &PlanConfig{
+			Plan: &structs.PlanConfig{Summary: structs.PlanSummaryAdditions},
+			Template: &structs.TemplateConfig{
+				Job: &nomad.Job{ID: helper.StringToPtr("example"), Name: helper.StringToPtr("example")},
+			},
+		},
+	}
+
+	changes, err := lp.plan()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !changes {
+		t.Fatal("expected changes")
+	}
+
+	expected := []string{"group api", "group cache task sidecar"}
+	if !reflect.DeepEqual(lp.additions, expected) {
+		t.Fatalf("expected additions %v, got %v", expected, lp.additions)
+	}
+	if len(lp.changes) != 0 {
+		t.Fatalf("expected field changes to be ignored, got %d", len(lp.changes))
+	}
+
+	if a := jobAdditions(&nomad.JobDiff{
+		Type:       diffTypeEdited,
+		TaskGroups: []*nomad.TaskGroupDiff{{Type: diffTypeEdited, Name: "cache"}},
+	}); len(a) != 0 {
+		t.Fatalf("expected no additions, got %v", a)
+	}
+}
@@ -0,0 +1,103 @@
+package levant
+
+import (
+	"fmt"
+	"strings"
+
+	nomad "github.com/hashicorp/nomad/api"
+)
+
+// The sections of the grouped plan output.
+const (
+	planSectionDestructive = "Destructive changes"
+	planSectionInPlace     = "In-place changes"
+	planSectionAdditions   = "Additions"
+	planSectionDeletions   = "Deletions"
+	planSectionOther       = "Other changes"
+)
+
+// planSections orders the sections of the grouped plan output.
+var planSections = []string{
+	planSectionDestructive,
+	planSectionInPlace,
+	planSectionAdditions,
+	planSectionDeletions,
+	planSectionOther,
+}
+
+// planGrouped renders the changes bucketed into sections by their impact on
+// the allocations, with the most disruptive first, so reviewers can triage
+// the destructive changes. Each change includes its group, task and field.
+// The task groups and tasks added or deleted within the job diff, if any, are
+// listed alongside the field changes. Empty sections are not output.
+func planGrouped(changes []*planChange, diff *nomad.JobDiff) string {
+
+	sections := make(map[string][]string, len(planSections))
+
+	for _, c := range changes {
+		if c.Type == diffTypeNone {
+			continue
+		}
+
+		var line string
+		switch c.Type {
+		case diffTypeAdded:
+			line = fmt.Sprintf("+ %s: %q", c.path(), c.New)
+		case diffTypeDeleted:
+			line = fmt.Sprintf("- %s: %q", c.path(), c.Old)
+		default:
+			line = fmt.Sprintf("~ %s: %q => %q", c.path(), c.Old, c.New)
+		}
+		section := planChangeSection(c)
+		sections[section] = append(sections[section], line)
+	}
+
+	if diff != nil {
+		for _, a := range jobAdditions(diff) {
+			sections[planSectionAdditions] = append(sections[planSectionAdditions], "+ "+a)
+		}
+		for _, d := range jobDeletions(diff) {
+			sections[planSectionDeletions] = append(sections[planSectionDeletions], "- "+d)
+		}
+	}
+
+	var b strings.Builder
+	for _, s := range planSections {
+		if len(sections[s]) == 0 {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%s:\n", s)
+		for _, l := range sections[s] {
+			fmt.Fprintf(&b, "%s%s\n", treeIndent(1), l)
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// planChangeSection returns the section of the grouped plan output the change
+// belongs to. The impact of the change on the allocations takes precedence
+// over its type.
+func planChangeSection(c *planChange) string {
+	switch {
+	case c.Update == planUpdateDestructive:
+		return planSectionDestructive
+	case c.Update == planUpdateInPlace:
+		return planSectionInPlace
+	case c.Type == diffTypeAdded:
+		return planSectionAdditions
+	case c.Type == diffTypeDeleted:
+		return planSectionDeletions
+	default:
+		return planSectionOther
+	}
+}
+
+// jobDeletions returns the task groups deleted from the job, along with the
+// tasks deleted from existing task groups, in name order.
+func jobDeletions(diff *nomad.JobDiff) []string {
+	return jobGroupTaskDiffs(diff, diffTypeDeleted)
+}
@@ -0,0 +1,131 @@
+package levant
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	nomad "github.com/hashicorp/nomad/api"
+)
+
+// sinceDiff logs the changes between the rendered job and the most recent
+// version of the job submitted before the configured duration ago, so the
+// changes accumulated over a time range can be reviewed. The changes are
+// logged in the same way as the plan but do not affect its result.
+//
+// The Nomad plan only diffs against the current version of the job, so the
+// diffs between each version since are combined with the plan diff.
+func (lp *levantPlan) sinceDiff() error {
+
+	job := lp.config.Template.Job
+	cutoff := time.Now().Add(-lp.config.Plan.Since)
+
+	versions, diffs, _, err := lp.jobs.Versions(*job.ID, true, nil)
+	if err != nil && strings.Contains(err.Error(), "404") {
+		lp.logger().Info().Msg("levant/plan: job is not registered, no versions to diff against")
+		return nil
+	} else if err != nil {
+		lp.logger().Error().Err(err).Msg("levant/plan: unable to query job versions")
+		return err
+	}
+
+	idx := versionSince(versions, cutoff)
+	if idx < 0 {
+		return fmt.Errorf("no version of job %s was submitted before %s", *job.ID, cutoff.Format(time.RFC3339))
+	}
+
+	// Nomad returns the versions newest first with the diff at each index
+	// describing the change from the next, older, version. Walk them oldest
+	// first, ending with the changes of the plan.
+	var ordered []*nomad.JobDiff
+	for i := idx - 1; i >= 0; i-- {
+		if i < len(diffs) {
+			ordered = append(ordered, diffs[i])
+		}
+	}
+	if lp.diff != nil && lp.diff.Type == diffTypeEdited {
+		ordered = append(ordered, lp.diff)
+	}
+
+	changes := lp.combineDiffs(ordered)
+
+	v := versions[idx]
+	submitted := time.Unix(0, *v.SubmitTime).Format(time.RFC3339)
+
+	if len(changes) == 0 {
+		lp.logger().Info().Msgf("levant/plan: no changes since version %d of the job, submitted at %s", *v.Version, submitted)
+		return nil
+	}
+
+	lp.logger().Info().Msgf("levant/plan: %d field(s) changed since version %d of the job, submitted at %s",
+		len(changes), *v.Version, submitted)
+	lp.logChanges(changes)
+
+	return nil
+}
+
+// versionSince returns the index of the most recent job version submitted at
+// or before the cutoff, or -1 if every version was submitted after it.
+func versionSince(versions []*nomad.Job, cutoff time.Time) int {
+
+	idx := -1
+	for i, v := range versions {
+		if v.Version == nil || v.SubmitTime == nil || time.Unix(0, *v.SubmitTime).After(cutoff) {
+			continue
+		}
+		if idx < 0 || *v.Version > *versions[idx].Version {
+			idx = i
+		}
+	}
+	return idx
+}
+
+// combineDiffs collects the changes of each diff, oldest first, into the net
+// change of each field: the old value from the first change and the new value
+// from the last. Fields which have been changed back to their original value
+// are dropped.
+func (lp *levantPlan) combineDiffs(diffs []*nomad.JobDiff) []*planChange {
+
+	var out []*planChange
+	seen := make(map[string]*planChange)
+
+	for _, d := range diffs {
+
+		// Collect each diff using a separate plan so the changes are not
+		// counted as those of the scheduler plan.
+		dp := &levantPlan{config: lp.config}
+		dp.collectDiff(d)
+
+		for _, c := range dp.changes {
+			if c.Type == diffTypeNone {
+				continue
+			}
+
+			prev, ok := seen[c.path()]
+			if !ok {
+				cp := *c
+				seen[c.path()] = &cp
+				out = append(out, &cp)
+				continue
+			}
+
+			prev.New = c.New
+			prev.Update = c.Update
+			switch {
+			case prev.Type == diffTypeAdded && c.Type != diffTypeDeleted:
+			case c.Type == diffTypeDeleted && prev.Type != diffTypeAdded:
+				prev.Type = diffTypeDeleted
+			default:
+				prev.Type = diffTypeEdited
+			}
+		}
+	}
+
+	var changes []*planChange
+	for _, c := range out {
+		if c.Old != c.New {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
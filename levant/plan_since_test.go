@@ -0,0 +1,203 @@
+package levant
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/helper"
+	"github.com/jrasell/levant/levant/structs"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func imageDiff(old, new string) *nomad.JobDiff {
+	return &nomad.JobDiff{
+		Type: diffTypeEdited,
+		TaskGroups: []*nomad.TaskGroupDiff{
+			{
+				Type: diffTypeEdited,
+				Name: "cache",
+				Tasks: []*nomad.TaskDiff{
+					{
+						Type: diffTypeEdited,
+						Name: "redis",
+						Objects: []*nomad.ObjectDiff{
+							{
+								Type: diffTypeEdited,
+								Name: "Config",
+								Fields: []*nomad.FieldDiff{
+									{Type: diffTypeEdited, Name: "image", Old: old, New: new},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func envDiff(dType, old, new string) *nomad.JobDiff {
+	return &nomad.JobDiff{
+		Type: diffTypeEdited,
+		TaskGroups: []*nomad.TaskGroupDiff{
+			{
+				Type: diffTypeEdited,
+				Name: "cache",
+				Tasks: []*nomad.TaskDiff{
+					{
+						Type: diffTypeEdited,
+						Name: "redis",
+						Objects: []*nomad.ObjectDiff{
+							{
+								Type: diffTypeEdited,
+								Name: "Env",
+								Fields: []*nomad.FieldDiff{
+									{Type: dType, Name: "DEBUG", Old: old, New: new},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestPlan_combineDiffs(t *testing.T) {
+
+	lp := &levantPlan{config: &PlanConfig{Plan: &structs.PlanConfig{}}}
+
+	changes := lp.combineDiffs([]*nomad.JobDiff{
+		imageDiff("redis:3.2", "redis:4.0"),
+		imageDiff("redis:4.0", "redis:5.0"),
+	})
+	if len(changes) != 1 || changes[0].Old != "redis:3.2" || changes[0].New != "redis:5.0" {
+		t.Fatalf("expected a single change from redis:3.2 to redis:5.0, got %+v", changes)
+	}
+
+	changes = lp.combineDiffs([]*nomad.JobDiff{
+		imageDiff("redis:3.2", "redis:4.0"),
+		imageDiff("redis:4.0", "redis:3.2"),
+	})
+	if len(changes) != 0 {
+		t.Fatalf("expected reverted change to be dropped, got %+v", changes)
+	}
+	changes = lp.combineDiffs([]*nomad.JobDiff{
+		imageDiff("redis:3.2", "redis:4.0"),
+		envDiff(diffTypeDeleted, "true", ""),
+	})
+	if len(changes) != 2 || changes[1].Type != diffTypeDeleted || changes[1].Old != "true" {
+		t.Fatalf("expected the deleted env var to be combined as a deletion, got %+v", changes)
+	}
+
+	changes = lp.combineDiffs([]*nomad.JobDiff{
+		envDiff(diffTypeDeleted, "true", ""),
+		envDiff(diffTypeAdded, "", "false"),
+	})
+	if len(changes) != 1 || changes[0].Type != diffTypeEdited || changes[0].Old != "true" || changes[0].New != "false" {
+		t.Fatalf("expected the deleted and re-added env var to be combined as an edit, got %+v", changes)
+	}
+}
+
+func TestPlan_versionSince(t *testing.T) {
+
+	now := time.Now()
+	submit := func(d time.Duration) *int64 { return helper.Int64ToPtr(now.Add(-d).UnixNano()) }
+	version := func(v uint64) *uint64 { return &v }
+
+	versions := []*nomad.Job{
+		{Version: version(3), SubmitTime: submit(time.Hour)},
+		{Version: version(2), SubmitTime: submit(30 * time.Hour)},
+		{Version: version(1), SubmitTime: submit(48 * time.Hour)},
+	}
+
+	if idx := versionSince(versions, now.Add(-24*time.Hour)); idx != 1 {
+		t.Fatalf("expected version at index 1, got %d", idx)
+	}
+	if idx := versionSince(versions, now.Add(-72*time.Hour)); idx != -1 {
+		t.Fatalf("expected no version, got %d", idx)
+	}
+}
+
+func TestPlan_sinceDiff(t *testing.T) {
+
+	now := time.Now()
+	v1, v2 := uint64(1), uint64(2)
+
+	versions := []*nomad.Job{
+		{Version: &v2, SubmitTime: helper.Int64ToPtr(now.Add(-time.Hour).UnixNano())},
+		{Version: &v1, SubmitTime: helper.Int64ToPtr(now.Add(-48 * time.Hour).UnixNano())},
+	}
+	diffs := []*nomad.JobDiff{imageDiff("redis:3.2", "redis:4.0")}
+
+	cases := []struct {
+		Name     string
+		Jobs     *fakeJobs
+		Plan     *nomad.JobDiff
+		Since    time.Duration
+		Contains []string
+		Error    bool
+	}{
+		{
+			Name:     "combined with plan",
+			Jobs:     &fakeJobs{versions: versions, diffs: diffs},
+			Plan:     imageDiff("redis:4.0", "redis:5.0"),
+			Since:    24 * time.Hour,
+			Contains: []string{"since version 1 of the job", "from redis:3.2 to redis:5.0"},
+		},
+		{
+			Name:     "current version",
+			Jobs:     &fakeJobs{versions: versions, diffs: diffs},
+			Plan:     &nomad.JobDiff{Type: diffTypeNone},
+			Since:    time.Minute,
+			Contains: []string{"no changes since version 2 of the job"},
+		},
+		{
+			Name:     "job not registered",
+			Jobs:     &fakeJobs{versionsErr: fmt.Errorf("Unexpected response code: 404 (job not found)")},
+			Since:    time.Hour,
+			Contains: []string{"job is not registered"},
+		},
+		{
+			Name:  "no version before",
+			Jobs:  &fakeJobs{versions: versions, diffs: diffs},
+			Since: 72 * time.Hour,
+			Error: true,
+		},
+	}
+
+	for _, tc := range cases {
+		var buf bytes.Buffer
+		log.Logger = zerolog.New(&buf)
+
+		lp := &levantPlan{
+			config: &PlanConfig{
+				Plan:     &structs.PlanConfig{Since: tc.Since},
+				Template: &structs.TemplateConfig{Job: &nomad.Job{ID: helper.StringToPtr("example")}},
+			},
+			jobs: tc.Jobs,
+			diff: tc.Plan,
+		}
+
+		err := lp.sinceDiff()
+		if tc.Error {
+			if err == nil {
+				t.Fatalf("case %s: expected error", tc.Name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("case %s: unexpected error: %v", tc.Name, err)
+		}
+		for _, c := range tc.Contains {
+			if !strings.Contains(buf.String(), c) {
+				t.Fatalf("case %s: expected logs to contain %q, got %s", tc.Name, c, buf.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,1374 @@
+package levant
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/helper"
+	"github.com/jrasell/levant/levant/structs"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func TestPlan_recurseObjDiffAdded(t *testing.T) {
+
+	var buf bytes.Buffer
+	log.Logger = zerolog.New(&buf)
+
+	// An edited group which has gained a new service block, which in turn
+	// contains a new check block.
+	diff := &nomad.JobDiff{
+		Type: diffTypeEdited,
+		TaskGroups: []*nomad.TaskGroupDiff{
+			{
+				Type: diffTypeEdited,
+				Name: "cache",
+				Objects: []*nomad.ObjectDiff{
+					{
+						Type: diffTypeAdded,
+						Name: "Service",
+						Fields: []*nomad.FieldDiff{
+							{Type: diffTypeAdded, Name: "Name", New: "redis-cache"},
+						},
+						Objects: []*nomad.ObjectDiff{
+							{
+								Type: diffTypeAdded,
+								Name: "Check",
+								Fields: []*nomad.FieldDiff{
+									{Type: diffTypeAdded, Name: "Type", New: "tcp"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	lp := &levantPlan{}
+	lp.planDiff(diff)
+
+	expected := []string{
+		"group cache plan indicates addition of Service:Name with value redis-cache",
+		"group cache plan indicates addition of Check:Type with value tcp",
+	}
+
+	for _, e := range expected {
+		if !strings.Contains(buf.String(), e) {
+			t.Fatalf("expected plan output to contain %q, got %s", e, buf.String())
+		}
+	}
+}
+
+func TestPlan_destructiveChanges(t *testing.T) {
+
+	log.Logger = zerolog.New(ioutil.Discard)
+
+	cases := []struct {
+		Annotations []string
+		Expected    []string
+	}{
+		{
+			[]string{annotationForcesDestructiveUpdate},
+			[]string{"group cache task redis Config:image"},
+		},
+		{
+			[]string{"forces in-place update"},
+			nil,
+		},
+	}
+
+	for i, tc := range cases {
+		diff := &nomad.JobDiff{
+			Type: diffTypeEdited,
+			TaskGroups: []*nomad.TaskGroupDiff{
+				{
+					Type: diffTypeEdited,
+					Name: "cache",
+					Tasks: []*nomad.TaskDiff{
+						{
+							Type:        diffTypeEdited,
+							Name:        "redis",
+							Annotations: tc.Annotations,
+							Objects: []*nomad.ObjectDiff{
+								{
+									Type: diffTypeEdited,
+									Name: "Config",
+									Fields: []*nomad.FieldDiff{
+										{Type: diffTypeEdited, Name: "image", Old: "redis:3.2", New: "redis:4.0"},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+
+		lp := &levantPlan{}
+		lp.planDiff(diff)
+
+		if !reflect.DeepEqual(lp.destructive, tc.Expected) {
+			t.Fatalf("case %d: got %v, expected %v", i, lp.destructive, tc.Expected)
+		}
+	}
+}
+
+func TestPlan_jobSpecDiffers(t *testing.T) {
+
+	buildJob := func(meta string, version uint64, modifyIndex uint64) *nomad.Job {
+		id := "example"
+		status := "running"
+		return &nomad.Job{
+			ID:          &id,
+			Name:        &id,
+			Meta:        map[string]string{"build": meta},
+			Status:      &status,
+			Version:     &version,
+			ModifyIndex: &modifyIndex,
+		}
+	}
+
+	cases := []struct {
+		Rendered *nomad.Job
+		Running  *nomad.Job
+		Expected bool
+	}{
+		{
+			buildJob("1", 0, 0),
+			buildJob("1", 3, 100),
+			false,
+		},
+		{
+			buildJob("2", 0, 0),
+			buildJob("1", 3, 100),
+			true,
+		},
+	}
+
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf).With().Str(structs.JobIDContextField, "example").Logger()
+	lp := &levantPlan{jobLogger: &logger}
+
+	for i, tc := range cases {
+		changed, err := lp.jobSpecDiffers(tc.Rendered, tc.Running)
+		if err != nil {
+			t.Fatalf("case %d: unexpected error: %v", i, err)
+		}
+		if changed != tc.Expected {
+			t.Fatalf("case %d: got %v, expected %v", i, changed, tc.Expected)
+		}
+	}
+
+	// The hashes are logged with the logger of the plan, so carry its job.
+	if !strings.Contains(buf.String(), `"`+structs.JobIDContextField+`":"example"`) {
+		t.Fatalf("expected the hashes to be logged with the job of the plan, got %s", buf.String())
+	}
+}
+
+func TestPlan_jobSpecDiffersVault(t *testing.T) {
+
+	buildJob := func(policy string) *nomad.Job {
+		return &nomad.Job{
+			ID:   helper.StringToPtr("example"),
+			Name: helper.StringToPtr("example"),
+			TaskGroups: []*nomad.TaskGroup{
+				{
+					Name: helper.StringToPtr("app"),
+					Tasks: []*nomad.Task{
+						{
+							Name:   "server",
+							Driver: "docker",
+							Vault: &nomad.Vault{
+								Policies:     []string{policy},
+								ChangeMode:   helper.StringToPtr("signal"),
+								ChangeSignal: helper.StringToPtr("SIGHUP"),
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	// The servers add the Vault version and signal constraints to the group
+	// when the job is registered.
+	running := buildJob("app")
+	running.TaskGroups[0].Constraints = []*nomad.Constraint{
+		{LTarget: "${attr.vault.version}", RTarget: ">= 0.6.1", Operand: "semver"},
+		{LTarget: "${attr.os.signals}", RTarget: "SIGHUP", Operand: "set_contains"},
+	}
+
+	lp := &levantPlan{}
+	changed, err := lp.jobSpecDiffers(buildJob("app"), running)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if changed {
+		t.Fatal("expected the implied constraints not to be reported as a change")
+	}
+
+	// Constraints written within the job are still compared.
+	constrained := buildJob("app")
+	constrained.TaskGroups[0].Constraints = []*nomad.Constraint{
+		{LTarget: "${attr.kernel.name}", RTarget: "linux", Operand: "="},
+	}
+	if changed, _ = lp.jobSpecDiffers(constrained, running); !changed {
+		t.Fatal("expected a written constraint to be reported as a change")
+	}
+
+	if changed, _ = lp.jobSpecDiffers(buildJob("other"), running); !changed {
+		t.Fatal("expected a changed Vault policy to be reported as a change")
+	}
+}
+
+func TestPlan_jobFieldDiff(t *testing.T) {
+
+	var buf bytes.Buffer
+	log.Logger = zerolog.New(&buf)
+
+	diff := &nomad.JobDiff{
+		Type: diffTypeEdited,
+		Fields: []*nomad.FieldDiff{
+			{Type: diffTypeEdited, Name: "Priority", Old: "50", New: "80"},
+		},
+	}
+
+	lp := &levantPlan{}
+	lp.planDiff(diff)
+
+	e := "plan indicates change of Job:Priority from 50 to 80"
+	if !strings.Contains(buf.String(), e) {
+		t.Fatalf("expected plan output to contain %q, got %s", e, buf.String())
+	}
+}
+
+func TestPlan_groupCountDiff(t *testing.T) {
+
+	var buf bytes.Buffer
+	log.Logger = zerolog.New(&buf)
+
+	// A count only change is reported within the group fields rather than the
+	// group objects.
+	diff := &nomad.JobDiff{
+		Type: diffTypeEdited,
+		TaskGroups: []*nomad.TaskGroupDiff{
+			{
+				Type: diffTypeEdited,
+				Name: "cache",
+				Fields: []*nomad.FieldDiff{
+					{Type: diffTypeEdited, Name: "Count", Old: "1", New: "3"},
+				},
+			},
+		},
+	}
+
+	lp := &levantPlan{}
+	lp.planDiff(diff)
+
+	e := "group cache plan indicates change of TaskGroup:Count from 1 to 3"
+	if !strings.Contains(buf.String(), e) {
+		t.Fatalf("expected plan output to contain %q, got %s", e, buf.String())
+	}
+	if len(lp.destructive) != 0 {
+		t.Fatalf("expected no destructive changes, got %v", lp.destructive)
+	}
+}
+
+func TestPlan_systemJobPlan(t *testing.T) {
+
+	var buf bytes.Buffer
+	log.Logger = zerolog.New(&buf)
+
+	system := nomad.JobTypeSystem
+	lp := &levantPlan{
+		config: &PlanConfig{
+			Template: &structs.TemplateConfig{Job: &nomad.Job{Type: &system}},
+		},
+	}
+
+	resp := &nomad.JobPlanResponse{
+		Diff: &nomad.JobDiff{
+			Type: diffTypeEdited,
+			TaskGroups: []*nomad.TaskGroupDiff{
+				{
+					Type: diffTypeEdited,
+					Name: "agent",
+					Tasks: []*nomad.TaskDiff{
+						{
+							Type:        diffTypeEdited,
+							Name:        "collector",
+							Annotations: []string{annotationForcesDestructiveUpdate},
+							Objects: []*nomad.ObjectDiff{
+								{
+									Type: diffTypeEdited,
+									Name: "Config",
+									Fields: []*nomad.FieldDiff{
+										{Type: diffTypeEdited, Name: "image", Old: "collector:1", New: "collector:2"},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		Annotations: &nomad.PlanAnnotations{
+			DesiredTGUpdates: map[string]*nomad.DesiredUpdates{
+				"agent": {DestructiveUpdate: 3},
+			},
+		},
+	}
+
+	lp.planDiff(resp.Diff)
+	lp.logNonDeploymentPlan(resp)
+
+	for _, e := range []string{
+		"group agent and task collector plan indicates change of Config:image from collector:1 to collector:2",
+		"system jobs do not use deployments",
+		"group agent plan indicates 0 allocation(s) to place, 0 to update in-place, 3 to update destructively and 0 to stop",
+	} {
+		if !strings.Contains(buf.String(), e) {
+			t.Fatalf("expected plan output to contain %q, got %s", e, buf.String())
+		}
+	}
+
+	// Service jobs use deployments and so do not log the placement summary.
+	buf.Reset()
+	service := nomad.JobTypeService
+	lp.config.Template.Job.Type = &service
+	lp.logNonDeploymentPlan(resp)
+
+	if buf.Len() != 0 {
+		t.Fatalf("expected no output for service job, got %s", buf.String())
+	}
+}
+
+func TestPlan_redactJob(t *testing.T) {
+
+	job := &nomad.Job{
+		ID:         helper.StringToPtr("example"),
+		VaultToken: helper.StringToPtr("s.secret"),
+		TaskGroups: []*nomad.TaskGroup{
+			{
+				Name: helper.StringToPtr("cache"),
+				Tasks: []*nomad.Task{
+					{
+						Name: "redis",
+						Env:  map[string]string{"DB_PASSWORD": "hunter2", "PORT": "6379"},
+					},
+				},
+			},
+		},
+	}
+
+	out, err := redactJob(job, []string{"*_password"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, s := range []string{"s.secret", "hunter2"} {
+		if strings.Contains(string(out), s) {
+			t.Fatalf("expected %q to be redacted, got %s", s, out)
+		}
+	}
+	for _, s := range []string{`"DB_PASSWORD": "***"`, `"PORT": "6379"`, `"ID": "example"`} {
+		if !strings.Contains(string(out), s) {
+			t.Fatalf("expected output to contain %q, got %s", s, out)
+		}
+	}
+}
+
+// fakeJobs implements jobsAPI returning canned responses.
+type fakeJobs struct {
+	plan    *nomad.JobPlanResponse
+	planErr error
+	info    *nomad.Job
+	infoErr error
+
+	validate *nomad.JobValidateResponse
+
+	versions    []*nomad.Job
+	diffs       []*nomad.JobDiff
+	versionsErr error
+}
+
+func (f *fakeJobs) Info(jobID string, q *nomad.QueryOptions) (*nomad.Job, *nomad.QueryMeta, error) {
+	return f.info, &nomad.QueryMeta{}, f.infoErr
+}
+
+func (f *fakeJobs) Plan(job *nomad.Job, diff bool, q *nomad.WriteOptions) (*nomad.JobPlanResponse, *nomad.WriteMeta, error) {
+	return f.plan, &nomad.WriteMeta{}, f.planErr
+}
+
+func (f *fakeJobs) Register(job *nomad.Job, q *nomad.WriteOptions) (*nomad.JobRegisterResponse, *nomad.WriteMeta, error) {
+	return &nomad.JobRegisterResponse{}, &nomad.WriteMeta{}, nil
+}
+
+func (f *fakeJobs) Validate(job *nomad.Job, q *nomad.WriteOptions) (*nomad.JobValidateResponse, *nomad.WriteMeta, error) {
+	if f.validate == nil {
+		return &nomad.JobValidateResponse{}, &nomad.WriteMeta{}, nil
+	}
+	return f.validate, &nomad.WriteMeta{}, nil
+}
+
+func (f *fakeJobs) Versions(jobID string, diffs bool, q *nomad.QueryOptions) ([]*nomad.Job, []*nomad.JobDiff, *nomad.QueryMeta, error) {
+	return f.versions, f.diffs, &nomad.QueryMeta{}, f.versionsErr
+}
+
+func TestPlan_plan(t *testing.T) {
+
+	log.Logger = zerolog.New(ioutil.Discard)
+
+	editedDiff := func(annotations []string) *nomad.JobDiff {
+		return &nomad.JobDiff{
+			Type: diffTypeEdited,
+			TaskGroups: []*nomad.TaskGroupDiff{
+				{
+					Type: diffTypeEdited,
+					Name: "cache",
+					Tasks: []*nomad.TaskDiff{
+						{
+							Type:        diffTypeEdited,
+							Name:        "redis",
+							Annotations: annotations,
+							Objects: []*nomad.ObjectDiff{
+								{
+									Type: diffTypeEdited,
+									Name: "Config",
+									Fields: []*nomad.FieldDiff{
+										{Type: diffTypeEdited, Name: "image", Old: "redis:3.2", New: "redis:4.0"},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	countDiff := &nomad.JobDiff{
+		Type: diffTypeEdited,
+		TaskGroups: []*nomad.TaskGroupDiff{
+			{
+				Type: diffTypeEdited,
+				Name: "cache",
+				Fields: []*nomad.FieldDiff{
+					{Type: diffTypeEdited, Name: "Count", Old: "5", New: "3"},
+				},
+			},
+		},
+	}
+
+	// A count drift alongside an added env var and an added task group, so
+	// the changes remain once the count is ignored.
+	countAndAddedDiff := &nomad.JobDiff{
+		Type: diffTypeEdited,
+		TaskGroups: []*nomad.TaskGroupDiff{
+			{Type: diffTypeAdded, Name: "api"},
+			{
+				Type: diffTypeEdited,
+				Name: "cache",
+				Fields: []*nomad.FieldDiff{
+					{Type: diffTypeEdited, Name: "Count", Old: "5", New: "3"},
+				},
+				Tasks: []*nomad.TaskDiff{
+					{
+						Type: diffTypeEdited,
+						Name: "redis",
+						Objects: []*nomad.ObjectDiff{
+							{
+								Type: diffTypeEdited,
+								Name: "Env",
+								Fields: []*nomad.FieldDiff{
+									{Type: diffTypeAdded, Name: "LOG_LEVEL", New: "debug"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	// A count drift alongside only an added task group.
+	countAndGroupDiff := &nomad.JobDiff{
+		Type: diffTypeEdited,
+		TaskGroups: []*nomad.TaskGroupDiff{
+			{Type: diffTypeAdded, Name: "api"},
+			{
+				Type: diffTypeEdited,
+				Name: "cache",
+				Fields: []*nomad.FieldDiff{
+					{Type: diffTypeEdited, Name: "Count", Old: "5", New: "3"},
+				},
+			},
+		},
+	}
+
+	// An ignored job meta timestamp alongside an added task, or an added env
+	// var, which must still be deployed.
+	metaAndAddedDiff := func(task *nomad.TaskDiff) *nomad.JobDiff {
+		return &nomad.JobDiff{
+			Type: diffTypeEdited,
+			Objects: []*nomad.ObjectDiff{
+				{
+					Type: diffTypeEdited,
+					Name: "Meta",
+					Fields: []*nomad.FieldDiff{
+						{Type: diffTypeEdited, Name: "deployed_at", Old: "1570000000", New: "1570000600"},
+					},
+				},
+			},
+			TaskGroups: []*nomad.TaskGroupDiff{
+				{Type: diffTypeEdited, Name: "cache", Tasks: []*nomad.TaskDiff{task}},
+			},
+		}
+	}
+	addedTask := &nomad.TaskDiff{Type: diffTypeAdded, Name: "exporter"}
+	addedEnv := &nomad.TaskDiff{
+		Type: diffTypeEdited,
+		Name: "redis",
+		Objects: []*nomad.ObjectDiff{
+			{
+				Type:   diffTypeEdited,
+				Name:   "Env",
+				Fields: []*nomad.FieldDiff{{Type: diffTypeAdded, Name: "LOG_LEVEL", New: "debug"}},
+			},
+		},
+	}
+
+	running := &nomad.Job{ID: helper.StringToPtr("example"), Name: helper.StringToPtr("example")}
+
+	failed := map[string]*nomad.AllocationMetric{
+		"cache": {NodesEvaluated: 3, NodesExhausted: 3, DimensionExhausted: map[string]int{"memory": 3}},
+	}
+
+	cases := []struct {
+		Name     string
+		Jobs     *fakeJobs
+		Plan     *structs.PlanConfig
+		Changes  bool
+		Error    bool
+		Recorded int
+	}{
+		{
+			Name:    "added",
+			Jobs:    &fakeJobs{plan: &nomad.JobPlanResponse{Diff: &nomad.JobDiff{Type: diffTypeAdded}}},
+			Plan:    &structs.PlanConfig{},
+			Changes: true,
+		},
+		{
+			Name: "none",
+			Jobs: &fakeJobs{plan: &nomad.JobPlanResponse{Diff: &nomad.JobDiff{Type: diffTypeNone}}},
+			Plan: &structs.PlanConfig{},
+		},
+		{
+			Name: "none accept no diff matching spec",
+			Jobs: &fakeJobs{plan: &nomad.JobPlanResponse{Diff: &nomad.JobDiff{Type: diffTypeNone}}, info: running},
+			Plan: &structs.PlanConfig{AcceptNoDiff: true},
+		},
+		{
+			Name:     "edited",
+			Jobs:     &fakeJobs{plan: &nomad.JobPlanResponse{Diff: editedDiff(nil)}},
+			Plan:     &structs.PlanConfig{FailOnDestructive: true},
+			Changes:  true,
+			Recorded: 1,
+		},
+		{
+			Name:     "edited destructive",
+			Jobs:     &fakeJobs{plan: &nomad.JobPlanResponse{Diff: editedDiff([]string{annotationForcesDestructiveUpdate})}},
+			Plan:     &structs.PlanConfig{FailOnDestructive: true},
+			Changes:  true,
+			Error:    true,
+			Recorded: 1,
+		},
+		{
+			Name: "edited ignored field",
+			Jobs: &fakeJobs{plan: &nomad.JobPlanResponse{Diff: editedDiff(nil)}},
+			Plan: &structs.PlanConfig{IgnoreFields: []string{"Config:image"}},
+		},
+		{
+			Name: "edited destructive ignored field",
+			Jobs: &fakeJobs{plan: &nomad.JobPlanResponse{Diff: editedDiff([]string{annotationForcesDestructiveUpdate})}},
+			Plan: &structs.PlanConfig{FailOnDestructive: true, IgnoreFields: []string{"Config:image"}},
+		},
+		{
+			Name:     "edited other field ignored",
+			Jobs:     &fakeJobs{plan: &nomad.JobPlanResponse{Diff: editedDiff(nil)}},
+			Plan:     &structs.PlanConfig{IgnoreFields: []string{"Meta:image"}},
+			Changes:  true,
+			Recorded: 1,
+		},
+		{
+			Name:    "ignored field with added task",
+			Jobs:    &fakeJobs{plan: &nomad.JobPlanResponse{Diff: metaAndAddedDiff(addedTask)}},
+			Plan:    &structs.PlanConfig{IgnoreFields: []string{"Meta:deployed_at"}},
+			Changes: true,
+		},
+		{
+			Name:     "ignored field with added env var",
+			Jobs:     &fakeJobs{plan: &nomad.JobPlanResponse{Diff: metaAndAddedDiff(addedEnv)}},
+			Plan:     &structs.PlanConfig{IgnoreFields: []string{"Meta:deployed_at"}},
+			Changes:  true,
+			Recorded: 1,
+		},
+		{
+			Name:     "edited count",
+			Jobs:     &fakeJobs{plan: &nomad.JobPlanResponse{Diff: countDiff}},
+			Plan:     &structs.PlanConfig{},
+			Changes:  true,
+			Recorded: 1,
+		},
+		{
+			Name: "edited count ignored",
+			Jobs: &fakeJobs{plan: &nomad.JobPlanResponse{Diff: countDiff}},
+			Plan: &structs.PlanConfig{IgnoreCountChanges: true},
+		},
+		{
+			Name:     "edited count ignored with additions",
+			Jobs:     &fakeJobs{plan: &nomad.JobPlanResponse{Diff: countAndAddedDiff}},
+			Plan:     &structs.PlanConfig{IgnoreCountChanges: true},
+			Changes:  true,
+			Recorded: 1,
+		},
+		{
+			Name:    "edited count ignored with added group",
+			Jobs:    &fakeJobs{plan: &nomad.JobPlanResponse{Diff: countAndGroupDiff}},
+			Plan:    &structs.PlanConfig{IgnoreCountChanges: true},
+			Changes: true,
+		},
+		{
+			Name:     "edited placement failure",
+			Jobs:     &fakeJobs{plan: &nomad.JobPlanResponse{Diff: editedDiff(nil), FailedTGAllocs: failed}},
+			Plan:     &structs.PlanConfig{},
+			Changes:  true,
+			Recorded: 1,
+		},
+		{
+			Name:     "edited fail on placement failure",
+			Jobs:     &fakeJobs{plan: &nomad.JobPlanResponse{Diff: editedDiff(nil), FailedTGAllocs: failed}},
+			Plan:     &structs.PlanConfig{FailOnPlacementFailure: true},
+			Changes:  true,
+			Error:    true,
+			Recorded: 1,
+		},
+		{
+			Name:    "added fail on placement failure",
+			Jobs:    &fakeJobs{plan: &nomad.JobPlanResponse{Diff: &nomad.JobDiff{Type: diffTypeAdded}, FailedTGAllocs: failed}},
+			Plan:    &structs.PlanConfig{FailOnPlacementFailure: true},
+			Changes: true,
+			Error:   true,
+		},
+		{
+			Name:  "plan error",
+			Jobs:  &fakeJobs{planErr: fmt.Errorf("connection refused")},
+			Plan:  &structs.PlanConfig{},
+			Error: true,
+		},
+		{
+			Name:  "plan forbidden",
+			Jobs:  &fakeJobs{planErr: fmt.Errorf("Unexpected response code: 403 (Permission denied)")},
+			Plan:  &structs.PlanConfig{},
+			Error: true,
+		},
+		{
+			Name:    "plan forbidden optional",
+			Jobs:    &fakeJobs{planErr: fmt.Errorf("Unexpected response code: 403 (Permission denied)")},
+			Plan:    &structs.PlanConfig{Optional: true},
+			Changes: true,
+		},
+		{
+			Name:    "plan not supported optional",
+			Jobs:    &fakeJobs{planErr: fmt.Errorf("Unexpected response code: 405 (method not allowed)")},
+			Plan:    &structs.PlanConfig{Optional: true},
+			Changes: true,
+		},
+		{
+			Name:  "plan error optional",
+			Jobs:  &fakeJobs{planErr: fmt.Errorf("Unexpected response code: 500 (rpc error: no leader)")},
+			Plan:  &structs.PlanConfig{Optional: true},
+			Error: true,
+		},
+	}
+
+	for _, tc := range cases {
+		lp := &levantPlan{
+			jobs: tc.Jobs,
+			config: &PlanConfig{
+				Plan: tc.Plan,
+				Template: &structs.TemplateConfig{
+					Job: &nomad.Job{ID: helper.StringToPtr("example"), Name: helper.StringToPtr("example")},
+				},
+			},
+		}
+
+		changes, err := lp.plan()
+		if (err != nil) != tc.Error {
+			t.Fatalf("%s: expected error %t, got %v", tc.Name, tc.Error, err)
+		}
+		if changes != tc.Changes {
+			t.Fatalf("%s: expected changes %t, got %t", tc.Name, tc.Changes, changes)
+		}
+		if len(lp.changes) != tc.Recorded {
+			t.Fatalf("%s: expected %d recorded changes, got %d", tc.Name, tc.Recorded, len(lp.changes))
+		}
+	}
+}
+
+func TestPlan_collectDiff(t *testing.T) {
+
+	log.Logger = zerolog.New(ioutil.Discard)
+
+	cases := []struct {
+		Name     string
+		Objects  []*nomad.ObjectDiff
+		Expected []string
+	}{
+		{
+			Name: "added",
+			Objects: []*nomad.ObjectDiff{
+				{
+					Type: diffTypeAdded,
+					Name: "Service",
+					Fields: []*nomad.FieldDiff{
+						{Type: diffTypeAdded, Name: "Name", New: "redis-cache"},
+					},
+				},
+			},
+			Expected: []string{"Added group cache Service:Name"},
+		},
+		{
+			Name: "edited",
+			Objects: []*nomad.ObjectDiff{
+				{
+					Type: diffTypeEdited,
+					Name: "RestartPolicy",
+					Fields: []*nomad.FieldDiff{
+						{Type: diffTypeEdited, Name: "Attempts", Old: "2", New: "3"},
+						{Type: diffTypeNone, Name: "Mode", Old: "fail", New: "fail"},
+					},
+				},
+			},
+			Expected: []string{"Edited group cache RestartPolicy:Attempts"},
+		},
+		{
+			Name: "deleted",
+			Objects: []*nomad.ObjectDiff{
+				{
+					Type: diffTypeEdited,
+					Name: "Meta",
+					Fields: []*nomad.FieldDiff{
+						{Type: "Deleted", Name: "owner", Old: "ops"},
+					},
+				},
+				{
+					Type: "Deleted",
+					Name: "Service",
+					Fields: []*nomad.FieldDiff{
+						{Type: "Deleted", Name: "Name", Old: "redis-cache"},
+					},
+				},
+			},
+			Expected: []string{"Deleted group cache Meta:owner", "Deleted group cache Service:Name"},
+		},
+		{
+			Name: "nested",
+			Objects: []*nomad.ObjectDiff{
+				{
+					Type: diffTypeEdited,
+					Name: "Service",
+					Objects: []*nomad.ObjectDiff{
+						{
+							Type: diffTypeEdited,
+							Name: "Check",
+							Fields: []*nomad.FieldDiff{
+								{Type: diffTypeEdited, Name: "Interval", Old: "10000000000", New: "5000000000"},
+							},
+						},
+						{
+							Type: diffTypeAdded,
+							Name: "CheckRestart",
+							Fields: []*nomad.FieldDiff{
+								{Type: diffTypeAdded, Name: "Limit", New: "3"},
+							},
+						},
+					},
+				},
+			},
+			Expected: []string{"Edited group cache Check:Interval", "Added group cache CheckRestart:Limit"},
+		},
+	}
+
+	for _, tc := range cases {
+		diff := &nomad.JobDiff{
+			Type: diffTypeEdited,
+			TaskGroups: []*nomad.TaskGroupDiff{
+				{Type: diffTypeEdited, Name: "cache", Objects: tc.Objects},
+			},
+		}
+
+		lp := &levantPlan{}
+		lp.collectDiff(diff)
+
+		var out []string
+		for _, c := range lp.changes {
+			out = append(out, c.Type+" "+c.path())
+		}
+
+		if !reflect.DeepEqual(out, tc.Expected) {
+			t.Fatalf("%s: expected %v, got %v", tc.Name, tc.Expected, out)
+		}
+	}
+}
+
+func TestPlan_diffContext(t *testing.T) {
+
+	var buf bytes.Buffer
+	log.Logger = zerolog.New(&buf)
+
+	diff := &nomad.JobDiff{
+		Type: diffTypeEdited,
+		TaskGroups: []*nomad.TaskGroupDiff{
+			{
+				Type: diffTypeEdited,
+				Name: "cache",
+				Objects: []*nomad.ObjectDiff{
+					{
+						Type: diffTypeEdited,
+						Name: "RestartPolicy",
+						Fields: []*nomad.FieldDiff{
+							{Type: diffTypeNone, Name: "Mode", Old: "fail", New: "fail"},
+							{Type: diffTypeEdited, Name: "Attempts", Old: "2", New: "3"},
+							{Type: diffTypeNone, Name: "Delay", Old: "15000000000", New: "15000000000"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	// By default only the changed fields are recorded.
+	lp := &levantPlan{config: &PlanConfig{Plan: &structs.PlanConfig{}}}
+	lp.planDiff(diff)
+
+	if len(lp.changes) != 1 || lp.changeCount() != 1 {
+		t.Fatalf("expected a single change, got %d", len(lp.changes))
+	}
+	if strings.Contains(buf.String(), "no change of") {
+		t.Fatalf("expected no context in plan output, got %s", buf.String())
+	}
+
+	buf.Reset()
+	lp = &levantPlan{config: &PlanConfig{Plan: &structs.PlanConfig{DiffContext: true}}}
+	lp.planDiff(diff)
+
+	var out []string
+	for _, c := range lp.changes {
+		out = append(out, c.Type+" "+c.path())
+	}
+	expected := []string{
+		"Edited group cache RestartPolicy:Attempts",
+		"None group cache RestartPolicy:Delay",
+		"None group cache RestartPolicy:Mode",
+	}
+	if !reflect.DeepEqual(out, expected) {
+		t.Fatalf("expected %v, got %v", expected, out)
+	}
+	if lp.changeCount() != 1 {
+		t.Fatalf("expected context fields not to be counted, got %d changes", lp.changeCount())
+	}
+
+	e := "group cache plan indicates no change of RestartPolicy:Mode with value fail"
+	if !strings.Contains(buf.String(), e) {
+		t.Fatalf("expected plan output to contain %q, got %s", e, buf.String())
+	}
+}
+
+func TestPlan_deterministicOrder(t *testing.T) {
+
+	field := func(name string) *nomad.FieldDiff {
+		return &nomad.FieldDiff{Type: diffTypeEdited, Name: name, Old: "1", New: "2"}
+	}
+	task := func(name string) *nomad.TaskDiff {
+		return &nomad.TaskDiff{
+			Type: diffTypeEdited,
+			Name: name,
+			Objects: []*nomad.ObjectDiff{
+				{Type: diffTypeEdited, Name: "Resources", Fields: []*nomad.FieldDiff{field("MemoryMB"), field("CPU")}},
+				{Type: diffTypeEdited, Name: "Config", Fields: []*nomad.FieldDiff{field("image")}},
+			},
+		}
+	}
+	group := func(name string) *nomad.TaskGroupDiff {
+		return &nomad.TaskGroupDiff{
+			Type:   diffTypeEdited,
+			Name:   name,
+			Fields: []*nomad.FieldDiff{field("Count")},
+			Tasks:  []*nomad.TaskDiff{task("sidecar"), task("app")},
+		}
+	}
+
+	expected := []string{
+		"group cache TaskGroup:Count",
+		"group cache task app Config:image",
+		"group cache task app Resources:CPU",
+		"group cache task app Resources:MemoryMB",
+		"group cache task sidecar Config:image",
+		"group cache task sidecar Resources:CPU",
+		"group cache task sidecar Resources:MemoryMB",
+		"group web TaskGroup:Count",
+		"group web task app Config:image",
+		"group web task app Resources:CPU",
+		"group web task app Resources:MemoryMB",
+		"group web task sidecar Config:image",
+		"group web task sidecar Resources:CPU",
+		"group web task sidecar Resources:MemoryMB",
+	}
+
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 10; i++ {
+		diff := &nomad.JobDiff{Type: diffTypeEdited, TaskGroups: []*nomad.TaskGroupDiff{group("web"), group("cache")}}
+
+		// Shuffle every level of the diff before collecting the changes.
+		r.Shuffle(len(diff.TaskGroups), func(a, b int) {
+			diff.TaskGroups[a], diff.TaskGroups[b] = diff.TaskGroups[b], diff.TaskGroups[a]
+		})
+		for _, tg := range diff.TaskGroups {
+			r.Shuffle(len(tg.Tasks), func(a, b int) { tg.Tasks[a], tg.Tasks[b] = tg.Tasks[b], tg.Tasks[a] })
+			for _, t := range tg.Tasks {
+				r.Shuffle(len(t.Objects), func(a, b int) { t.Objects[a], t.Objects[b] = t.Objects[b], t.Objects[a] })
+				for _, o := range t.Objects {
+					r.Shuffle(len(o.Fields), func(a, b int) { o.Fields[a], o.Fields[b] = o.Fields[b], o.Fields[a] })
+				}
+			}
+		}
+
+		lp := &levantPlan{}
+		lp.collectDiff(diff)
+
+		var out []string
+		for _, c := range lp.changes {
+			out = append(out, c.path())
+		}
+
+		if !reflect.DeepEqual(out, expected) {
+			t.Fatalf("run %d: expected %v, got %v", i, expected, out)
+		}
+	}
+}
+
+func TestPlan_maxPlanDepth(t *testing.T) {
+
+	var buf bytes.Buffer
+	log.Logger = zerolog.New(&buf)
+
+	// Build an edited object nested far deeper than any real job diff with
+	// a changed field at the very bottom.
+	obj := &nomad.ObjectDiff{
+		Type:   diffTypeEdited,
+		Name:   "Leaf",
+		Fields: []*nomad.FieldDiff{{Type: diffTypeEdited, Name: "Value", Old: "a", New: "b"}},
+	}
+	for i := 0; i < 10000; i++ {
+		obj = &nomad.ObjectDiff{Type: diffTypeEdited, Name: "Nested", Objects: []*nomad.ObjectDiff{obj}}
+	}
+
+	diff := &nomad.JobDiff{
+		Type:       diffTypeEdited,
+		TaskGroups: []*nomad.TaskGroupDiff{{Type: diffTypeEdited, Name: "cache", Objects: []*nomad.ObjectDiff{obj, obj}}},
+	}
+
+	lp := &levantPlan{config: &PlanConfig{Plan: &structs.PlanConfig{MaxPlanDepth: 5}}}
+	lp.planDiff(diff)
+
+	if len(lp.changes) != 0 {
+		t.Fatalf("expected no changes beyond the maximum depth, got %d", len(lp.changes))
+	}
+
+	e := "exceeds the maximum depth of 5"
+	if n := strings.Count(buf.String(), e); n != 1 {
+		t.Fatalf("expected a single warning containing %q, got %d in %s", e, n, buf.String())
+	}
+
+	// The default depth still walks the nesting found in real job diffs.
+	leaf := &nomad.ObjectDiff{
+		Type:   diffTypeEdited,
+		Name:   "Leaf",
+		Fields: []*nomad.FieldDiff{{Type: diffTypeEdited, Name: "Value", Old: "a", New: "b"}},
+	}
+	shallow := &nomad.ObjectDiff{Type: diffTypeEdited, Name: "Nested", Objects: []*nomad.ObjectDiff{
+		{Type: diffTypeEdited, Name: "Nested", Objects: []*nomad.ObjectDiff{leaf}},
+	}}
+
+	lp = &levantPlan{}
+	lp.collectDiff(&nomad.JobDiff{
+		Type:       diffTypeEdited,
+		TaskGroups: []*nomad.TaskGroupDiff{{Type: diffTypeEdited, Name: "cache", Objects: []*nomad.ObjectDiff{shallow}}},
+	})
+	if lp.depthExceeded || len(lp.changes) != 1 {
+		t.Fatalf("expected a single change within the default depth, got %d", len(lp.changes))
+	}
+}
+
+func TestPlan_planTree(t *testing.T) {
+
+	changes := []*planChange{
+		{Type: diffTypeEdited, Object: "Job", Field: "Priority", Old: "50", New: "60"},
+		{Group: "cache", Type: diffTypeEdited, Object: "TaskGroup", Field: "Count", Old: "1", New: "2"},
+		{Group: "cache", Task: "redis", Type: diffTypeEdited, Object: "Config", Field: "image", Old: "redis:3.2", New: "redis:4.0"},
+		{Group: "cache", Task: "redis", Type: diffTypeEdited, Object: "Config", Field: "port_map", Old: "6379", New: "6380"},
+		{Group: "cache", Task: "redis", Type: diffTypeAdded, Object: "Service", Field: "Name", New: "redis-cache"},
+		{Group: "web", Task: "nginx", Type: diffTypeEdited, Object: "Config", Field: "image", Old: "nginx:1.16", New: "nginx:1.17"},
+	}
+
+	expected := `Job
+  ~ Priority: "50" => "60"
+group "cache"
+  TaskGroup
+    ~ Count: "1" => "2"
+  task "redis"
+    Config
+      ~ image: "redis:3.2" => "redis:4.0"
+      ~ port_map: "6379" => "6380"
+    Service
+      + Name: "redis-cache"
+group "web"
+  task "nginx"
+    Config
+      ~ image: "nginx:1.16" => "nginx:1.17"`
+
+	if out := planTree(changes); out != expected {
+		t.Fatalf("got plan tree:\n%s\nexpected:\n%s", out, expected)
+	}
+}
+
+func TestPlan_planGrouped(t *testing.T) {
+
+	changes := []*planChange{
+		{Type: diffTypeEdited, Object: "Job", Field: "Priority", Old: "50", New: "60"},
+		{Group: "cache", Task: "redis", Type: diffTypeEdited, Object: "Config", Field: "image", Old: "redis:3.2", New: "redis:4.0", Update: planUpdateDestructive},
+		{Group: "cache", Task: "redis", Type: diffTypeNone, Object: "Config", Field: "port_map", Old: "6379", New: "6379"},
+		{Group: "cache", Task: "redis", Type: diffTypeAdded, Object: "Service", Field: "Name", New: "redis-cache", Update: planUpdateInPlace},
+		{Group: "web", Task: "nginx", Type: diffTypeAdded, Object: "Env", Field: "PORT", New: "80"},
+		{Group: "web", Task: "nginx", Type: diffTypeDeleted, Object: "Env", Field: "DEBUG", Old: "true"},
+	}
+
+	diff := &nomad.JobDiff{
+		Type: diffTypeEdited,
+		TaskGroups: []*nomad.TaskGroupDiff{
+			{Type: diffTypeAdded, Name: "api"},
+			{Type: diffTypeEdited, Name: "web", Tasks: []*nomad.TaskDiff{{Type: diffTypeDeleted, Name: "sidecar"}}},
+			{Type: diffTypeDeleted, Name: "legacy"},
+		},
+	}
+
+	expected := `Destructive changes:
+  ~ group cache task redis Config:image: "redis:3.2" => "redis:4.0"
+
+In-place changes:
+  + group cache task redis Service:Name: "redis-cache"
+
+Additions:
+  + group web task nginx Env:PORT: "80"
+  + group api
+
+Deletions:
+  - group web task nginx Env:DEBUG: "true"
+  - group legacy
+  - group web task sidecar
+
+Other changes:
+  ~ Job:Priority: "50" => "60"`
+
+	if out := planGrouped(changes, diff); out != expected {
+		t.Fatalf("got grouped plan:\n%s\nexpected:\n%s", out, expected)
+	}
+}
+
+func TestPlan_planGroupedDeletions(t *testing.T) {
+
+	log.Logger = zerolog.New(ioutil.Discard)
+
+	lp := &levantPlan{}
+	lp.collectDiff(envDiff(diffTypeDeleted, "true", ""))
+
+	expected := `Deletions:
+  - group cache task redis Env:DEBUG: "true"`
+
+	if out := planGrouped(lp.changes, nil); out != expected {
+		t.Fatalf("got grouped plan:\n%s\nexpected:\n%s", out, expected)
+	}
+}
+
+func TestPlan_fieldUpdateType(t *testing.T) {
+
+	var buf bytes.Buffer
+	log.Logger = zerolog.New(&buf)
+
+	diff := &nomad.JobDiff{
+		Type: diffTypeEdited,
+		TaskGroups: []*nomad.TaskGroupDiff{
+			{
+				Type: diffTypeEdited,
+				Name: "cache",
+				Fields: []*nomad.FieldDiff{
+					{Type: diffTypeEdited, Name: "Count", Old: "1", New: "3", Annotations: []string{"forces create"}},
+				},
+				Tasks: []*nomad.TaskDiff{
+					{
+						Type:        diffTypeEdited,
+						Name:        "redis",
+						Annotations: []string{annotationForcesInPlaceUpdate},
+						Objects: []*nomad.ObjectDiff{
+							{
+								Type: diffTypeEdited,
+								Name: "Service",
+								Fields: []*nomad.FieldDiff{
+									{Type: diffTypeEdited, Name: "PortLabel", Old: "db", New: "redis"},
+								},
+							},
+						},
+					},
+					{
+						Type:        diffTypeEdited,
+						Name:        "sidecar",
+						Annotations: []string{annotationForcesInPlaceUpdate},
+						Objects: []*nomad.ObjectDiff{
+							{
+								Type: diffTypeEdited,
+								Name: "Config",
+								Fields: []*nomad.FieldDiff{
+									{Type: diffTypeEdited, Name: "image", Old: "envoy:1.13", New: "envoy:1.14",
+										Annotations: []string{annotationForcesDestructiveUpdate}},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	lp := &levantPlan{}
+	lp.planDiff(diff)
+
+	var out []string
+	for _, c := range lp.changes {
+		out = append(out, c.path()+" "+c.Update)
+	}
+	expected := []string{
+		"group cache TaskGroup:Count ",
+		"group cache task redis Service:PortLabel in-place",
+		"group cache task sidecar Config:image forces destroy",
+	}
+	if !reflect.DeepEqual(out, expected) {
+		t.Fatalf("expected %v, got %v", expected, out)
+	}
+
+	for _, e := range []string{
+		`"update":"in-place","message":"levant/plan: group cache and task redis plan indicates change of Service:PortLabel from db to redis [in-place]"`,
+		`"update":"forces destroy","message":"levant/plan: group cache and task sidecar plan indicates change of Config:image from envoy:1.13 to envoy:1.14 [forces destroy]"`,
+		`"message":"levant/plan: group cache plan indicates change of TaskGroup:Count from 1 to 3"`,
+	} {
+		if !strings.Contains(buf.String(), e) {
+			t.Fatalf("expected plan output to contain %q, got %s", e, buf.String())
+		}
+	}
+
+	if !reflect.DeepEqual(lp.destructive, []string{"group cache task sidecar Config:image"}) {
+		t.Fatalf("unexpected destructive changes: %v", lp.destructive)
+	}
+}
+
+func TestPlan_logSummary(t *testing.T) {
+
+	var buf bytes.Buffer
+	log.Logger = zerolog.New(&buf)
+
+	diff := &nomad.JobDiff{
+		Type: diffTypeEdited,
+		TaskGroups: []*nomad.TaskGroupDiff{
+			{Type: diffTypeAdded, Name: "api"},
+			{
+				Type: diffTypeEdited,
+				Name: "cache",
+				Fields: []*nomad.FieldDiff{
+					{Type: diffTypeEdited, Name: "Count", Old: "1", New: "3"},
+				},
+				Tasks: []*nomad.TaskDiff{
+					{
+						Type:        diffTypeEdited,
+						Name:        "redis",
+						Annotations: []string{annotationForcesDestructiveUpdate},
+						Objects: []*nomad.ObjectDiff{
+							{
+								Type: diffTypeEdited,
+								Name: "Config",
+								Fields: []*nomad.FieldDiff{
+									{Type: diffTypeEdited, Name: "image", Old: "redis:3.2", New: "redis:4.0"},
+								},
+							},
+						},
+					},
+				},
+			},
+			{Type: diffTypeDeleted, Name: "legacy"},
+			{
+				Type: diffTypeEdited,
+				Name: "web",
+				Objects: []*nomad.ObjectDiff{
+					{
+						Type: diffTypeEdited,
+						Name: "Meta",
+						Fields: []*nomad.FieldDiff{
+							{Type: diffTypeEdited, Name: "deployed_at", Old: "1", New: "2"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	lp := &levantPlan{config: &PlanConfig{Plan: &structs.PlanConfig{IgnoreFields: []string{"Meta:deployed_at"}}}}
+	lp.planDiff(diff)
+
+	expected := `"groups_added":1,"groups_edited":1,"groups_deleted":1,"fields_changed":2,"destructive_changes":1`
+	if !strings.Contains(buf.String(), expected) {
+		t.Fatalf("expected summary %s, got %s", expected, buf.String())
+	}
+}
+
+func TestPlan_placementReasons(t *testing.T) {
+
+	m := &nomad.AllocationMetric{
+		NodesEvaluated:     4,
+		ConstraintFiltered: map[string]int{"${attr.kernel.name} = windows": 3},
+		ClassFiltered:      map[string]int{"gpu": 1},
+		NodesExhausted:     1,
+		DimensionExhausted: map[string]int{"memory": 1, "cpu": 1},
+	}
+
+	expected := []string{
+		`constraint "${attr.kernel.name} = windows" filtered 3 node(s)`,
+		`class "gpu" filtered 1 node(s)`,
+		"resources exhausted on 1 node(s)",
+		`dimension "cpu" exhausted on 1 node(s)`,
+		`dimension "memory" exhausted on 1 node(s)`,
+	}
+	if got := placementReasons(m); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+
+	if got := placementReasons(&nomad.AllocationMetric{}); !reflect.DeepEqual(got, []string{"no nodes were available"}) {
+		t.Fatalf("unexpected reasons for no nodes: %v", got)
+	}
+}
+
+func TestPlan_countFromRunning(t *testing.T) {
+
+	log.Logger = zerolog.New(ioutil.Discard)
+
+	running := &nomad.Job{
+		ID:         helper.StringToPtr("example"),
+		Status:     helper.StringToPtr(jobStatusRunning),
+		TaskGroups: []*nomad.TaskGroup{{Name: helper.StringToPtr("cache"), Count: helper.IntToPtr(5)}},
+	}
+
+	cases := []struct {
+		Name             string
+		CountFromRunning bool
+		Jobs             *fakeJobs
+		Expected         int
+		Error            bool
+	}{
+		{Name: "template counts", Jobs: &fakeJobs{info: running}, Expected: 2},
+		{Name: "running counts", CountFromRunning: true, Jobs: &fakeJobs{info: running}, Expected: 5},
+		{
+			Name:             "job not running",
+			CountFromRunning: true,
+			Jobs:             &fakeJobs{infoErr: fmt.Errorf("Unexpected response code: 404 (job not found)")},
+			Expected:         2,
+		},
+		{
+			Name:             "info error",
+			CountFromRunning: true,
+			Jobs:             &fakeJobs{infoErr: fmt.Errorf("connection refused")},
+			Expected:         2,
+			Error:            true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc.Jobs.plan = &nomad.JobPlanResponse{Diff: &nomad.JobDiff{Type: diffTypeNone}}
+
+		job := &nomad.Job{
+			ID:         helper.StringToPtr("example"),
+			Name:       helper.StringToPtr("example"),
+			TaskGroups: []*nomad.TaskGroup{{Name: helper.StringToPtr("cache"), Count: helper.IntToPtr(2)}},
+		}
+		lp := &levantPlan{
+			jobs: tc.Jobs,
+			config: &PlanConfig{
+				Plan:     &structs.PlanConfig{CountFromRunning: tc.CountFromRunning},
+				Template: &structs.TemplateConfig{Job: job},
+			},
+		}
+
+		if _, err := lp.plan(); (err != nil) != tc.Error {
+			t.Fatalf("%s: expected error %t, got %v", tc.Name, tc.Error, err)
+		}
+		if *job.TaskGroups[0].Count != tc.Expected {
+			t.Fatalf("%s: expected count %d, got %d", tc.Name, tc.Expected, *job.TaskGroups[0].Count)
+		}
+	}
+}
+
+func TestPlan_redactChanges(t *testing.T) {
+
+	var buf bytes.Buffer
+	log.Logger = zerolog.New(&buf)
+
+	lp := &levantPlan{
+		config: &PlanConfig{
+			Plan: &structs.PlanConfig{Redact: []string{"*_password", "Job:Meta[deploy_token]"}},
+		},
+	}
+	lp.changes = []*planChange{
+		{Group: "cache", Task: "redis", Type: diffTypeEdited, Object: "Env", Field: "DB_PASSWORD", Old: "hunter2", New: "hunter3"},
+		{Group: "cache", Task: "redis", Type: diffTypeAdded, Object: "Env", Field: "ADMIN_PASSWORD", New: "secret"},
+		{Type: diffTypeEdited, Object: "Job", Field: "Meta[deploy_token]", Old: "abc", New: "def"},
+		{Group: "cache", Task: "redis", Type: diffTypeEdited, Object: "Env", Field: "PORT", Old: "6379", New: "6380"},
+	}
+
+	lp.logChanges(lp.changes)
+	out := buf.String()
+
+	for _, s := range []string{"hunter2", "hunter3", "secret", "abc", "def"} {
+		if strings.Contains(out, s) {
+			t.Fatalf("expected %q to be redacted, got %s", s, out)
+		}
+	}
+	for _, s := range []string{"Env:DB_PASSWORD from *** to ***", "Env:ADMIN_PASSWORD with value ***", "from 6379 to 6380"} {
+		if !strings.Contains(out, s) {
+			t.Fatalf("expected logs to contain %q, got %s", s, out)
+		}
+	}
+
+	// The recorded changes keep their values.
+	if lp.changes[0].Old != "hunter2" {
+		t.Fatalf("expected recorded change to be unmodified, got %q", lp.changes[0].Old)
+	}
+
+	if !redactMatch([]string{"*_TOKEN"}, "Job", "Meta[deploy_token]") {
+		t.Fatal("expected map key to match redact pattern")
+	}
+}
@@ -0,0 +1,109 @@
+package levant
+
+import (
+	"fmt"
+	"sort"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/jrasell/levant/client"
+	"github.com/jrasell/levant/levant/structs"
+	"github.com/rs/zerolog/log"
+)
+
+// TriggerPromote provides the main entry point into a Levant promote and is
+// used to setup the clients before promoting the canaries of the latest
+// deployment of the job. If groups is empty all canaries are promoted.
+func TriggerPromote(job string, groups []string, clientConfig *structs.ClientConfig) bool {
+
+	client, err := client.NewNomadTargetClient(clientConfig)
+	if err != nil {
+		log.Error().Msgf("levant/promote: unable to setup Levant promote: %v", err)
+		return false
+	}
+
+	dep := &levantDeployment{}
+	dep.nomad = client
+	dep.config = &DeployConfig{
+		Client:   clientConfig,
+		Deploy:   &structs.DeployConfig{},
+		Template: &structs.TemplateConfig{},
+	}
+
+	if err := dep.promote(job, groups); err != nil {
+		log.Error().Err(err).Msgf("levant/promote: promotion of job %s failed", job)
+		return false
+	}
+
+	log.Info().Msgf("levant/promote: promotion of job %s successful", job)
+	return true
+}
+
+// promote finds the latest deployment of the job and promotes the canaries
+// of the requested groups, or all groups when none are specified.
+func (l *levantDeployment) promote(job string, groups []string) error {
+
+	dep, _, err := l.nomad.Jobs().LatestDeployment(job, &nomad.QueryOptions{AllowStale: l.config.Client.AllowStale})
+	if err != nil {
+		return err
+	}
+	if dep == nil {
+		return fmt.Errorf("job %s has no deployment", job)
+	}
+	if dep.Status != jobStatusRunning {
+		return fmt.Errorf("deployment %s has status %s and cannot be promoted", dep.ID, dep.Status)
+	}
+
+	if err := validatePromoteGroups(dep, groups); err != nil {
+		return err
+	}
+
+	var resp *nomad.DeploymentUpdateResponse
+
+	if len(groups) == 0 {
+		l.logger().Info().Msgf("levant/promote: triggering promotion of all canaries in deployment %s", dep.ID)
+		resp, _, err = l.nomad.Deployments().PromoteAll(dep.ID, nil)
+	} else {
+		l.logger().Info().Msgf("levant/promote: triggering promotion of canaries for groups %v in deployment %s",
+			groups, dep.ID)
+		resp, _, err = l.nomad.Deployments().PromoteGroups(dep.ID, groups, nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	l.logger().Info().Msgf("levant/promote: deployment %s promoted with evaluation %s", dep.ID, resp.EvalID)
+	return nil
+}
+
+// validatePromoteGroups checks that every requested group is part of the
+// deployment and has canaries which are waiting to be promoted.
+func validatePromoteGroups(dep *nomad.Deployment, groups []string) error {
+
+	var canaryGroups []string
+
+	for name, state := range dep.TaskGroups {
+		if state.DesiredCanaries > 0 {
+			canaryGroups = append(canaryGroups, name)
+		}
+	}
+	sort.Strings(canaryGroups)
+
+	if len(canaryGroups) == 0 {
+		return fmt.Errorf("deployment %s has no canaries to promote", dep.ID)
+	}
+
+	for _, g := range groups {
+		state, ok := dep.TaskGroups[g]
+		if !ok {
+			return fmt.Errorf("group %s is not part of deployment %s", g, dep.ID)
+		}
+		if state.DesiredCanaries == 0 {
+			return fmt.Errorf("group %s has no canaries to promote, groups with canaries: %v", g, canaryGroups)
+		}
+		if state.Promoted {
+			log.Warn().Msgf("levant/promote: group %s in deployment %s is already promoted", g, dep.ID)
+		}
+	}
+
+	return nil
+}
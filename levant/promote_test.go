@@ -0,0 +1,48 @@
+package levant
+
+import (
+	"testing"
+
+	nomad "github.com/hashicorp/nomad/api"
+)
+
+func TestPromote_validatePromoteGroups(t *testing.T) {
+
+	dep := &nomad.Deployment{
+		ID: "f6e5a1b6-3e5d-7c4a-7a7e-1c7f7c2c4a11",
+		TaskGroups: map[string]*nomad.DeploymentState{
+			"web":   {DesiredCanaries: 1},
+			"api":   {DesiredCanaries: 2},
+			"cache": {DesiredCanaries: 0},
+		},
+	}
+
+	cases := []struct {
+		groups []string
+		err    bool
+	}{
+		{nil, false},
+		{[]string{"web"}, false},
+		{[]string{"web", "api"}, false},
+		{[]string{"cache"}, true},
+		{[]string{"web", "missing"}, true},
+	}
+
+	for _, c := range cases {
+		err := validatePromoteGroups(dep, c.groups)
+		if c.err && err == nil {
+			t.Fatalf("expected error promoting groups %v", c.groups)
+		}
+		if !c.err && err != nil {
+			t.Fatalf("unexpected error promoting groups %v: %v", c.groups, err)
+		}
+	}
+
+	noCanaries := &nomad.Deployment{
+		ID:         "a1b2c3d4-3e5d-7c4a-7a7e-1c7f7c2c4a11",
+		TaskGroups: map[string]*nomad.DeploymentState{"web": {}},
+	}
+	if err := validatePromoteGroups(noCanaries, nil); err == nil {
+		t.Fatal("expected error promoting deployment without canaries")
+	}
+}
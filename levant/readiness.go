@@ -0,0 +1,221 @@
+package levant
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// readinessPollInterval is the time waited between requests to a readiness
+	// endpoint which has not yet returned a 2xx status.
+	readinessPollInterval = 2 * time.Second
+
+	// readinessRequestTimeout bounds each request to a readiness endpoint.
+	readinessRequestTimeout = 10 * time.Second
+)
+
+// readinessWatcher polls the readiness endpoint of each task named within the
+// readiness config, for every running allocation of the latest version of the
+// job, until it returns a 2xx status. This covers tasks, such as raw exec
+// tasks and sidecars, whose readiness is not reflected in the job status or
+// deployment health. The endpoints are polled until the readiness timeout is
+// reached, after which an error wrapping ErrDeployTimeout is returned.
+func (l *levantDeployment) readinessWatcher() error {
+
+	var names []string
+	for name := range l.config.Deploy.Readiness {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ctx := l.config.Client.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, l.config.Deploy.ReadinessTimeout)
+	defer cancel()
+
+	q := &nomad.QueryOptions{AllowStale: l.config.Client.AllowStale}
+	stubs, _, err := l.nomad.Jobs().Allocations(*l.config.Template.Job.ID, false, q)
+	if err != nil {
+		return fmt.Errorf("%w: unable to query allocations of job %s: %v",
+			ErrDeployFailed, *l.config.Template.Job.ID, err)
+	}
+	stubs = latestVersionAllocs(stubs)
+
+	httpClient := &http.Client{Timeout: readinessRequestTimeout}
+
+	for _, name := range names {
+		group, task := splitGroupTask(l.config.Template.Job, name)
+
+		var checked int
+		for _, stub := range stubs {
+			if stub.TaskGroup != group || stub.DesiredStatus != nomad.AllocDesiredStatusRun ||
+				stub.ClientStatus != nomad.AllocClientStatusRunning {
+				continue
+			}
+
+			alloc, _, err := l.nomad.Allocations().Info(stub.ID, q)
+			if err != nil {
+				return fmt.Errorf("%w: unable to query allocation %s: %v", ErrDeployFailed, stub.ID, err)
+			}
+
+			url, err := readinessURL(l.config.Deploy.Readiness[name], alloc, task)
+			if err != nil {
+				return fmt.Errorf("%w: unable to resolve readiness URL of %s in allocation %s: %v",
+					ErrDeployFailed, name, alloc.ID, err)
+			}
+
+			l.logger().Info().Msgf("levant/readiness: waiting for %s in allocation %s to be ready at %s", name, alloc.ID, url)
+			if err := pollReadiness(ctx, l.logger(), httpClient, url); err != nil {
+				return fmt.Errorf("%w: %s in allocation %s was not ready within %v: %v",
+					ErrDeployTimeout, name, alloc.ID, l.config.Deploy.ReadinessTimeout, err)
+			}
+			checked++
+		}
+
+		if checked == 0 {
+			return fmt.Errorf("%w: no running allocations of group %s to check the readiness of %s",
+				ErrDeployFailed, group, name)
+		}
+		l.logger().Info().Msgf("levant/readiness: %s is ready in %d allocation(s)", name, checked)
+	}
+
+	return nil
+}
+
+// splitGroupTask splits the group.task name into the names of the group and
+// task, matching against the tasks of the job so that group names containing
+// dots are split correctly.
+func splitGroupTask(job *nomad.Job, name string) (string, string) {
+	for _, group := range job.TaskGroups {
+		if group.Name == nil {
+			continue
+		}
+		for _, task := range group.Tasks {
+			if *group.Name+"."+task.Name == name {
+				return *group.Name, task.Name
+			}
+		}
+	}
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return name, ""
+}
+
+// readinessURL resolves the ${NOMAD_ADDR_<label>}, ${NOMAD_IP_<label>} and
+// ${NOMAD_PORT_<label>} placeholders within the raw URL using the ports of
+// the network of the task within the allocation, as the host address and port
+// the task is reachable on. Any other placeholder is an error.
+func readinessURL(raw string, alloc *nomad.Allocation, task string) (string, error) {
+
+	ports := make(map[string]string)
+	for _, n := range allocNetworks(alloc, task) {
+		for _, p := range append(append([]nomad.Port{}, n.ReservedPorts...), n.DynamicPorts...) {
+			ports[p.Label] = net.JoinHostPort(n.IP, strconv.Itoa(p.Value))
+		}
+	}
+
+	var missing []string
+	url := os.Expand(raw, func(key string) string {
+		for _, prefix := range []string{"NOMAD_ADDR_", "NOMAD_IP_", "NOMAD_PORT_"} {
+			if !strings.HasPrefix(key, prefix) {
+				continue
+			}
+			addr, ok := ports[strings.TrimPrefix(key, prefix)]
+			if !ok {
+				break
+			}
+			host, port, _ := net.SplitHostPort(addr)
+			switch prefix {
+			case "NOMAD_IP_":
+				return host
+			case "NOMAD_PORT_":
+				return port
+			default:
+				return addr
+			}
+		}
+		missing = append(missing, key)
+		return ""
+	})
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("unknown placeholder(s) %s", strings.Join(missing, ", "))
+	}
+	return url, nil
+}
+
+// allocNetworks returns the networks of the task within the allocation along
+// with the networks shared by the group, such as a bridge network. Clusters
+// which do not report allocated resources use the task resources instead.
+func allocNetworks(alloc *nomad.Allocation, task string) []*nomad.NetworkResource {
+
+	var networks []*nomad.NetworkResource
+
+	if alloc.AllocatedResources != nil {
+		if tr, ok := alloc.AllocatedResources.Tasks[task]; ok && tr != nil {
+			networks = append(networks, tr.Networks...)
+		}
+		networks = append(networks, alloc.AllocatedResources.Shared.Networks...)
+	}
+
+	if len(networks) == 0 {
+		if r, ok := alloc.TaskResources[task]; ok && r != nil {
+			networks = append(networks, r.Networks...)
+		}
+	}
+
+	return networks
+}
+
+// pollReadiness requests the URL until it returns a 2xx status or the context
+// is done, returning the last failure when the context ends first.
+func pollReadiness(ctx context.Context, logger *zerolog.Logger, c *http.Client, url string) error {
+
+	for {
+		err := readinessRequest(ctx, c, url)
+		if err == nil {
+			return nil
+		}
+		logger.Debug().Err(err).Msgf("levant/readiness: %s is not yet ready", url)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(readinessPollInterval):
+		}
+	}
+}
+
+// readinessRequest performs a single GET request of the URL, returning an
+// error unless the response has a 2xx status.
+func readinessRequest(ctx context.Context, c *http.Client, url string) error {
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package levant
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/helper"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func TestReadiness_readinessURL(t *testing.T) {
+
+	alloc := &nomad.Allocation{
+		AllocatedResources: &nomad.AllocatedResources{
+			Tasks: map[string]*nomad.AllocatedTaskResources{
+				"server": {Networks: []*nomad.NetworkResource{
+					{IP: "10.0.0.5", DynamicPorts: []nomad.Port{{Label: "http", Value: 23456}}},
+				}},
+			},
+			Shared: nomad.AllocatedSharedResources{Networks: []*nomad.NetworkResource{
+				{IP: "10.0.0.5", ReservedPorts: []nomad.Port{{Label: "envoy", Value: 19000}}},
+			}},
+		},
+	}
+
+	cases := []struct {
+		Raw      string
+		Task     string
+		Expected string
+		Error    bool
+	}{
+		{Raw: "http://${NOMAD_ADDR_http}/ready", Task: "server", Expected: "http://10.0.0.5:23456/ready"},
+		{Raw: "http://${NOMAD_IP_envoy}:${NOMAD_PORT_envoy}/ready", Task: "server", Expected: "http://10.0.0.5:19000/ready"},
+		{Raw: "http://127.0.0.1:8080/ready", Task: "server", Expected: "http://127.0.0.1:8080/ready"},
+		{Raw: "http://${NOMAD_ADDR_admin}/ready", Task: "server", Error: true},
+		{Raw: "http://${NOMAD_ADDR_http}/ready", Task: "proxy", Error: true},
+		{Raw: "http://${HOST}/ready", Task: "server", Error: true},
+	}
+
+	for _, tc := range cases {
+		out, err := readinessURL(tc.Raw, alloc, tc.Task)
+		if tc.Error {
+			if err == nil {
+				t.Fatalf("%s: expected error, got %s", tc.Raw, out)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.Raw, err)
+		}
+		if out != tc.Expected {
+			t.Fatalf("%s: got %s, expected %s", tc.Raw, out, tc.Expected)
+		}
+	}
+}
+
+func TestReadiness_splitGroupTask(t *testing.T) {
+
+	job := &nomad.Job{
+		TaskGroups: []*nomad.TaskGroup{
+			{Name: helper.StringToPtr("web"), Tasks: []*nomad.Task{{Name: "v2.nginx"}}},
+			{Name: helper.StringToPtr("web.v2"), Tasks: []*nomad.Task{{Name: "nginx"}}},
+			{Name: helper.StringToPtr("web.v3"), Tasks: []*nomad.Task{{Name: "nginx"}}},
+		},
+	}
+
+	group, task := splitGroupTask(job, "web.v3.nginx")
+	if group != "web.v3" || task != "nginx" {
+		t.Fatalf("got group %s task %s, expected group web.v3 task nginx", group, task)
+	}
+}
+
+func TestReadiness_pollReadiness(t *testing.T) {
+
+	log.Logger = zerolog.New(ioutil.Discard)
+
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := pollReadiness(ctx, &log.Logger, srv.Client(), srv.URL); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Fatalf("expected 2 requests, got %d", n)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if err := pollReadiness(ctx, &log.Logger, failing.Client(), failing.URL); err == nil {
+		t.Fatal("expected error once the timeout is reached")
+	}
+}
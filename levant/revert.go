@@ -0,0 +1,148 @@
+package levant
+
+import (
+	"errors"
+	"fmt"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/jrasell/levant/client"
+	"github.com/jrasell/levant/levant/structs"
+	"github.com/rs/zerolog/log"
+)
+
+// RevertConfig is the set of config required to run a Levant revert.
+type RevertConfig struct {
+	Client *structs.ClientConfig
+
+	// Job is the ID of the job to revert.
+	Job string
+
+	// ToVersion is the job version to revert to. When nil the job is reverted
+	// to the latest stable version older than the current version.
+	ToVersion *uint64
+
+	// CancelOnInterrupt fails the revert deployment when Levant receives an
+	// interrupt or terminate signal while watching it.
+	CancelOnInterrupt bool
+}
+
+// TriggerRevert provides the main entry point into a Levant revert. The job is
+// reverted to the resolved version and the resulting deployment watched until
+// it completes. The returned error wraps ErrDeployFailed or
+// ErrDeployInterrupted.
+func TriggerRevert(config *RevertConfig) error {
+
+	c, err := client.NewNomadTargetClient(config.Client)
+	if err != nil {
+		log.Error().Msgf("levant/revert: unable to setup Levant revert: %v", err)
+		return fmt.Errorf("%w: %v", ErrDeployFailed, err)
+	}
+
+	q := &nomad.QueryOptions{AllowStale: config.Client.AllowStale}
+	versions, _, _, err := c.Jobs().Versions(config.Job, false, q)
+	if err != nil {
+		return fmt.Errorf("%w: unable to query versions of job %s: %v", ErrDeployFailed, config.Job, err)
+	}
+
+	current, target, err := resolveRevertVersion(versions, config.ToVersion)
+	if err != nil {
+		return fmt.Errorf("%w: unable to revert job %s: %v", ErrDeployFailed, config.Job, err)
+	}
+
+	log.Info().Msgf("levant/revert: reverting job %s from version %d to version %d",
+		config.Job, *current.Version, *target.Version)
+
+	resp, _, err := c.Jobs().Revert(config.Job, *target.Version, current.Version, nil, "", "")
+	if err != nil {
+		log.Error().Err(err).Msg("levant/revert: unable to revert job with Nomad")
+		return fmt.Errorf("%w: unable to revert job: %v", ErrDeployFailed, err)
+	}
+
+	dep := &levantDeployment{}
+	dep.nomad = c
+	dep.config = &DeployConfig{
+		Client:   config.Client,
+		Deploy:   &structs.DeployConfig{CancelOnInterrupt: config.CancelOnInterrupt},
+		Template: &structs.TemplateConfig{Job: target},
+		EvalID:   resp.EvalID,
+	}
+
+	if err := dep.watchRevert(resp.EvalID); err != nil {
+		return err
+	}
+
+	log.Info().Msgf("levant/revert: job %s successfully reverted from version %d to version %d",
+		config.Job, *current.Version, *target.Version)
+	return nil
+}
+
+// resolveRevertVersion returns the current version of the job along with the
+// version to revert to from the job versions, which Nomad returns newest
+// first. When no version is requested the latest stable version older than
+// the current version is used.
+func resolveRevertVersion(versions []*nomad.Job, toVersion *uint64) (*nomad.Job, *nomad.Job, error) {
+
+	if len(versions) == 0 || versions[0].Version == nil {
+		return nil, nil, errors.New("job has no versions")
+	}
+	current := versions[0]
+
+	if toVersion != nil {
+		if *toVersion == *current.Version {
+			return nil, nil, fmt.Errorf("version %d is the current version", *toVersion)
+		}
+		for _, v := range versions[1:] {
+			if v.Version != nil && *v.Version == *toVersion {
+				return current, v, nil
+			}
+		}
+		return nil, nil, fmt.Errorf("version %d not found", *toVersion)
+	}
+
+	for _, v := range versions[1:] {
+		if v.Version != nil && v.Stable != nil && *v.Stable {
+			return current, v, nil
+		}
+	}
+	return nil, nil, errors.New("no stable version older than the current version found")
+}
+
+// watchRevert monitors the evaluation created by the revert and, for jobs
+// which use Nomad deployments, watches the resulting deployment. Other jobs
+// are checked using the job status checker.
+func (l *levantDeployment) watchRevert(evalID string) error {
+
+	// Periodic and parameterized jobs do not return an evaluation.
+	if evalID == "" {
+		l.logger().Info().Msg("levant/revert: revert did not create an evaluation; no allocations to watch")
+		return nil
+	}
+
+	if err := l.evaluationInspector(&evalID); err != nil {
+		l.logger().Error().Err(err).Msg("levant/revert: evaluation inspection failed")
+		return fmt.Errorf("%w: %v", ErrDeployFailed, err)
+	}
+
+	if watchStrategy(l.config.Template.Job) != watchStrategyDeployment {
+		return jobStatusError(l.jobStatusChecker(&evalID))
+	}
+
+	depID, err := l.getDeploymentID(evalID)
+	if err != nil {
+		l.logger().Error().Err(err).Msgf("levant/revert: unable to get info of evaluation %s", evalID)
+		if errors.Is(err, ErrDeployTimeout) {
+			return err
+		}
+		return fmt.Errorf("%w: %v", ErrDeployFailed, err)
+	}
+
+	l.logger().Info().Msgf("levant/revert: beginning deployment watcher for deployment %s", depID)
+
+	if l.deploymentWatcher(depID) {
+		return nil
+	}
+	if l.interrupted {
+		return fmt.Errorf("%w: deployment %s", ErrDeployInterrupted, depID)
+	}
+	return fmt.Errorf("%w: deployment %s did not succeed", ErrDeployFailed, depID)
+}
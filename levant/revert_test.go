@@ -0,0 +1,79 @@
+package levant
+
+import (
+	"testing"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/helper"
+)
+
+func TestRevert_resolveRevertVersion(t *testing.T) {
+
+	version := func(v uint64, stable bool) *nomad.Job {
+		return &nomad.Job{Version: helper.Uint64ToPtr(v), Stable: helper.BoolToPtr(stable)}
+	}
+
+	// Nomad returns the versions newest first.
+	versions := []*nomad.Job{version(3, false), version(2, false), version(1, true), version(0, true)}
+
+	cases := []struct {
+		Name      string
+		Versions  []*nomad.Job
+		ToVersion *uint64
+		Expected  uint64
+		Error     bool
+	}{
+		{Name: "latest stable", Versions: versions, Expected: 1},
+		{Name: "current stable", Versions: []*nomad.Job{version(1, true), version(0, true)}, Expected: 0},
+		{Name: "requested", Versions: versions, ToVersion: helper.Uint64ToPtr(2), Expected: 2},
+		{Name: "requested current", Versions: versions, ToVersion: helper.Uint64ToPtr(3), Error: true},
+		{Name: "requested missing", Versions: versions, ToVersion: helper.Uint64ToPtr(7), Error: true},
+		{Name: "no stable", Versions: []*nomad.Job{version(1, false), version(0, false)}, Error: true},
+		{Name: "no versions", Error: true},
+	}
+
+	for _, tc := range cases {
+		current, target, err := resolveRevertVersion(tc.Versions, tc.ToVersion)
+		if tc.Error {
+			if err == nil {
+				t.Fatalf("%s: expected error", tc.Name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.Name, err)
+		}
+		if *current.Version != *tc.Versions[0].Version {
+			t.Fatalf("%s: got current version %d, expected %d", tc.Name, *current.Version, *tc.Versions[0].Version)
+		}
+		if *target.Version != tc.Expected {
+			t.Fatalf("%s: got target version %d, expected %d", tc.Name, *target.Version, tc.Expected)
+		}
+	}
+}
+
+func TestRevert_watchStrategy(t *testing.T) {
+
+	group := func(maxParallel int) *nomad.TaskGroup {
+		return &nomad.TaskGroup{Update: &nomad.UpdateStrategy{MaxParallel: helper.IntToPtr(maxParallel)}}
+	}
+
+	// Reverted jobs are watched using the same strategy as deployed jobs,
+	// including the job level update stanza and the max_parallel default.
+	cases := []struct {
+		Name     string
+		Job      *nomad.Job
+		Expected string
+	}{
+		{"service with update", &nomad.Job{Type: helper.StringToPtr(nomad.JobTypeService), TaskGroups: []*nomad.TaskGroup{group(0), group(1)}}, watchStrategyDeployment},
+		{"service without update", &nomad.Job{Type: helper.StringToPtr(nomad.JobTypeService), TaskGroups: []*nomad.TaskGroup{{}, group(0)}}, watchStrategyJobStatus},
+		{"service with job update", &nomad.Job{Type: helper.StringToPtr(nomad.JobTypeService), Update: &nomad.UpdateStrategy{}, TaskGroups: []*nomad.TaskGroup{{}}}, watchStrategyDeployment},
+		{"batch", &nomad.Job{Type: helper.StringToPtr(nomad.JobTypeBatch), TaskGroups: []*nomad.TaskGroup{group(1)}}, watchStrategyJobStatus},
+	}
+
+	for _, tc := range cases {
+		if got := watchStrategy(tc.Job); got != tc.Expected {
+			t.Fatalf("%s: got %s, expected %s", tc.Name, got, tc.Expected)
+		}
+	}
+}
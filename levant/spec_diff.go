@@ -0,0 +1,320 @@
+package levant
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	nomad "github.com/hashicorp/nomad/api"
+)
+
+// specDiffContext is the number of unchanged lines included around each
+// change within the unified diff.
+const specDiffContext = 3
+
+// The operations making up a line diff.
+const (
+	diffOpEqual = iota
+	diffOpDelete
+	diffOpInsert
+)
+
+type diffLine struct {
+	op   int
+	text string
+}
+
+// writeSpecDiff writes a unified diff between the job specification of the
+// running job and that of the rendered job to the configured file. A job
+// which is not registered is diffed against an empty specification. The
+// values of fields matching the redact patterns are replaced in both, and
+// the file is only readable by its owner as it may still hold secrets set
+// within the job.
+func (lp *levantPlan) writeSpecDiff() error {
+
+	job := lp.config.Template.Job
+
+	var running []byte
+
+	rJob, _, err := lp.jobs.Info(*job.ID, nil)
+	switch {
+	case err != nil && strings.Contains(err.Error(), "404"):
+		lp.logger().Info().Msg("levant/plan: job is not registered, diffing against an empty specification")
+	case err != nil:
+		lp.logger().Error().Err(err).Msg("levant/plan: unable to query running job for spec diff")
+		return err
+	default:
+		if running, err = indentedJobSpec(rJob, lp.config.Plan.Redact); err != nil {
+			return err
+		}
+	}
+
+	rendered, err := indentedJobSpec(job, lp.config.Plan.Redact)
+	if err != nil {
+		return err
+	}
+
+	diff := unifiedDiff("running/"+*job.ID, "rendered/"+*job.ID, string(running), string(rendered))
+
+	if err = ioutil.WriteFile(lp.config.Plan.SpecDiffFile, []byte(diff), 0600); err != nil {
+		return fmt.Errorf("unable to write job spec diff: %v", err)
+	}
+
+	lp.logger().Info().Msgf("levant/plan: job spec diff written to %s", lp.config.Plan.SpecDiffFile)
+	return nil
+}
+
+// indentedJobSpec returns the canonical JSON representation of the job, as
+// compared when checking for specification changes, indented so that each
+// field is on its own line and redacted as by redactJob.
+func indentedJobSpec(job *nomad.Job, redact []string) ([]byte, error) {
+
+	raw, err := canonicalizeJob(job)
+	if err != nil {
+		return nil, err
+	}
+
+	if raw, err = redactJSON(raw, redact); err != nil {
+		return nil, err
+	}
+	return append(raw, '\n'), nil
+}
+
+// unifiedDiff returns the unified diff of the two texts labelled with the
+// passed names. An empty string is returned if the texts are the same.
+func unifiedDiff(fromName, toName, from, to string) string {
+
+	lines := diffLines(splitLines(from), splitLines(to))
+
+	var out strings.Builder
+
+	// Walk the diff finding each change and extending the hunk to include
+	// the surrounding context, merging hunks whose context overlaps.
+	for i := 0; i < len(lines); {
+		if lines[i].op == diffOpEqual {
+			i++
+			continue
+		}
+
+		start := i - specDiffContext
+		if start < 0 {
+			start = 0
+		}
+
+		end := i
+		for end < len(lines) {
+			if lines[end].op != diffOpEqual {
+				end++
+				continue
+			}
+			next := end
+			for next < len(lines) && lines[next].op == diffOpEqual {
+				next++
+			}
+			if next == len(lines) || next-end > 2*specDiffContext {
+				end += specDiffContext
+				if end > len(lines) {
+					end = len(lines)
+				}
+				break
+			}
+			end = next
+		}
+
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		writeHunk(&out, lines, start, end)
+		i = end
+	}
+
+	return out.String()
+}
+
+// writeHunk writes the lines between start and end as a single hunk.
+func writeHunk(out *strings.Builder, lines []diffLine, start, end int) {
+
+	// Calculate the line numbers, which are 1 based, of the hunk within
+	// each text.
+	fromLine, toLine := 1, 1
+	for _, l := range lines[:start] {
+		if l.op != diffOpInsert {
+			fromLine++
+		}
+		if l.op != diffOpDelete {
+			toLine++
+		}
+	}
+
+	var fromCount, toCount int
+	var body strings.Builder
+
+	for _, l := range lines[start:end] {
+		switch l.op {
+		case diffOpEqual:
+			fromCount++
+			toCount++
+			body.WriteString(" " + l.text + "\n")
+		case diffOpDelete:
+			fromCount++
+			body.WriteString("-" + l.text + "\n")
+		case diffOpInsert:
+			toCount++
+			body.WriteString("+" + l.text + "\n")
+		}
+	}
+
+	// An empty range is identified by the line before it.
+	if fromCount == 0 {
+		fromLine--
+	}
+	if toCount == 0 {
+		toLine--
+	}
+
+	fmt.Fprintf(out, "@@ -%d,%d +%d,%d @@\n%s", fromLine, fromCount, toLine, toCount, body.String())
+}
+
+// diffLines returns the operations transforming the lines of a into those of
+// b, using the shortest edit script found by the linear space variant of
+// Myers' algorithm.
+func diffLines(a, b []string) []diffLine {
+	return appendDiff(nil, a, b)
+}
+
+// appendDiff appends the operations transforming the lines of a into those
+// of b. The common prefix and suffix, which for a job specification is most
+// of the text, are removed before searching for the middle of the edit
+// script and diffing either side of it.
+func appendDiff(out []diffLine, a, b []string) []diffLine {
+
+	var prefix int
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	for _, l := range a[:prefix] {
+		out = append(out, diffLine{op: diffOpEqual, text: l})
+	}
+	a, b = a[prefix:], b[prefix:]
+
+	var suffix int
+	for suffix < len(a) && suffix < len(b) && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	common := a[len(a)-suffix:]
+	a, b = a[:len(a)-suffix], b[:len(b)-suffix]
+
+	if x, y := middleSnake(a, b); x < 0 {
+		for _, l := range a {
+			out = append(out, diffLine{op: diffOpDelete, text: l})
+		}
+		for _, l := range b {
+			out = append(out, diffLine{op: diffOpInsert, text: l})
+		}
+	} else {
+		out = appendDiff(out, a[:x], b[:y])
+		out = appendDiff(out, a[x:], b[y:])
+	}
+
+	for _, l := range common {
+		out = append(out, diffLine{op: diffOpEqual, text: l})
+	}
+	return out
+}
+
+// middleSnake searches forwards from the start and backwards from the end of
+// both lines at once, returning the point at which the paths of the shortest
+// edit script meet so the lines either side can be diffed separately. Only
+// the furthest point reached along each diagonal is held, so the space used
+// is linear in the number of lines. When either is empty, or the lines have
+// no point in common, -1 is returned.
+func middleSnake(a, b []string) (int, int) {
+
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return -1, -1
+	}
+
+	maxD := (n + m + 1) / 2
+	offset := maxD + 1
+	fwd := make([]int, 2*offset+1)
+	rev := make([]int, 2*offset+1)
+	for i := range fwd {
+		fwd[i], rev[i] = -1, -1
+	}
+	fwd[offset+1], rev[offset+1] = 0, 0
+
+	delta := n - m
+	odd := delta%2 != 0
+
+	// The diagonals which have run off the edge of the edit graph are
+	// trimmed from either end of the search.
+	var fStart, fEnd, rStart, rEnd int
+
+	for d := 0; d < maxD; d++ {
+		for k := -d + fStart; k <= d-fEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && fwd[offset+k-1] < fwd[offset+k+1]) {
+				x = fwd[offset+k+1]
+			} else {
+				x = fwd[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			fwd[offset+k] = x
+
+			switch {
+			case x > n:
+				fEnd += 2
+			case y > m:
+				fStart += 2
+			case odd:
+				if rk := offset + delta - k; rk >= 0 && rk < len(rev) && rev[rk] != -1 && x >= n-rev[rk] {
+					return x, y
+				}
+			}
+		}
+
+		for k := -d + rStart; k <= d-rEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && rev[offset+k-1] < rev[offset+k+1]) {
+				x = rev[offset+k+1]
+			} else {
+				x = rev[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[n-x-1] == b[m-y-1] {
+				x++
+				y++
+			}
+			rev[offset+k] = x
+
+			switch {
+			case x > n:
+				rEnd += 2
+			case y > m:
+				rStart += 2
+			case !odd:
+				if fk := offset + delta - k; fk >= 0 && fk < len(fwd) && fwd[fk] != -1 {
+					fx := fwd[fk]
+					if fx >= n-x {
+						return fx, fx - (fk - offset)
+					}
+				}
+			}
+		}
+	}
+
+	return -1, -1
+}
+
+// splitLines splits the text into lines, without the trailing newline.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
@@ -0,0 +1,218 @@
+package levant
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/helper"
+	"github.com/jrasell/levant/levant/structs"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func TestSpecDiff_unifiedDiff(t *testing.T) {
+
+	cases := []struct {
+		Name     string
+		From     string
+		To       string
+		Expected string
+	}{
+		{
+			Name: "no changes",
+			From: "a\nb\nc\n",
+			To:   "a\nb\nc\n",
+		},
+		{
+			Name:     "single change",
+			From:     "a\nb\nc\nd\ne\nf\ng\nh\n",
+			To:       "a\nb\nc\nd\nE\nf\ng\nh\n",
+			Expected: "--- from\n+++ to\n@@ -2,7 +2,7 @@\n b\n c\n d\n-e\n+E\n f\n g\n h\n",
+		},
+		{
+			Name: "separate hunks",
+			From: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			To:   "0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n12\n",
+			Expected: "--- from\n+++ to\n@@ -1,3 +1,4 @@\n+0\n 1\n 2\n 3\n" +
+				"@@ -8,5 +9,4 @@\n 8\n 9\n 10\n-11\n 12\n",
+		},
+		{
+			Name:     "empty from",
+			From:     "",
+			To:       "a\nb\n",
+			Expected: "--- from\n+++ to\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+	}
+
+	for _, tc := range cases {
+		if out := unifiedDiff("from", "to", tc.From, tc.To); out != tc.Expected {
+			t.Fatalf("case %s: expected:\n%s\ngot:\n%s", tc.Name, tc.Expected, out)
+		}
+	}
+}
+
+func TestSpecDiff_diffLines(t *testing.T) {
+
+	// lcsLen returns the length of the longest common subsequence, which
+	// the number of unchanged lines of the shortest edit script equals.
+	lcsLen := func(a, b []string) int {
+		prev := make([]int, len(b)+1)
+		for i := range a {
+			cur := make([]int, len(b)+1)
+			for j := range b {
+				switch {
+				case a[i] == b[j]:
+					cur[j+1] = prev[j] + 1
+				case prev[j+1] > cur[j]:
+					cur[j+1] = prev[j+1]
+				default:
+					cur[j+1] = cur[j]
+				}
+			}
+			prev = cur
+		}
+		return prev[len(b)]
+	}
+
+	r := rand.New(rand.NewSource(1))
+	lines := func(n int) []string {
+		out := make([]string, n)
+		for i := range out {
+			out[i] = string(rune('a' + r.Intn(4)))
+		}
+		return out
+	}
+
+	for i := 0; i < 500; i++ {
+		a, b := lines(r.Intn(12)), lines(r.Intn(12))
+
+		var from, to []string
+		var equal int
+		for _, l := range diffLines(a, b) {
+			if l.op != diffOpInsert {
+				from = append(from, l.text)
+			}
+			if l.op != diffOpDelete {
+				to = append(to, l.text)
+			}
+			if l.op == diffOpEqual {
+				equal++
+			}
+		}
+
+		if strings.Join(from, "") != strings.Join(a, "") || strings.Join(to, "") != strings.Join(b, "") {
+			t.Fatalf("case %d: diff of %v and %v does not transform one into the other", i, a, b)
+		}
+		if l := lcsLen(a, b); equal != l {
+			t.Fatalf("case %d: diff of %v and %v has %d unchanged lines, expected %d", i, a, b, equal, l)
+		}
+	}
+
+	// A large specification with changes at either end is diffed without
+	// holding a table of every pair of lines.
+	a := make([]string, 100000)
+	for i := range a {
+		a[i] = fmt.Sprintf("line %d", i)
+	}
+	b := append([]string{"first"}, a[1:len(a)-1]...)
+	b = append(b, "last")
+
+	var changes int
+	for _, l := range diffLines(a, b) {
+		if l.op != diffOpEqual {
+			changes++
+		}
+	}
+	if changes != 4 {
+		t.Fatalf("expected 4 changed lines, got %d", changes)
+	}
+}
+
+func TestSpecDiff_writeSpecDiff(t *testing.T) {
+
+	log.Logger = zerolog.New(ioutil.Discard)
+
+	dir, err := ioutil.TempDir("", "levant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rendered := &nomad.Job{ID: helper.StringToPtr("example"), Priority: helper.IntToPtr(60),
+		Meta: map[string]string{"db_password": "rendered-secret"}}
+	running := &nomad.Job{ID: helper.StringToPtr("example"), Priority: helper.IntToPtr(50),
+		Meta: map[string]string{"db_password": "running-secret"}}
+
+	cases := []struct {
+		Name     string
+		Jobs     *fakeJobs
+		Contains []string
+		Error    bool
+	}{
+		{
+			Name:     "running job",
+			Jobs:     &fakeJobs{info: running},
+			Contains: []string{"--- running/example", `-  "Priority": 50,`, `+  "Priority": 60,`},
+		},
+		{
+			Name:     "job not registered",
+			Jobs:     &fakeJobs{infoErr: fmt.Errorf("Unexpected response code: 404 (job not found)")},
+			Contains: []string{"@@ -0,0 +1,", `+  "Priority": 60,`, `+    "db_password": "***"`},
+		},
+		{
+			Name:  "info error",
+			Jobs:  &fakeJobs{infoErr: fmt.Errorf("connection refused")},
+			Error: true,
+		},
+	}
+
+	for _, tc := range cases {
+		path := filepath.Join(dir, strings.Replace(tc.Name, " ", "_", -1)+".diff")
+
+		lp := &levantPlan{
+			config: &PlanConfig{
+				Plan:     &structs.PlanConfig{SpecDiffFile: path, Redact: []string{"*_password"}},
+				Template: &structs.TemplateConfig{Job: rendered},
+			},
+			jobs: tc.Jobs,
+		}
+
+		err := lp.writeSpecDiff()
+		if tc.Error {
+			if err == nil {
+				t.Fatalf("case %s: expected error", tc.Name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("case %s: unexpected error: %v", tc.Name, err)
+		}
+
+		out, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range tc.Contains {
+			if !strings.Contains(string(out), s) {
+				t.Fatalf("case %s: expected diff to contain %q, got:\n%s", tc.Name, s, out)
+			}
+		}
+		if strings.Contains(string(out), "-secret") {
+			t.Fatalf("case %s: expected redacted values within diff, got:\n%s", tc.Name, out)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Fatalf("case %s: expected diff file mode 0600, got %v", tc.Name, info.Mode().Perm())
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package levant
+
+import (
+	"fmt"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/jrasell/levant/client"
+)
+
+// jobStabilityAPI is the subset of the Nomad jobs API used when setting the
+// stability of a job version.
+type jobStabilityAPI interface {
+	Stable(jobID string, version uint64, stable bool, q *nomad.WriteOptions) (*nomad.JobStabilityResponse, *nomad.WriteMeta, error)
+}
+
+// recordJobVersion stores the version of the job created by the registration
+// so its stability can be set once the deployment finishes, even if the job
+// is registered again in the meantime.
+func (l *levantDeployment) recordJobVersion() {
+
+	job, _, err := l.nomad.Jobs().Info(*l.config.Template.Job.ID, nil)
+	if err != nil || job.Version == nil {
+		l.logger().Warn().Err(err).Msg("levant/stability: unable to query registered job version")
+		return
+	}
+
+	v := *job.Version
+	l.config.JobVersion = &v
+}
+
+// MarkJobStability sets the stability of the job version created by the
+// deployment. Stable versions are the target of Nomad auto-revert and the
+// Levant revert command.
+func MarkJobStability(config *DeployConfig, stable bool) error {
+
+	c, err := client.NewNomadTargetClient(config.Client)
+	if err != nil {
+		config.logger().Error().Msgf("levant/stability: unable to setup Levant job stability: %v", err)
+		return err
+	}
+
+	return markJobStability(c.Jobs(), config, stable)
+}
+
+func markJobStability(jobs jobStabilityAPI, config *DeployConfig, stable bool) error {
+
+	jobID := *config.Template.Job.ID
+
+	if config.JobVersion == nil {
+		config.logger().Error().Msg("levant/stability: registered job version is unknown; unable to set stability")
+		return fmt.Errorf("unable to set stability of job %s as the registered version is unknown", jobID)
+	}
+
+	if _, _, err := jobs.Stable(jobID, *config.JobVersion, stable, nil); err != nil {
+		config.logger().Error().Err(err).Msgf("levant/stability: unable to set stability of job version %d", *config.JobVersion)
+		return fmt.Errorf("unable to set stability of job %s version %d: %v", jobID, *config.JobVersion, err)
+	}
+
+	config.logger().Info().Msgf("levant/stability: marked job version %d as %s", *config.JobVersion, stabilityName(stable))
+	return nil
+}
+
+// stabilityName describes the stability for log messages.
+func stabilityName(stable bool) string {
+	if stable {
+		return "stable"
+	}
+	return "unstable"
+}
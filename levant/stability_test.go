@@ -0,0 +1,69 @@
+package levant
+
+import (
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/helper"
+	"github.com/jrasell/levant/levant/structs"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// fakeStability records the stability requests made.
+type fakeStability struct {
+	version uint64
+	stable  *bool
+	err     error
+}
+
+func (f *fakeStability) Stable(jobID string, version uint64, stable bool, q *nomad.WriteOptions) (*nomad.JobStabilityResponse, *nomad.WriteMeta, error) {
+	f.version = version
+	f.stable = &stable
+	return &nomad.JobStabilityResponse{}, &nomad.WriteMeta{}, f.err
+}
+
+func TestStability_markJobStability(t *testing.T) {
+
+	log.Logger = zerolog.New(ioutil.Discard)
+
+	v := uint64(4)
+
+	cases := []struct {
+		Name    string
+		Version *uint64
+		Stable  bool
+		Err     error
+		Error   bool
+	}{
+		{Name: "stable", Version: &v, Stable: true},
+		{Name: "unstable", Version: &v, Stable: false},
+		{Name: "unknown version", Stable: true, Error: true},
+		{Name: "api error", Version: &v, Stable: true, Err: fmt.Errorf("permission denied"), Error: true},
+	}
+
+	for _, tc := range cases {
+		jobs := &fakeStability{err: tc.Err}
+		config := &DeployConfig{
+			Deploy:     &structs.DeployConfig{},
+			Template:   &structs.TemplateConfig{Job: &nomad.Job{ID: helper.StringToPtr("example")}},
+			JobVersion: tc.Version,
+		}
+
+		err := markJobStability(jobs, config, tc.Stable)
+		if tc.Error {
+			if err == nil {
+				t.Fatalf("case %s: expected error", tc.Name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("case %s: unexpected error: %v", tc.Name, err)
+		}
+		if jobs.stable == nil || *jobs.stable != tc.Stable || jobs.version != v {
+			t.Fatalf("case %s: expected version %d marked %t, got %d %v", tc.Name, v, tc.Stable, jobs.version, jobs.stable)
+		}
+	}
+}
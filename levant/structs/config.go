@@ -1,12 +1,22 @@
 package structs
 
-import nomad "github.com/hashicorp/nomad/api"
+import (
+	"context"
+	"net/http"
+	"time"
+
+	nomad "github.com/hashicorp/nomad/api"
+)
 
 const (
 	// JobIDContextField is the logging context feild added when interacting
 	// with jobs.
 	JobIDContextField = "job_id"
 
+	// TargetContextField is the logging context field added when deploying
+	// to one of several targets.
+	TargetContextField = "target"
+
 	// ScalingDirectionOut represents a scaling out event; adding to the total number.
 	ScalingDirectionOut = "Out"
 
@@ -18,15 +28,38 @@ const (
 
 	// ScalingDirectionTypePercent means the scale event will use a percentage of current change.
 	ScalingDirectionTypePercent = "Percent"
+
+	// PlanFormatLog logs each change identified by the plan as a single line.
+	PlanFormatLog = "log"
+
+	// PlanFormatTree outputs the changes identified by the plan as an indented
+	// tree of the groups, tasks, objects and fields.
+	PlanFormatTree = "tree"
+
+	// PlanFormatGrouped outputs the changes identified by the plan grouped
+	// into sections by their impact, with destructive changes first.
+	PlanFormatGrouped = "grouped"
+
+	// PlanSummaryAdditions reports only the task groups and tasks added by
+	// the plan, ignoring any changes to existing fields.
+	PlanSummaryAdditions = "additions"
 )
 
 // DeployConfig is the main struct used to configure and run a Levant deployment on
 // a given target job.
 type DeployConfig struct {
+	// BatchTimeout bounds the time Levant waits for the allocations of a batch
+	// job to complete. A value of zero waits indefinitely.
+	BatchTimeout time.Duration
+
 	// Canary enables canary autopromote and is the value in seconds to wait
 	// until attempting to perform autopromote.
 	Canary int
 
+	// CancelOnInterrupt fails the running deployment when Levant receives an
+	// interrupt or terminate signal while watching it.
+	CancelOnInterrupt bool
+
 	// Force is a boolean flag that can be used to force a deployment
 	// even though levant didn't detect any changes.
 	Force bool
@@ -39,10 +72,61 @@ type DeployConfig struct {
 	// and force the count based on the rendered job file.
 	ForceCount bool
 
+	// FailOnBlockedEval causes the deployment to fail if the registration
+	// evaluation is blocked due to the cluster lacking the resources to place
+	// all allocations. The allocations placed and stopped by the evaluation
+	// are also reported.
+	FailOnBlockedEval bool
+
+	// HealthyGrace is the time the allocations of a successful deployment
+	// must remain healthy before the deployment is considered successful. A
+	// value of zero does not watch the allocations after the deployment.
+	HealthyGrace time.Duration
+
+	// KeepRendered enables writing the rendered job, as submitted to Nomad, to
+	// disk so that it can be inspected after the deployment.
+	KeepRendered bool
+
+	// KeepRenderedAlways writes the rendered job on every deployment rather
+	// than only when the deployment fails.
+	KeepRenderedAlways bool
+
+	// KeepRenderedPath is the file path the rendered job is written to. If
+	// this is empty a temporary file is used.
+	KeepRenderedPath string
+
+	// MarkStable, when set, marks the job version created by a successful
+	// deployment as stable or unstable, regardless of the stability set by
+	// Nomad.
+	MarkStable *bool
+
+	// Message is a note attached to the job version created by the deployment
+	// as a version tag, on Nomad clusters which support them.
+	Message string
+
 	// EnvVault is a boolean flag that can be used to enable reading the VAULT_TOKEN
 	// from the enviromment.
 	EnvVault bool
 
+	// Readiness is the URL polled for each task, in the form group.task,
+	// once the allocations of the job have been placed. The URL may contain
+	// placeholders resolved from the network of each allocation, and the
+	// deployment is only successful once it returns a 2xx status.
+	Readiness map[string]string
+
+	// ReadinessTimeout bounds the time Levant waits for the readiness URLs
+	// of the tasks to return a 2xx status.
+	ReadinessTimeout time.Duration
+
+	// SystemTimeout bounds the time Levant waits for the allocations of a
+	// system job to become healthy on all eligible nodes. A value of zero
+	// waits indefinitely.
+	SystemTimeout time.Duration
+
+	// VaultRenew enables the periodic renewal of the Vault token through the
+	// Vault API for the duration of the deployment.
+	VaultRenew bool
+
 	// VaultToken is a string with the vault token.
 	VaultToken string
 }
@@ -57,17 +141,108 @@ type ClientConfig struct {
 	// ConsulAddr is the Consul API address to use for all calls.
 	ConsulAddr string
 
+	// Region is the Nomad region targeted by calls made during a deployment
+	// or plan. The region of the environment or agent is used when empty.
+	Region string
+
+	// Namespace is the Nomad namespace targeted by calls made during a
+	// deployment or plan. The namespace of the environment, or the default
+	// namespace, is used when empty.
+	Namespace string
+
 	// AllowStale sets consistency level for nomad query
 	// https://www.nomadproject.io/api/index.html#consistency-modes
 	AllowStale bool
+
+	// Headers are the custom HTTP headers added to every Nomad API request,
+	// such as an Authorization header required by an auth proxy in front of
+	// Nomad.
+	Headers http.Header
+
+	// Context, when set, cancels the Nomad and Consul requests made by the
+	// clients created from the config once done, such as one carrying the
+	// deadline of the whole command.
+	Context context.Context
 }
 
 // PlanConfig contains any configuration options that are specific to running a
 // Nomad plan.
 type PlanConfig struct {
+	// AcceptNoDiff allows a plan with no scheduler changes to succeed. If the
+	// job specification still differs from the running job, for example due
+	// to fields not included in the scheduler diff, the job is registered.
+	AcceptNoDiff bool
+
+	// CountFromRunning copies the count of each task group of the running job
+	// into the rendered job before planning, so the plan reflects the counts
+	// which will be deployed.
+	CountFromRunning bool
+
+	// DiffContext includes the unchanged fields of edited objects within the
+	// plan output so changes can be reviewed within their surrounding config.
+	DiffContext bool
+
+	// FailOnDestructive causes the plan to fail if any of the changes will
+	// force allocations to be destroyed and recreated.
+	FailOnDestructive bool
+
+	// FailOnPlacementFailure causes the plan to fail if it indicates any of
+	// the task groups can not be placed, such as due to constraints or
+	// exhausted resources.
+	FailOnPlacementFailure bool
+
+	// Format is the format used to output the changes identified by the plan;
+	// one of PlanFormatLog, PlanFormatTree or PlanFormatGrouped. Empty uses
+	// PlanFormatLog.
+	Format string
+
+	// IgnoreCountChanges ignores changes to the count of task groups within
+	// the plan, such as those caused by autoscaling.
+	IgnoreCountChanges bool
+
+	// IgnoreFields lists the fields, in the form objName:fieldName, whose
+	// changes are not logged or counted as changes to the job.
+	IgnoreFields []string
+
 	// IgnoreNoChanges is used to allow operators to force Levant to exit cleanly
 	// even if there are no changes found during the plan.
 	IgnoreNoChanges bool
+
+	// MaxPlanDepth is the maximum depth of nested objects walked when logging
+	// the plan diff. A value of zero uses the default.
+	MaxPlanDepth int
+
+	// NoChangesExitCode, when set, is the exit code used when the plan does
+	// not detect any changes. It takes precedence over IgnoreNoChanges.
+	NoChangesExitCode *int
+
+	// Optional skips the plan, continuing as if changes were found, when the
+	// Nomad plan endpoint is restricted or not supported. Other plan errors
+	// still cause the plan to fail.
+	Optional bool
+
+	// Redact lists the glob patterns, such as *_PASSWORD, of the fields whose
+	// old and new values are replaced within the plan output, and whose
+	// values are replaced within the rendered job logged by ShowJob.
+	Redact []string
+
+	// ShowJob logs the rendered job before the plan is run. The Vault token
+	// is always redacted.
+	ShowJob bool
+
+	// Since, when set, logs the changes between the rendered job and the
+	// most recent version of the job submitted before the duration ago, in
+	// addition to the changes identified by the plan.
+	Since time.Duration
+
+	// SpecDiffFile, when set, is the file path a unified diff between the
+	// specification of the running job and the rendered job is written to
+	// before the plan is run.
+	SpecDiffFile string
+
+	// Summary, when set, limits the plan output to a summary of the changes.
+	// PlanSummaryAdditions reports only the added task groups and tasks.
+	Summary string
 }
 
 // TemplateConfig contains all the job templating configuration options including
@@ -76,6 +251,19 @@ type TemplateConfig struct {
 	// Job represents the Nomad Job definition that will be deployed.
 	Job *nomad.Job
 
+	// Canary, when set, overrides the canary count of the update stanza of
+	// each task group within the rendered job. A value of zero disables
+	// canaries.
+	Canary *int
+
+	// Images overrides the Docker image of tasks within the rendered job,
+	// keyed on the group and task name in the form group.task.
+	Images map[string]string
+
+	// Priority overrides the priority of the rendered job when set to a value
+	// greater than zero.
+	Priority int
+
 	// TemplateFile is the job specification template which will be rendered
 	// before being deployed to the cluster.
 	TemplateFile string
@@ -108,4 +296,8 @@ type ScaleConfig struct {
 
 	// TaskGroup is the Nomad job taskgroup which has been selected for scaling.
 	TaskGroup string
+
+	// Wait causes the scaling event to wait for the registration evaluation to
+	// complete, failing if the evaluation is blocked.
+	Wait bool
 }
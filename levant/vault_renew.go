@@ -0,0 +1,217 @@
+package levant
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// defaultVaultAddr is the Vault API address used when VAULT_ADDR is not
+	// set, matching the default of the Vault CLI.
+	defaultVaultAddr = "https://127.0.0.1:8200"
+
+	// vaultRenewMinWait is the shortest time waited between attempts to renew
+	// the Vault token, so a failing renewal close to expiry does not retry in
+	// a tight loop.
+	vaultRenewMinWait = time.Second
+)
+
+// vaultTokenRenewer periodically renews a Vault token for the duration of a
+// deployment so a long watch does not outlive the token lease.
+type vaultTokenRenewer struct {
+	client    *http.Client
+	addr      string
+	token     string
+	namespace string
+
+	// minWait is the shortest time waited between renewal attempts.
+	minWait time.Duration
+}
+
+// vaultTokenResponse is the subset of the Vault token lookup and renew
+// responses used to schedule renewals.
+type vaultTokenResponse struct {
+	Auth *struct {
+		LeaseDuration int  `json:"lease_duration"`
+		Renewable     bool `json:"renewable"`
+	} `json:"auth"`
+	Data *struct {
+		TTL       int  `json:"ttl"`
+		Renewable bool `json:"renewable"`
+	} `json:"data"`
+}
+
+// newVaultTokenRenewer sets up the renewer for the token using the Vault
+// address, TLS configuration and namespace from the environment variables
+// used by the Vault CLI.
+func newVaultTokenRenewer(token string) (*vaultTokenRenewer, error) {
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		addr = defaultVaultAddr
+	}
+
+	client, err := vaultHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return &vaultTokenRenewer{
+		client:    client,
+		addr:      strings.TrimSuffix(addr, "/"),
+		token:     token,
+		namespace: os.Getenv("VAULT_NAMESPACE"),
+		minWait:   vaultRenewMinWait,
+	}, nil
+}
+
+// vaultHTTPClient builds the HTTP client used to call Vault, configuring TLS
+// from the VAULT_CACERT, VAULT_CAPATH, VAULT_CLIENT_CERT, VAULT_CLIENT_KEY,
+// VAULT_TLS_SERVER_NAME and VAULT_SKIP_VERIFY environment variables.
+func vaultHTTPClient() (*http.Client, error) {
+
+	tlsConfig := &nomad.TLSConfig{
+		CACert:        os.Getenv("VAULT_CACERT"),
+		CAPath:        os.Getenv("VAULT_CAPATH"),
+		ClientCert:    os.Getenv("VAULT_CLIENT_CERT"),
+		ClientKey:     os.Getenv("VAULT_CLIENT_KEY"),
+		TLSServerName: os.Getenv("VAULT_TLS_SERVER_NAME"),
+	}
+	if v := os.Getenv("VAULT_SKIP_VERIFY"); v != "" {
+		insecure, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse VAULT_SKIP_VERIFY: %v", err)
+		}
+		tlsConfig.Insecure = insecure
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+
+	client := &http.Client{Transport: transport, Timeout: 30 * time.Second}
+	if err := nomad.ConfigureTLS(client, tlsConfig); err != nil {
+		return nil, fmt.Errorf("unable to configure Vault TLS: %v", err)
+	}
+	return client, nil
+}
+
+// startVaultTokenRenewal renews the Vault token of the deployment in the
+// background until the returned stop function is called.
+func startVaultTokenRenewal(token string) (stop func()) {
+	r, err := newVaultTokenRenewer(token)
+	if err != nil {
+		log.Warn().Err(err).Msg("levant/vault_renew: unable to setup Vault client, the token will not be renewed")
+		return func() {}
+	}
+
+	stopCh := make(chan interface{})
+	go r.run(stopCh)
+	return func() { close(stopCh) }
+}
+
+// run renews the token each time half of its remaining TTL has passed until
+// the stop channel is closed. Failed renewals are logged and retried until
+// the token expires, at which point renewal stops.
+func (r *vaultTokenRenewer) run(stopCh chan interface{}) {
+
+	ttl, renewable, err := r.lookup()
+	if err != nil {
+		log.Warn().Err(err).Msg("levant/vault_renew: unable to lookup Vault token, it will not be renewed")
+		return
+	}
+	if !renewable || ttl == 0 {
+		log.Info().Msg("levant/vault_renew: Vault token does not have a renewable lease, it will not be renewed")
+		return
+	}
+
+	expiry := time.Now().Add(ttl)
+	log.Info().Msgf("levant/vault_renew: renewing Vault token which expires in %v", ttl)
+
+	for {
+		wait := time.Until(expiry) / 2
+		if wait < r.minWait {
+			wait = r.minWait
+		}
+
+		select {
+		case <-stopCh:
+			return
+		case <-time.After(wait):
+		}
+
+		ttl, err := r.renew()
+		if err != nil {
+			if !time.Now().Before(expiry) {
+				log.Warn().Err(err).Msg("levant/vault_renew: Vault token has expired and could not be renewed")
+				return
+			}
+			log.Warn().Err(err).Msgf("levant/vault_renew: unable to renew Vault token, retrying until it expires in %v",
+				time.Until(expiry).Round(time.Second))
+			continue
+		}
+
+		expiry = time.Now().Add(ttl)
+		log.Debug().Msgf("levant/vault_renew: renewed Vault token which now expires in %v", ttl)
+	}
+}
+
+// lookup returns the remaining TTL of the token and whether it can be
+// renewed.
+func (r *vaultTokenRenewer) lookup() (time.Duration, bool, error) {
+	resp, err := r.request(http.MethodGet, "/v1/auth/token/lookup-self")
+	if err != nil {
+		return 0, false, err
+	}
+	if resp.Data == nil {
+		return 0, false, fmt.Errorf("Vault token lookup response did not include the token data")
+	}
+	return time.Duration(resp.Data.TTL) * time.Second, resp.Data.Renewable, nil
+}
+
+// renew renews the token, returning the TTL of the renewed lease.
+func (r *vaultTokenRenewer) renew() (time.Duration, error) {
+	resp, err := r.request(http.MethodPost, "/v1/auth/token/renew-self")
+	if err != nil {
+		return 0, err
+	}
+	if resp.Auth == nil {
+		return 0, fmt.Errorf("Vault token renew response did not include the token lease")
+	}
+	return time.Duration(resp.Auth.LeaseDuration) * time.Second, nil
+}
+
+// request performs a call against the Vault token API using the token.
+func (r *vaultTokenRenewer) request(method, path string) (*vaultTokenResponse, error) {
+	req, err := http.NewRequest(method, r.addr+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", r.token)
+	if r.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", r.namespace)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response code from Vault: %d", resp.StatusCode)
+	}
+
+	out := &vaultTokenResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,164 @@
+package levant
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func vaultTestServer(t *testing.T, renewable bool, renewCode int, renews *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "s.token" {
+			t.Errorf("expected Vault token header, got %q", r.Header.Get("X-Vault-Token"))
+		}
+
+		switch r.URL.Path {
+		case "/v1/auth/token/lookup-self":
+			if renewable {
+				w.Write([]byte(`{"data": {"ttl": 1, "renewable": true}}`))
+			} else {
+				w.Write([]byte(`{"data": {"ttl": 0, "renewable": false}}`))
+			}
+		case "/v1/auth/token/renew-self":
+			atomic.AddInt32(renews, 1)
+			w.WriteHeader(renewCode)
+			w.Write([]byte(`{"auth": {"lease_duration": 1, "renewable": true}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestVaultRenew_run(t *testing.T) {
+
+	log.Logger = zerolog.New(ioutil.Discard)
+
+	cases := []struct {
+		Name      string
+		Renewable bool
+		RenewCode int
+		Stops     bool
+		MinRenews int32
+	}{
+		{Name: "renewed", Renewable: true, RenewCode: http.StatusOK, MinRenews: 2},
+		{Name: "renew failure", Renewable: true, RenewCode: http.StatusForbidden, Stops: true, MinRenews: 2},
+		{Name: "not renewable", Stops: true},
+	}
+
+	for _, tc := range cases {
+		var renews int32
+		srv := vaultTestServer(t, tc.Renewable, tc.RenewCode, &renews)
+
+		r := &vaultTokenRenewer{client: srv.Client(), addr: srv.URL, token: "s.token", minWait: 10 * time.Millisecond}
+		stopCh := make(chan interface{})
+		doneCh := make(chan interface{})
+		go func() {
+			r.run(stopCh)
+			close(doneCh)
+		}()
+
+		// A failed renewal is retried until the token expires, after which
+		// the renewer stops by itself.
+		select {
+		case <-doneCh:
+			if !tc.Stops {
+				t.Fatalf("%s: expected renewal to continue until stopped", tc.Name)
+			}
+		case <-time.After(1500 * time.Millisecond):
+			if tc.Stops {
+				t.Fatalf("%s: expected renewal to stop", tc.Name)
+			}
+			close(stopCh)
+			<-doneCh
+		}
+		srv.Close()
+
+		if n := atomic.LoadInt32(&renews); n < tc.MinRenews {
+			t.Fatalf("%s: expected at least %d renewals, got %d", tc.Name, tc.MinRenews, n)
+		}
+	}
+}
+
+func TestVaultRenew_newVaultTokenRenewer(t *testing.T) {
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Namespace") != "team" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"data": {"ttl": 60, "renewable": true}}`))
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "levant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	caFile := filepath.Join(dir, "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := ioutil.WriteFile(caFile, ca, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name  string
+		Env   map[string]string
+		Setup bool
+		Error bool
+	}{
+		{Name: "ca cert", Env: map[string]string{"VAULT_CACERT": caFile}, Setup: true},
+		{Name: "skip verify", Env: map[string]string{"VAULT_SKIP_VERIFY": "true"}, Setup: true},
+		{Name: "unknown authority", Env: map[string]string{}, Setup: true, Error: true},
+		{Name: "invalid skip verify", Env: map[string]string{"VAULT_SKIP_VERIFY": "maybe"}},
+		{Name: "missing client key", Env: map[string]string{"VAULT_CLIENT_CERT": caFile}},
+	}
+
+	vars := []string{"VAULT_ADDR", "VAULT_NAMESPACE", "VAULT_CACERT", "VAULT_SKIP_VERIFY", "VAULT_CLIENT_CERT"}
+	for _, tc := range cases {
+		for _, v := range vars {
+			os.Unsetenv(v)
+		}
+		os.Setenv("VAULT_ADDR", srv.URL)
+		os.Setenv("VAULT_NAMESPACE", "team")
+		for k, v := range tc.Env {
+			os.Setenv(k, v)
+		}
+
+		r, err := newVaultTokenRenewer("s.token")
+		if !tc.Setup {
+			if err == nil {
+				t.Fatalf("%s: expected setup error", tc.Name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected setup error: %v", tc.Name, err)
+		}
+
+		ttl, _, err := r.lookup()
+		if tc.Error {
+			if err == nil {
+				t.Fatalf("%s: expected lookup error", tc.Name)
+			}
+			continue
+		}
+		if err != nil || ttl != time.Minute {
+			t.Fatalf("%s: expected ttl of 1m, got %v and error %v", tc.Name, ttl, err)
+		}
+	}
+
+	for _, v := range vars {
+		os.Unsetenv(v)
+	}
+}
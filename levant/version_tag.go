@@ -0,0 +1,83 @@
+package levant
+
+import (
+	"fmt"
+	"net/url"
+
+	version "github.com/hashicorp/go-version"
+	nomad "github.com/hashicorp/nomad/api"
+)
+
+// minVersionTagVersion is the first Nomad version supporting job version tags.
+var minVersionTagVersion = version.Must(version.NewVersion("1.9.0"))
+
+// versionTagRequest is the body of the Nomad job version tag request. It is
+// not available within the vendored Nomad API.
+type versionTagRequest struct {
+	Version     uint64
+	Description string
+}
+
+// tagJobVersion attaches the deploy message to the job version created by the
+// registration so it is visible within the job history. Failing to tag the
+// version does not fail the deployment; clusters which do not support version
+// tags are skipped with a warning.
+func (l *levantDeployment) tagJobVersion() {
+
+	self, err := l.nomad.Agent().Self()
+	if err != nil {
+		l.logger().Warn().Err(err).Msg("levant/version_tag: unable to determine Nomad version; deploy message not recorded")
+		return
+	}
+
+	if !versionTagSupported(self) {
+		l.logger().Warn().Msgf("levant/version_tag: Nomad %s does not support job version tags; deploy message not recorded",
+			self.Member.Tags["build"])
+		return
+	}
+
+	job, _, err := l.nomad.Jobs().Info(*l.config.Template.Job.ID, nil)
+	if err != nil || job.Version == nil {
+		l.logger().Warn().Err(err).Msg("levant/version_tag: unable to query registered job version; deploy message not recorded")
+		return
+	}
+
+	name := versionTagName(*job.Version)
+	endpoint := fmt.Sprintf("/v1/job/%s/versions/%s/tag", url.PathEscape(*job.ID), url.PathEscape(name))
+	req := &versionTagRequest{Version: *job.Version, Description: l.config.Deploy.Message}
+
+	if _, err := l.nomad.Raw().Write(endpoint, req, nil, nil); err != nil {
+		l.logger().Warn().Err(err).Msgf("levant/version_tag: unable to tag job version %d; deploy message not recorded", *job.Version)
+		return
+	}
+
+	l.logger().Info().Msgf("levant/version_tag: tagged job version %d as %s with the deploy message", *job.Version, name)
+}
+
+// versionTagName returns the name of the tag applied to the job version.
+func versionTagName(v uint64) string {
+	return fmt.Sprintf("levant-v%d", v)
+}
+
+// versionTagSupported checks whether the Nomad agent version supports job
+// version tags.
+func versionTagSupported(self *nomad.AgentSelf) bool {
+	v, err := version.NewVersion(self.Member.Tags["build"])
+	if err != nil {
+		return false
+	}
+
+	// Compare only the version segments so prereleases and enterprise builds
+	// of a supported version are also supported.
+	seg := v.Segments64()
+	min := minVersionTagVersion.Segments64()
+	for i := range min {
+		if i >= len(seg) {
+			return false
+		}
+		if seg[i] != min[i] {
+			return seg[i] > min[i]
+		}
+	}
+	return true
+}
@@ -0,0 +1,32 @@
+package levant
+
+import (
+	"testing"
+
+	nomad "github.com/hashicorp/nomad/api"
+)
+
+func TestVersionTag_versionTagSupported(t *testing.T) {
+
+	cases := []struct {
+		Build    string
+		Expected bool
+	}{
+		{Build: "1.9.0", Expected: true},
+		{Build: "1.10.2", Expected: true},
+		{Build: "2.0.0", Expected: true},
+		{Build: "1.9.0-beta.1", Expected: true},
+		{Build: "1.9.3+ent", Expected: true},
+		{Build: "1.8.4", Expected: false},
+		{Build: "0.10.2", Expected: false},
+		{Build: "", Expected: false},
+		{Build: "unknown", Expected: false},
+	}
+
+	for _, tc := range cases {
+		self := &nomad.AgentSelf{Member: nomad.AgentMember{Tags: map[string]string{"build": tc.Build}}}
+		if got := versionTagSupported(self); got != tc.Expected {
+			t.Fatalf("build %q: got %t, expected %t", tc.Build, got, tc.Expected)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package levant
+
+import (
+	"fmt"
+	"time"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/jrasell/levant/client"
+	"github.com/jrasell/levant/levant/structs"
+	"github.com/rs/zerolog/log"
+)
+
+// JobVersion describes a single registered version of a job along with the
+// changes made from the previous version.
+type JobVersion struct {
+	Version    uint64
+	Stable     bool
+	SubmitTime time.Time
+
+	// Changes lists the location of each field changed from the previous
+	// version. It is empty for the first version of the job.
+	Changes []string
+}
+
+// TriggerVersions queries Nomad for all versions of the job, newest first,
+// including a summary of the changes each version introduced.
+func TriggerVersions(job string, clientConfig *structs.ClientConfig) ([]*JobVersion, error) {
+
+	c, err := client.NewNomadTargetClient(clientConfig)
+	if err != nil {
+		log.Error().Msgf("levant/versions: unable to setup Levant versions: %v", err)
+		return nil, err
+	}
+
+	jobs, diffs, _, err := c.Jobs().Versions(job, true, &nomad.QueryOptions{AllowStale: clientConfig.AllowStale})
+	if err != nil {
+		return nil, fmt.Errorf("unable to query versions of job %s: %v", job, err)
+	}
+
+	return jobVersions(jobs, diffs), nil
+}
+
+// jobVersions builds the version summaries from the Nomad versions response.
+// Nomad returns the versions newest first with the diff at each index
+// describing the change from the next, older, version.
+func jobVersions(jobs []*nomad.Job, diffs []*nomad.JobDiff) []*JobVersion {
+
+	var versions []*JobVersion
+
+	for i, j := range jobs {
+		v := &JobVersion{}
+
+		if j.Version != nil {
+			v.Version = *j.Version
+		}
+		if j.Stable != nil {
+			v.Stable = *j.Stable
+		}
+		if j.SubmitTime != nil {
+			v.SubmitTime = time.Unix(0, *j.SubmitTime)
+		}
+
+		if i < len(diffs) && diffs[i] != nil {
+			lp := &levantPlan{}
+			lp.collectDiff(diffs[i])
+
+			for _, c := range lp.changes {
+				v.Changes = append(v.Changes, c.path())
+			}
+		}
+
+		versions = append(versions, v)
+	}
+
+	return versions
+}
@@ -0,0 +1,70 @@
+package levant
+
+import (
+	"reflect"
+	"testing"
+
+	nomad "github.com/hashicorp/nomad/api"
+)
+
+func TestVersions_jobVersions(t *testing.T) {
+
+	v0, v1 := uint64(0), uint64(1)
+	stable, unstable := true, false
+	submit := int64(1583971200000000000)
+
+	jobs := []*nomad.Job{
+		{Version: &v1, Stable: &unstable, SubmitTime: &submit},
+		{Version: &v0, Stable: &stable, SubmitTime: &submit},
+	}
+	diffs := []*nomad.JobDiff{
+		{
+			Type: diffTypeEdited,
+			Fields: []*nomad.FieldDiff{
+				{Type: diffTypeEdited, Name: "Priority", Old: "50", New: "60"},
+			},
+			TaskGroups: []*nomad.TaskGroupDiff{
+				{
+					Type: diffTypeEdited,
+					Name: "cache",
+					Tasks: []*nomad.TaskDiff{
+						{
+							Type: diffTypeEdited,
+							Name: "redis",
+							Objects: []*nomad.ObjectDiff{
+								{
+									Type: diffTypeEdited,
+									Name: "Config",
+									Fields: []*nomad.FieldDiff{
+										{Type: diffTypeEdited, Name: "image", Old: "redis:3.2", New: "redis:4.0"},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	versions := jobVersions(jobs, diffs)
+	if len(versions) != 2 {
+		t.Fatalf("got %d versions, expected 2", len(versions))
+	}
+
+	if versions[0].Version != 1 || versions[0].Stable {
+		t.Fatalf("unexpected latest version: %+v", versions[0])
+	}
+	if versions[0].SubmitTime.UnixNano() != submit {
+		t.Fatalf("got submit time %v, expected %v", versions[0].SubmitTime.UnixNano(), submit)
+	}
+
+	expected := []string{"Job:Priority", "group cache task redis Config:image"}
+	if !reflect.DeepEqual(versions[0].Changes, expected) {
+		t.Fatalf("got changes %v, expected %v", versions[0].Changes, expected)
+	}
+
+	if versions[1].Version != 0 || !versions[1].Stable || versions[1].Changes != nil {
+		t.Fatalf("unexpected first version: %+v", versions[1])
+	}
+}
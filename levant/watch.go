@@ -0,0 +1,92 @@
+package levant
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/jrasell/levant/client"
+	"github.com/jrasell/levant/levant/structs"
+	"github.com/rs/zerolog/log"
+)
+
+// TriggerWatch provides the main entry point into a Levant watch and is used
+// to setup the clients before watching an existing deployment until it
+// completes. The returned error wraps ErrDeployFailed or ErrDeployInterrupted.
+func TriggerWatch(depID string, clientConfig *structs.ClientConfig, cancelOnInterrupt bool) error {
+
+	client, err := client.NewNomadTargetClient(clientConfig)
+	if err != nil {
+		log.Error().Msgf("levant/watch: unable to setup Levant watch: %v", err)
+		return fmt.Errorf("%w: %v", ErrDeployFailed, err)
+	}
+
+	dep := &levantDeployment{}
+	dep.nomad = client
+	dep.config = &DeployConfig{
+		Client:   clientConfig,
+		Deploy:   &structs.DeployConfig{CancelOnInterrupt: cancelOnInterrupt},
+		Template: &structs.TemplateConfig{},
+	}
+
+	log.Info().Msgf("levant/watch: beginning deployment watcher for deployment %s", depID)
+
+	if dep.deploymentWatcher(depID) {
+		return nil
+	}
+	if dep.interrupted {
+		return fmt.Errorf("%w: deployment %s", ErrDeployInterrupted, depID)
+	}
+	return fmt.Errorf("%w: deployment %s did not succeed", ErrDeployFailed, depID)
+}
+
+// interruptWatch is called when the deployment watcher receives a signal. It
+// logs the current state of the deployment and how to resume watching it, and
+// fails the deployment if the operator has asked for this.
+func (l *levantDeployment) interruptWatch(depID string, sig os.Signal) {
+
+	l.interrupted = true
+	l.logger().Warn().Msgf("levant/deploy: received %v, stopping watch of deployment %s", sig, depID)
+
+	if l.config.Deploy.CancelOnInterrupt {
+		if _, _, err := l.nomad.Deployments().Fail(depID, nil); err != nil {
+			l.logger().Error().Err(err).Msgf("levant/deploy: unable to cancel deployment %s", depID)
+		} else {
+			l.logger().Info().Msgf("levant/deploy: deployment %s has been cancelled", depID)
+			return
+		}
+	}
+
+	dep, _, err := l.nomad.Deployments().Info(depID, &nomad.QueryOptions{AllowStale: l.config.Client.AllowStale})
+	if err != nil {
+		l.logger().Error().Err(err).Msgf("levant/deploy: unable to get info of deployment %s", depID)
+	} else {
+		for _, s := range deploymentSummary(dep) {
+			l.logger().Info().Msgf("levant/deploy: %s", s)
+		}
+	}
+
+	l.logger().Info().Msgf("levant/deploy: the deployment continues in Nomad, resume watching with: levant watch %s", depID)
+}
+
+// deploymentSummary describes the status of the deployment and the progress of
+// each of its task groups, sorted by group name.
+func deploymentSummary(dep *nomad.Deployment) []string {
+
+	out := []string{fmt.Sprintf("deployment %s has status %s", dep.ID, dep.Status)}
+
+	groups := make([]string, 0, len(dep.TaskGroups))
+	for name := range dep.TaskGroups {
+		groups = append(groups, name)
+	}
+	sort.Strings(groups)
+
+	for _, name := range groups {
+		s := dep.TaskGroups[name]
+		out = append(out, fmt.Sprintf("group %s has %d desired, %d placed, %d healthy and %d unhealthy allocations",
+			name, s.DesiredTotal, s.PlacedAllocs, s.HealthyAllocs, s.UnhealthyAllocs))
+	}
+
+	return out
+}
@@ -0,0 +1,30 @@
+package levant
+
+import (
+	"reflect"
+	"testing"
+
+	nomad "github.com/hashicorp/nomad/api"
+)
+
+func TestWatch_deploymentSummary(t *testing.T) {
+
+	dep := &nomad.Deployment{
+		ID:     "abc",
+		Status: jobStatusRunning,
+		TaskGroups: map[string]*nomad.DeploymentState{
+			"web":   {DesiredTotal: 3, PlacedAllocs: 2, HealthyAllocs: 1},
+			"cache": {DesiredTotal: 1, PlacedAllocs: 1, HealthyAllocs: 1},
+		},
+	}
+
+	expected := []string{
+		"deployment abc has status running",
+		"group cache has 1 desired, 1 placed, 1 healthy and 0 unhealthy allocations",
+		"group web has 3 desired, 2 placed, 1 healthy and 0 unhealthy allocations",
+	}
+
+	if out := deploymentSummary(dep); !reflect.DeepEqual(out, expected) {
+		t.Fatalf("expected %v but got %v", expected, out)
+	}
+}
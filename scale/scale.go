@@ -14,23 +14,44 @@ type Config struct {
 	Scale  *structs.ScaleConfig
 }
 
+// Result details the outcome of a scaling event.
+type Result struct {
+	// JobID is the Nomad job which was scaled.
+	JobID string
+
+	// EvalID is the evaluation created when the scaled job was registered.
+	EvalID string
+
+	// Groups contains the count changes for each scaled task group.
+	Groups []*GroupResult
+}
+
+// GroupResult details the count change of a single task group.
+type GroupResult struct {
+	Group         string
+	PreviousCount int
+	NewCount      int
+}
+
 // TriggerScalingEvent provides the exported entry point into performing a job
 // scale based on user inputs.
-func TriggerScalingEvent(config *Config) bool {
+func TriggerScalingEvent(config *Config) (*Result, bool) {
 
 	// Add the JobID as a log context field.
 	log.Logger = log.With().Str(structs.JobIDContextField, config.Scale.JobID).Logger()
 
-	nomadClient, err := client.NewNomadClient(config.Client.Addr)
+	nomadClient, err := client.NewNomadTargetClient(config.Client)
 	if err != nil {
 		log.Error().Msg("levant/scale: unable to setup Levant scaling event")
-		return false
+		return nil, false
 	}
 
-	job := updateJob(nomadClient, config)
+	res := &Result{JobID: config.Scale.JobID}
+
+	job := updateJob(nomadClient, config, res)
 	if job == nil {
 		log.Error().Msg("levant/scale: unable to perform job count update")
-		return false
+		return nil, false
 	}
 
 	// Setup a deployment object, as a scaling event is a deployment and should
@@ -38,25 +59,27 @@ func TriggerScalingEvent(config *Config) bool {
 	deploymentConfig := &levant.DeployConfig{}
 	deploymentConfig.Template = &structs.TemplateConfig{Job: job}
 	deploymentConfig.Client = config.Client
-	deploymentConfig.Deploy = &structs.DeployConfig{ForceCount: true}
+	deploymentConfig.Deploy = &structs.DeployConfig{
+		ForceCount:        true,
+		FailOnBlockedEval: config.Scale.Wait,
+	}
 
 	log.Info().Msg("levant/scale: job will now be deployed with updated counts")
 
 	// Trigger a deployment of the updated job which results in the scaling of
 	// the job and will go through all the deployment tracking until an end
 	// state is reached.
-	success := levant.TriggerDeployment(deploymentConfig, nomadClient)
-	if !success {
-		return false
-	}
+	err = levant.TriggerDeployment(deploymentConfig, nomadClient)
+	res.EvalID = deploymentConfig.EvalID
 
-	return true
+	return res, err == nil
 }
 
 // updateJob gathers information on the current state of the running job and
 // along with the user defined input updates the in-memory job specification
-// to reflect the desired scaled state.
-func updateJob(client *nomad.Client, config *Config) *nomad.Job {
+// to reflect the desired scaled state. Each scaled group is recorded within the
+// passed result.
+func updateJob(client *nomad.Client, config *Config, res *Result) *nomad.Job {
 
 	job, _, err := client.Jobs().Info(config.Scale.JobID, nil)
 	if err != nil {
@@ -79,14 +102,14 @@ func updateJob(client *nomad.Client, config *Config) *nomad.Job {
 			if *group.Name == config.Scale.TaskGroup {
 				log.Debug().Msgf("levant/scale: scaling action to be requested on taskgroup %s only",
 					config.Scale.TaskGroup)
-				updateTaskGroup(config, group)
+				res.Groups = append(res.Groups, updateTaskGroup(config, group))
 			}
 
 			// If no taskgroup has been specified, all found will have their
 			// count updated.
 		} else {
 			log.Debug().Msg("levant/scale: scaling action requested on all taskgroups")
-			updateTaskGroup(config, group)
+			res.Groups = append(res.Groups, updateTaskGroup(config, group))
 		}
 	}
 
@@ -95,10 +118,12 @@ func updateJob(client *nomad.Client, config *Config) *nomad.Job {
 
 // updateTaskGroup is tasked with performing the count update based on the user
 // configuration when a group is identified as being marked for scaling.
-func updateTaskGroup(config *Config, group *nomad.TaskGroup) {
+func updateTaskGroup(config *Config, group *nomad.TaskGroup) *GroupResult {
 
 	var c int
 
+	res := &GroupResult{Group: *group.Name, PreviousCount: *group.Count}
+
 	// If a percentage scale value has been passed, we must convert this to an
 	// int which represents the count to scale by as Nomad job submissions must
 	// be done with group counts as desired ints.
@@ -125,6 +150,9 @@ func updateTaskGroup(config *Config, group *nomad.TaskGroup) {
 			*group.Name, *group.Count, nc)
 		*group.Count = nc
 	}
+
+	res.NewCount = *group.Count
+	return res
 }
 
 // calculateCountBasedOnPercent is a small helper function to turn a percentage
@@ -42,11 +42,15 @@ func TestScale_updateTaskGroup(t *testing.T) {
 	}
 
 	for _, tc := range cases {
-		updateTaskGroup(tc.Config, tc.Group)
+		startCount := *tc.Group.Count
+		res := updateTaskGroup(tc.Config, tc.Group)
 
 		if tc.EndCount != *tc.Group.Count {
 			t.Fatalf("got: %#v, expected %#v", *tc.Group.Count, tc.EndCount)
 		}
+		if res.PreviousCount != startCount || res.NewCount != tc.EndCount {
+			t.Fatalf("got result: %#v, expected previous %v and new %v", res, startCount, tc.EndCount)
+		}
 	}
 }
 
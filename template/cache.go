@@ -0,0 +1,80 @@
+package template
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// parseCache holds the parsed job templates of the local files rendered by
+// the process, keyed on path, so a template rendered repeatedly, such as for
+// each environment of a matrix, is only parsed once. An entry is only used
+// while the modification time and size of the file, and the names of the
+// functions available to it, are unchanged.
+var parseCache = struct {
+	sync.Mutex
+	entries map[string]*parseCacheEntry
+}{entries: make(map[string]*parseCacheEntry)}
+
+// parseCacheEntry is the parse of a template file along with the state of the
+// file and functions it was parsed with.
+type parseCacheEntry struct {
+	modTime time.Time
+	size    int64
+	funcs   string
+	tmpl    *template.Template
+}
+
+// cachedTemplate returns a copy of the cached parse of the file, bound to the
+// functions, or nil if the file has not been parsed with the same functions
+// since it last changed.
+func cachedTemplate(path string, info os.FileInfo, funcs template.FuncMap) *template.Template {
+
+	parseCache.Lock()
+	defer parseCache.Unlock()
+
+	e, ok := parseCache.entries[path]
+	if !ok || !e.modTime.Equal(info.ModTime()) || e.size != info.Size() || e.funcs != funcNames(funcs) {
+		return nil
+	}
+
+	tmpl, err := e.tmpl.Clone()
+	if err != nil {
+		return nil
+	}
+	return tmpl.Funcs(funcs)
+}
+
+// storeTemplate caches a copy of the parse of the file, replacing any parse
+// of an earlier version of the file.
+func storeTemplate(path string, info os.FileInfo, funcs template.FuncMap, tmpl *template.Template) {
+
+	clone, err := tmpl.Clone()
+	if err != nil {
+		return
+	}
+
+	parseCache.Lock()
+	defer parseCache.Unlock()
+
+	parseCache.entries[path] = &parseCacheEntry{
+		modTime: info.ModTime(),
+		size:    info.Size(),
+		funcs:   funcNames(funcs),
+		tmpl:    clone,
+	}
+}
+
+// funcNames returns the sorted names of the functions, as the functions
+// available when parsing determine whether the parse succeeds.
+func funcNames(funcs template.FuncMap) string {
+	names := make([]string, 0, len(funcs))
+	for name := range funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,69 @@
+package template
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/jrasell/levant/helper"
+	"github.com/rs/zerolog/log"
+)
+
+// variableExplanation describes the source which provided the final value of
+// a variable and the sources it overrode.
+type variableExplanation struct {
+	Key        string
+	Value      interface{}
+	Source     string
+	Overridden []string
+}
+
+// explainVariables logs each variable with the source which provided its
+// final value, along with any sources it overrode, so the variable precedence
+// can be debugged. It is run before the template is parsed so that it is
+// logged even if rendering fails. The file variables are named by the files
+// which provided them, in the order they were merged.
+func (t *tmpl) explainVariables(vars map[string]interface{}, origins, fileOrigins map[string][]string) {
+	for _, e := range explainVariables(vars, origins, fileOrigins) {
+
+		var overrides string
+		if len(e.Overridden) > 0 {
+			overrides = ", overriding " + strings.Join(e.Overridden, ", ")
+		}
+
+		log.Info().
+			Str("key", e.Key).
+			Str("source", e.Source).
+			Msgf("template/render: variable %s is %v from %s%s", e.Key, e.Value, e.Source, overrides)
+	}
+}
+
+// explainVariables explains each of the merged variables using the origins
+// recorded by helper.VariableMergeOrdered, naming the file source by each of
+// the files which set the variable. The explanations are sorted by key.
+func explainVariables(vars map[string]interface{}, origins, fileOrigins map[string][]string) []*variableExplanation {
+
+	out := make([]*variableExplanation, 0, len(origins))
+
+	for k, sources := range origins {
+		var names []string
+		for _, s := range sources {
+			files, ok := fileOrigins[k]
+			if !ok || s != helper.VarSourceFile {
+				names = append(names, s)
+				continue
+			}
+			for _, f := range files {
+				names = append(names, s+" "+f)
+			}
+		}
+
+		e := &variableExplanation{Key: k, Value: vars[k], Source: names[len(names)-1]}
+		if len(names) > 1 {
+			e.Overridden = names[:len(names)-1]
+		}
+		out = append(out, e)
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
@@ -5,33 +5,45 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/url"
 	"os"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"text/template"
 	"time"
+	"unicode"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ssm"
 	consul "github.com/hashicorp/consul/api"
+	nomad "github.com/hashicorp/nomad/api"
 	"github.com/rs/zerolog/log"
 )
 
-// funcMap builds the template functions and passes the consulClient where this
-// is required.
-func funcMap(consulClient *consul.Client) template.FuncMap {
+// funcMap builds the template functions and passes the consulClient and
+// nomadClient where this is required.
+func funcMap(consulClient *consul.Client, nomadClient *nomad.Client) template.FuncMap {
 	return template.FuncMap{
 		"consulKey":          consulKeyFunc(consulClient),
 		"consulKeyExists":    consulKeyExistsFunc(consulClient),
 		"consulKeyOrDefault": consulKeyOrDefaultFunc(consulClient),
 		"env":                envFunc(),
 		"fileContents":       fileContents(),
+		"hclBlock":           hclBlock,
+		"hclList":            hclList,
 		"loop":               loop,
+		"nomadVar":           nomadVarFunc(nomadClient),
 		"parseBool":          parseBool,
 		"parseFloat":         parseFloat,
 		"parseInt":           parseInt,
 		"parseJSON":          parseJSON,
 		"parseUint":          parseUint,
 		"replace":            replace,
+		"ssmParam":           ssmParamFunc(),
 		"timeNow":            timeNowFunc,
 		"timeNowUTC":         timeNowUTCFunc,
 		"timeNowTimezone":    timeNowTimezoneFunc(),
@@ -119,6 +131,202 @@ func consulKeyOrDefaultFunc(consulClient *consul.Client) func(string, string) (s
 	}
 }
 
+// nomadVariable is the subset of a Nomad Variables API response used when
+// rendering.
+type nomadVariable struct {
+	Items map[string]string
+}
+
+// nomadVarFunc reads a single key of a variable within the Nomad Variables
+// store. The region and namespace of the Nomad client, which default to
+// NOMAD_REGION and NOMAD_NAMESPACE, are used. Values are never logged as they
+// are typically secrets.
+func nomadVarFunc(nomadClient *nomad.Client) func(string, string) (string, error) {
+	return func(path, key string) (string, error) {
+
+		path = strings.Trim(path, "/")
+		if path == "" || key == "" {
+			return "", errors.New("nomadVar requires both a variable path and key")
+		}
+
+		v := &nomadVariable{}
+		if _, err := nomadClient.Raw().Query("/v1/var/"+escapeVarPath(path), v, nil); err != nil {
+			return "", nomadVarError(path, err)
+		}
+
+		val, ok := v.Items[key]
+		if !ok {
+			return "", fmt.Errorf("Nomad variable %s does not contain key %s", path, key)
+		}
+
+		log.Info().Msgf("template/funcs: using Nomad variable %s key %s", path, key)
+		return val, nil
+	}
+}
+
+// escapeVarPath escapes each segment of the variable path so that characters
+// such as ? and # are sent as part of the path rather than ending it.
+func escapeVarPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// nomadVarError converts errors from the Nomad Variables API into clearer
+// errors. A 404 response indicates either the variable does not exist or, on
+// Nomad versions before 1.4, that the Variables API is not available.
+func nomadVarError(path string, err error) error {
+	if !strings.Contains(err.Error(), "Unexpected response code: 404") {
+		return fmt.Errorf("unable to read Nomad variable %s: %v", path, err)
+	}
+	if strings.Contains(err.Error(), "variable not found") {
+		return fmt.Errorf("Nomad variable %s not found", path)
+	}
+	return fmt.Errorf("unable to read Nomad variable %s, Nomad variables require Nomad 1.4 or later: %v", path, err)
+}
+
+// hclList renders a list variable using HCL list syntax, such as the job
+// datacenters. Strings are quoted while numbers and booleans are not. A string
+// is treated as a comma separated list so lists can also be passed using the
+// -var flag.
+func hclList(v interface{}) (string, error) {
+
+	var items []string
+
+	switch val := v.(type) {
+	case nil:
+	case string:
+		for _, s := range strings.Split(val, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				items = append(items, strconv.Quote(s))
+			}
+		}
+	case []string:
+		for _, s := range val {
+			items = append(items, strconv.Quote(s))
+		}
+	case []interface{}:
+		for _, e := range val {
+			item, err := hclListItem(e)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, item)
+		}
+	default:
+		return "", fmt.Errorf("hclList does not support a value of type %T", v)
+	}
+
+	return "[" + strings.Join(items, ", ") + "]", nil
+}
+
+// hclListItem renders a single hclList element.
+func hclListItem(v interface{}) (string, error) {
+	switch e := v.(type) {
+	case string:
+		return strconv.Quote(e), nil
+	case bool, int, int64, uint64, float64:
+		return fmt.Sprint(e), nil
+	default:
+		return "", fmt.Errorf("hclList does not support list elements of type %T", v)
+	}
+}
+
+// hclBlock renders a map variable as an HCL block of the given name, such as
+// a constraint, with an attribute for each key in sorted order. A list of maps
+// renders a block for each map so repeated stanzas can be templated from a
+// list within a variable file. Nested maps and lists of maps are rendered as
+// nested blocks and lists of scalars using hclList.
+func hclBlock(name string, v interface{}) (string, error) {
+
+	var b strings.Builder
+	if err := writeHCLBlocks(&b, name, v, ""); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+// writeHCLBlocks writes the map, or each map within the list, as a block.
+func writeHCLBlocks(b *strings.Builder, name string, v interface{}, indent string) error {
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		return writeHCLBlock(b, name, val, indent)
+	case []interface{}:
+		for _, e := range val {
+			m, ok := e.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("hclBlock does not support list elements of type %T", e)
+			}
+			if err := writeHCLBlock(b, name, m, indent); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("hclBlock does not support a value of type %T", v)
+	}
+}
+
+// writeHCLBlock writes a single block containing the map attributes.
+func writeHCLBlock(b *strings.Builder, name string, m map[string]interface{}, indent string) error {
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintf(b, "%s%s {\n", indent, hclKey(name))
+
+	for _, k := range keys {
+		switch val := m[k].(type) {
+		case nil:
+			continue
+		case map[string]interface{}:
+			if err := writeHCLBlock(b, k, val, indent+"  "); err != nil {
+				return err
+			}
+		case []interface{}:
+			if len(val) > 0 {
+				if _, ok := val[0].(map[string]interface{}); ok {
+					if err := writeHCLBlocks(b, k, val, indent+"  "); err != nil {
+						return err
+					}
+					continue
+				}
+			}
+			list, err := hclList(val)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(b, "%s  %s = %s\n", indent, hclKey(k), list)
+		default:
+			item, err := hclListItem(val)
+			if err != nil {
+				return fmt.Errorf("hclBlock does not support attribute %s of type %T", k, val)
+			}
+			fmt.Fprintf(b, "%s  %s = %s\n", indent, hclKey(k), item)
+		}
+	}
+
+	fmt.Fprintf(b, "%s}\n", indent)
+	return nil
+}
+
+// hclKey returns the key as an HCL identifier, quoting it if it contains
+// characters which are not valid within one.
+func hclKey(k string) string {
+	for _, r := range k {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' {
+			return strconv.Quote(k)
+		}
+	}
+	return k
+}
+
 func loop(ints ...int64) (<-chan int64, error) {
 	var start, stop int64
 	switch len(ints) {
@@ -207,6 +415,46 @@ func replace(input, from, to string) string {
 	return strings.Replace(input, from, to, -1)
 }
 
+func ssmParamFunc() func(string) (string, error) {
+
+	// The AWS client is only setup when the function is first used, so that
+	// templates which do not use SSM do not need AWS credentials.
+	var client *ssm.SSM
+
+	return func(s string) (string, error) {
+
+		if len(s) == 0 {
+			return "", nil
+		}
+
+		if client == nil {
+			sess, err := session.NewSessionWithOptions(session.Options{
+				SharedConfigState: session.SharedConfigEnable,
+			})
+			if err != nil {
+				return "", err
+			}
+			client = ssm.New(sess)
+		}
+
+		out, err := client.GetParameter(&ssm.GetParameterInput{
+			Name:           aws.String(s),
+			WithDecryption: aws.Bool(true),
+		})
+		if err != nil {
+			if aErr, ok := err.(awserr.Error); ok && aErr.Code() == ssm.ErrCodeParameterNotFound {
+				return "", fmt.Errorf("SSM parameter %s not found", s)
+			}
+			return "", err
+		}
+
+		// Never log the value as SSM parameters regularly contain secrets.
+		log.Info().Msgf("template/funcs: using SSM parameter with name %s", s)
+
+		return aws.StringValue(out.Parameter.Value), nil
+	}
+}
+
 func timeNowFunc() string {
 	return time.Now().Format("2006-01-02T15:04:05Z07:00")
 }
@@ -0,0 +1,156 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template/parse"
+)
+
+const (
+	varUnresolved = iota
+	varResolving
+	varResolved
+)
+
+// interpolateVariables resolves references to other variables, such as
+// [[ .registry ]], within string variable values before they are used to
+// render the template. Referenced variables are resolved first so references
+// can be chained; a reference cycle causes an error.
+func (t *tmpl) interpolateVariables(variables map[string]interface{}) (map[string]interface{}, error) {
+
+	out := make(map[string]interface{}, len(variables))
+	for k, v := range variables {
+		out[k] = v
+	}
+
+	state := make(map[string]int, len(out))
+	var stack []string
+
+	var resolve func(name string) error
+	resolve = func(name string) error {
+
+		switch state[name] {
+		case varResolved:
+			return nil
+		case varResolving:
+			for i, s := range stack {
+				if s == name {
+					return fmt.Errorf("variable reference cycle detected: %s",
+						strings.Join(append(stack[i:], name), " -> "))
+				}
+			}
+		}
+
+		s, ok := out[name].(string)
+		if !ok || !strings.Contains(s, leftDelim) {
+			state[name] = varResolved
+			return nil
+		}
+
+		state[name] = varResolving
+		stack = append(stack, name)
+
+		tpl, removed, err := t.newTemplate()
+		if err != nil {
+			return err
+		}
+		if tpl, err = tpl.Parse(s); err != nil {
+			return fmt.Errorf("unable to parse variable %s: %v", name, disallowedFuncError(err, removed))
+		}
+
+		for _, ref := range variableRefs(tpl.Tree.Root) {
+			if _, ok := out[ref]; !ok {
+				continue
+			}
+			if err := resolve(ref); err != nil {
+				return err
+			}
+		}
+
+		var buf bytes.Buffer
+		if err := tpl.Execute(&buf, out); err != nil {
+			return fmt.Errorf("unable to render variable %s: %v", name, err)
+		}
+		out[name] = buf.String()
+
+		stack = stack[:len(stack)-1]
+		state[name] = varResolved
+		return nil
+	}
+
+	// Resolve the variables in name order so any error is reported
+	// consistently between runs.
+	names := make([]string, 0, len(out))
+	for k := range out {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := resolve(name); err != nil {
+			return nil, err
+		}
+	}
+
+	return out, nil
+}
+
+// variableRefs returns the names of the top level variables referenced within
+// the parsed template, such as registry for both .registry and $.registry.
+func variableRefs(node parse.Node) []string {
+
+	var refs []string
+
+	var walk func(n parse.Node)
+	walk = func(n parse.Node) {
+		switch n := n.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, c := range n.Nodes {
+				walk(c)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, c := range n.Cmds {
+				walk(c)
+			}
+		case *parse.CommandNode:
+			for _, a := range n.Args {
+				walk(a)
+			}
+		case *parse.ChainNode:
+			walk(n.Node)
+		case *parse.FieldNode:
+			refs = append(refs, n.Ident[0])
+		case *parse.VariableNode:
+			if len(n.Ident) > 1 && n.Ident[0] == "$" {
+				refs = append(refs, n.Ident[1])
+			}
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.TemplateNode:
+			walk(n.Pipe)
+		}
+	}
+	walk(node)
+
+	return refs
+}
@@ -0,0 +1,85 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/rs/zerolog/log"
+)
+
+// RenderJobs takes in a template and variables performing a render of the
+// template followed by a Nomad jobspec parse of each job it contains. This
+// supports documents holding multiple job blocks, such as the output of Nomad
+// Pack, with the jobs returned in the order they are defined.
+func RenderJobs(templateFile string, variableFiles []string, addr string, flagVars *map[string]string, opts *RenderOptions) ([]*nomad.Job, error) {
+	tpl, err := RenderTemplate(templateFile, variableFiles, addr, flagVars, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := splitJobs(tpl.String())
+	if len(docs) > 1 {
+		log.Debug().Msgf("template/render: found %d jobs within the template", len(docs))
+	}
+
+	var jobs []*nomad.Job
+	seen := make(map[string]struct{}, len(docs))
+
+	for _, doc := range docs {
+		job, err := parseJob(bytes.NewBufferString(doc), opts)
+		if err != nil {
+			return nil, err
+		}
+
+		if job.ID != nil {
+			if _, ok := seen[*job.ID]; ok {
+				return nil, fmt.Errorf("job %s is defined more than once within the template", *job.ID)
+			}
+			seen[*job.ID] = struct{}{}
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
+// splitJobs splits the rendered document into a document for each top level
+// job block. Documents which can not be parsed as HCL1, such as those using
+// HCL2 only syntax, or which contain a single job are returned unchanged so
+// the job parse reports any errors.
+func splitJobs(src string) []string {
+
+	root, err := hcl.Parse(src)
+	if err != nil {
+		return []string{src}
+	}
+
+	list, ok := root.Node.(*ast.ObjectList)
+	if !ok {
+		return []string{src}
+	}
+
+	jobs := list.Filter("job")
+	if len(jobs.Items) < 2 {
+		return []string{src}
+	}
+
+	var docs []string
+	for _, item := range list.Items {
+		obj, ok := item.Val.(*ast.ObjectType)
+		if !ok || len(item.Keys) == 0 || item.Keys[0].Token.Value() != "job" {
+			return []string{src}
+		}
+
+		start, end := item.Keys[0].Pos().Offset, obj.Rbrace.Offset+1
+		if start < 0 || end > len(src) || start >= end {
+			return []string{src}
+		}
+		docs = append(docs, src[start:end])
+	}
+
+	return docs
+}
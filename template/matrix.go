@@ -0,0 +1,73 @@
+package template
+
+import (
+	"fmt"
+	"strings"
+)
+
+// matrixNameKey is the key within each matrix entry which names the
+// environment.
+const matrixNameKey = "name"
+
+// MatrixEnvironment is a single entry of a matrix variable. Its variables are
+// used as the OverlayVariables when rendering the template for the
+// environment.
+type MatrixEnvironment struct {
+	Name      string
+	Variables map[string]interface{}
+}
+
+// MatrixEnvironments parses the variable files and returns the environments
+// listed within the named matrix variable. The variable must be a list of
+// maps, each with a unique name key identifying the environment.
+func MatrixEnvironments(variableFiles []string, matrix string, opts *RenderOptions) ([]*MatrixEnvironment, error) {
+
+	t := newTmpl("", variableFiles, nil, opts)
+
+	variables, _, err := t.fileVariables(variableFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	v, ok := variables[matrix]
+	if !ok {
+		return nil, fmt.Errorf("matrix variable %s not found within the variable files", matrix)
+	}
+
+	return matrixEnvironments(matrix, v)
+}
+
+// matrixEnvironments validates the matrix variable value and converts each
+// entry into an environment.
+func matrixEnvironments(matrix string, v interface{}) ([]*MatrixEnvironment, error) {
+
+	entries, ok := v.([]interface{})
+	if !ok || len(entries) == 0 {
+		return nil, fmt.Errorf("matrix variable %s must be a list of variable maps", matrix)
+	}
+
+	var out []*MatrixEnvironment
+	seen := make(map[string]bool)
+
+	for i, e := range entries {
+		vars, ok := e.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("matrix variable %s entry %d must be a map of variables but is %T", matrix, i, e)
+		}
+
+		name, _ := vars[matrixNameKey].(string)
+		switch {
+		case name == "":
+			return nil, fmt.Errorf("matrix variable %s entry %d must have a %s", matrix, i, matrixNameKey)
+		case strings.ContainsAny(name, `/\`) || name == "." || name == "..":
+			return nil, fmt.Errorf("matrix variable %s environment name %q is not a valid file name", matrix, name)
+		case seen[name]:
+			return nil, fmt.Errorf("matrix variable %s environment %s is listed more than once", matrix, name)
+		}
+		seen[name] = true
+
+		out = append(out, &MatrixEnvironment{Name: name, Variables: vars})
+	}
+
+	return out, nil
+}
@@ -0,0 +1,113 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+
+	version "github.com/hashicorp/go-version"
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/jobspec"
+	"github.com/jrasell/levant/client"
+	"github.com/rs/zerolog/log"
+)
+
+// The HCL versions which can be used to parse the rendered job specification.
+const (
+	// HCLVersionAuto parses the job as HCL2 when the cluster is running Nomad
+	// 1.0 or later, and as HCL1 otherwise, including when the cluster can
+	// not be reached.
+	HCLVersionAuto = 0
+
+	// HCLVersion1 parses the job locally using the Nomad jobspec package.
+	HCLVersion1 = 1
+
+	// HCLVersion2 parses the job using the Nomad API, which requires the
+	// cluster to be running Nomad 1.0 or later.
+	HCLVersion2 = 2
+)
+
+// parseJob parses the rendered job specification using the HCL version
+// configured within the render options.
+func parseJob(tpl *bytes.Buffer, opts *RenderOptions) (*nomad.Job, error) {
+
+	hclVersion := HCLVersionAuto
+	if opts != nil {
+		hclVersion = opts.HCLVersion
+	}
+
+	switch hclVersion {
+	case HCLVersionAuto:
+		c, err := client.NewNomadTargetClient(opts.nomadClientConfig())
+		if err == nil {
+			var build string
+			if build, err = nomadBuild(c); err == nil && hcl2Supported(build) {
+				return parseJobHCL2(c, build, tpl.String())
+			}
+			if err == nil {
+				err = fmt.Errorf("Nomad %s does not support HCL2 jobs", build)
+			}
+		}
+		log.Debug().Err(err).Msg("template/parse: parsing job as HCL1")
+		return parseJobHCL1(tpl)
+
+	case HCLVersion1:
+		return parseJobHCL1(tpl)
+
+	case HCLVersion2:
+		c, err := client.NewNomadTargetClient(opts.nomadClientConfig())
+		if err != nil {
+			return nil, err
+		}
+		build, err := nomadBuild(c)
+		if err != nil {
+			return nil, fmt.Errorf("unable to determine Nomad version for HCL2 parsing: %v", err)
+		}
+		if !hcl2Supported(build) {
+			return nil, fmt.Errorf("unable to parse job as HCL2: Nomad %s does not support HCL2 jobs; use HCL version %d",
+				build, HCLVersion1)
+		}
+		return parseJobHCL2(c, build, tpl.String())
+	}
+
+	return nil, fmt.Errorf("unsupported HCL version %d; must be %d or %d", hclVersion, HCLVersion1, HCLVersion2)
+}
+
+// parseJobHCL1 parses the job specification locally as HCL1.
+func parseJobHCL1(tpl *bytes.Buffer) (*nomad.Job, error) {
+	job, err := jobspec.Parse(tpl)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse job as HCL1: %v", err)
+	}
+	return job, nil
+}
+
+// nomadBuild returns the build version of the Nomad agent.
+func nomadBuild(c *nomad.Client) (string, error) {
+	self, err := c.Agent().Self()
+	if err != nil {
+		return "", err
+	}
+	return self.Member.Tags["build"], nil
+}
+
+// parseJobHCL2 parses the job specification as HCL2 using the Nomad API of a
+// cluster whose build version has been checked to support HCL2 jobs.
+func parseJobHCL2(c *nomad.Client, build, jobHCL string) (*nomad.Job, error) {
+
+	log.Debug().Msgf("template/parse: parsing job as HCL2 using Nomad %s", build)
+
+	job, err := c.Jobs().ParseHCL(jobHCL, false)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse job as HCL2: %v", err)
+	}
+	return job, nil
+}
+
+// hcl2Supported checks whether the Nomad build version parses HCL2 jobs.
+func hcl2Supported(build string) bool {
+	v, err := version.NewVersion(build)
+	if err != nil {
+		return false
+	}
+	return v.Segments64()[0] >= 1
+}
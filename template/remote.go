@@ -0,0 +1,115 @@
+package template
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	cleanhttp "github.com/hashicorp/go-cleanhttp"
+	"github.com/rs/zerolog/log"
+)
+
+// defaultRemoteTimeout is the time allowed to fetch a remote template or
+// variable file when no timeout has been configured.
+const defaultRemoteTimeout = 30 * time.Second
+
+// isRemoteFile checks whether the template or variable file path is an HTTP
+// or HTTPS URL rather than a local file.
+func isRemoteFile(p string) bool {
+	l := strings.ToLower(p)
+	return strings.HasPrefix(l, "http://") || strings.HasPrefix(l, "https://")
+}
+
+// fileExt returns the extension of the local file or the path of the remote
+// file URL, ignoring any query string.
+func fileExt(p string) string {
+	if isRemoteFile(p) {
+		if u, err := url.Parse(p); err == nil {
+			return path.Ext(u.Path)
+		}
+	}
+	return path.Ext(p)
+}
+
+// readFile returns the contents of the local file or fetches the remote file.
+func (t *tmpl) readFile(p string) ([]byte, error) {
+	if !isRemoteFile(p) {
+		return ioutil.ReadFile(p)
+	}
+	return t.fetchRemoteFile(p)
+}
+
+// fetchRemoteFile performs a GET of the URL, including the configured remote
+// headers, returning the body of a successful response.
+func (t *tmpl) fetchRemoteFile(rawURL string) ([]byte, error) {
+
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch %s: %v", rawURL, err)
+	}
+	req = req.WithContext(t.ctx)
+	for k, v := range t.remoteHeaders {
+		req.Header[k] = v
+	}
+
+	c := cleanhttp.DefaultClient()
+	c.Timeout = t.remoteTimeout
+	if c.Timeout == 0 {
+		c.Timeout = defaultRemoteTimeout
+	}
+
+	log.Debug().Msgf("template/remote: fetching remote file %s", rawURL)
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch %s: %v", rawURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to fetch %s: unexpected status code %d", rawURL, resp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %v", rawURL, err)
+	}
+	return body, nil
+}
+
+// localFile returns the path of a local copy of the file, fetching remote
+// files into a temporary file for parsers which only read from disk. The
+// returned function removes any temporary file.
+func (t *tmpl) localFile(p string) (string, func(), error) {
+	if !isRemoteFile(p) {
+		return p, func() {}, nil
+	}
+
+	body, err := t.fetchRemoteFile(p)
+	if err != nil {
+		return "", nil, err
+	}
+
+	f, err := ioutil.TempFile("", "levant-*"+fileExt(p))
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.Remove(f.Name()) }
+
+	if _, err := f.Write(body); err != nil {
+		f.Close()
+		cleanup()
+		return "", nil, err
+	}
+	if err := f.Close(); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	return f.Name(), cleanup, nil
+}
@@ -2,50 +2,142 @@ package template
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"path"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+	"time"
 
 	"github.com/jrasell/levant/client"
 	"github.com/jrasell/levant/helper"
+	"github.com/jrasell/levant/levant/structs"
 	"github.com/rs/zerolog/log"
 	yaml "gopkg.in/yaml.v2"
 
 	nomad "github.com/hashicorp/nomad/api"
-	"github.com/hashicorp/nomad/jobspec"
 	"github.com/hashicorp/terraform/config"
 )
 
+// RenderOptions holds the optional settings which control how a template is
+// rendered. A nil RenderOptions uses the defaults.
+type RenderOptions struct {
+
+	// VarPrecedence is the order, lowest precedence first, in which the
+	// variable sources are merged. Defaults to helper.DefaultVarPrecedence.
+	VarPrecedence []string
+
+	// AllowFuncs, when not empty, restricts the template functions available
+	// to those listed.
+	AllowFuncs []string
+
+	// DenyFuncs lists template functions which are not available.
+	DenyFuncs []string
+
+	// NomadAddr is the Nomad HTTP API address used by the nomadVar function
+	// and when parsing HCL2 jobs. The Nomad client defaults are used when
+	// empty.
+	NomadAddr string
+
+	// NomadRegion and NomadNamespace are the Nomad region and namespace used
+	// by the nomadVar function and when parsing HCL2 jobs. The Nomad client
+	// defaults, NOMAD_REGION and NOMAD_NAMESPACE, are used when empty.
+	NomadRegion    string
+	NomadNamespace string
+
+	// NomadHeaders are the custom HTTP headers added to the requests of the
+	// Nomad client, such as an Authorization header required by an auth
+	// proxy in front of Nomad.
+	NomadHeaders http.Header
+
+	// HCLVersion is the HCL version used to parse the rendered job, either
+	// HCLVersion1 or HCLVersion2. Defaults to HCLVersionAuto, which uses HCL2
+	// only when the Nomad cluster supports it.
+	HCLVersion int
+
+	// RemoteHeaders are the HTTP headers sent when fetching a template or
+	// variable file from an http(s) URL, such as an Authorization header.
+	RemoteHeaders http.Header
+
+	// RemoteTimeout is the time allowed to fetch each remote template or
+	// variable file. Defaults to 30 seconds.
+	RemoteTimeout time.Duration
+
+	// OverlayVariables are merged over the variables of the variable files,
+	// such as those of a matrix environment, before the variable precedence
+	// is applied.
+	OverlayVariables map[string]interface{}
+
+	// ExplainVars logs each variable with the source which provided its final
+	// value once the variable sources are merged, before the template is
+	// rendered.
+	ExplainVars bool
+
+	// Context, when set, cancels the Consul, Nomad and remote file requests
+	// made while rendering once done.
+	Context context.Context
+}
+
+// nomadClientConfig returns the config of the Nomad client used by the
+// nomadVar function and when parsing HCL2 jobs. A nil RenderOptions uses the
+// Nomad client defaults.
+func (o *RenderOptions) nomadClientConfig() *structs.ClientConfig {
+	if o == nil {
+		return &structs.ClientConfig{}
+	}
+	return &structs.ClientConfig{
+		Addr:      o.NomadAddr,
+		Region:    o.NomadRegion,
+		Namespace: o.NomadNamespace,
+		Headers:   o.NomadHeaders,
+		Context:   o.Context,
+	}
+}
+
 // RenderJob takes in a template and variables performing a render of the
 // template followed by Nomad jobspec parse.
-func RenderJob(templateFile string, variableFiles []string, addr string, flagVars *map[string]string) (job *nomad.Job, err error) {
+func RenderJob(templateFile string, variableFiles []string, addr string, flagVars *map[string]string, opts *RenderOptions) (job *nomad.Job, err error) {
 	var tpl *bytes.Buffer
-	tpl, err = RenderTemplate(templateFile, variableFiles, addr, flagVars)
+	tpl, err = RenderTemplate(templateFile, variableFiles, addr, flagVars, opts)
 	if err != nil {
 		return
 	}
 
-	job, err = jobspec.Parse(tpl)
+	job, err = parseJob(tpl, opts)
 	return
 }
 
 // RenderTemplate is the main entry point to render the template based on the
 // passed variables file.
-func RenderTemplate(templateFile string, variableFiles []string, addr string, flagVars *map[string]string) (tpl *bytes.Buffer, err error) {
+func RenderTemplate(templateFile string, variableFiles []string, addr string, flagVars *map[string]string, opts *RenderOptions) (tpl *bytes.Buffer, err error) {
+	tpl = &bytes.Buffer{}
+	err = RenderTemplateTo(tpl, templateFile, variableFiles, addr, flagVars, opts)
+	return
+}
 
-	t := &tmpl{}
-	t.flagVariables = flagVars
-	t.jobTemplateFile = templateFile
-	t.variableFiles = variableFiles
+// RenderTemplateTo renders the template based on the passed variables file,
+// streaming the output to w rather than buffering the entire document. If an
+// error occurs partway through rendering, w may contain partial output.
+func RenderTemplateTo(w io.Writer, templateFile string, variableFiles []string, addr string, flagVars *map[string]string, opts *RenderOptions) (err error) {
 
-	c, err := client.NewConsulClient(addr)
+	t := newTmpl(templateFile, variableFiles, flagVars, opts)
+
+	c, err := client.NewConsulClient(t.ctx, addr)
 	if err != nil {
 		return
 	}
 
 	t.consulClient = c
 
+	// Creating the Nomad client does not contact the cluster, so clusters are
+	// only queried when the template uses the nomadVar function.
+	if t.nomadClient, err = client.NewNomadTargetClient(opts.nomadClientConfig()); err != nil {
+		return
+	}
+
 	if len(variableFiles) == 0 {
 		log.Debug().Msgf("template/render: no variable file passed, trying defaults")
 		defaultVarFile := helper.GetDefaultVarFile()
@@ -56,16 +148,84 @@ func RenderTemplate(templateFile string, variableFiles []string, addr string, fl
 		}
 	}
 
+	mergedVariables, fileOrigins, err := t.fileVariables(variableFiles)
+	if err != nil {
+		return
+	}
+
+	if opts != nil {
+		for k, v := range opts.OverlayVariables {
+			mergedVariables[k] = v
+		}
+	}
+
+	// The variable sources are merged in the configured order of precedence
+	// before the template is parsed, so the variables can be explained even
+	// if rendering fails.
+	variables, origins := helper.VariableMergeOrdered(t.varPrecedence, t.variableSources(mergedVariables))
+	if t.explainVars {
+		t.explainVariables(variables, origins, fileOrigins)
+	}
+
+	tmpl, err := t.parseJobTemplate()
+	if err != nil {
+		return
+	}
+
+	// If no command line variables are passed; log this as DEBUG to provide much
+	// greater feedback.
+	if len(*t.flagVariables) == 0 {
+		log.Debug().Msgf("template/render: no command line variables passed")
+	}
+
+	return t.renderTemplate(w, tmpl, variables)
+}
+
+// newTmpl sets up the template for rendering using the passed options. A nil
+// RenderOptions uses the defaults.
+func newTmpl(templateFile string, variableFiles []string, flagVars *map[string]string, opts *RenderOptions) *tmpl {
+
+	t := &tmpl{}
+	t.flagVariables = flagVars
+	t.jobTemplateFile = templateFile
+	t.variableFiles = variableFiles
+	t.varPrecedence = helper.DefaultVarPrecedence
+	t.ctx = context.Background()
+
+	if opts != nil {
+		if len(opts.VarPrecedence) > 0 {
+			t.varPrecedence = opts.VarPrecedence
+		}
+		t.allowFuncs = opts.AllowFuncs
+		t.denyFuncs = opts.DenyFuncs
+		t.remoteHeaders = opts.RemoteHeaders
+		t.remoteTimeout = opts.RemoteTimeout
+		t.explainVars = opts.ExplainVars
+		if opts.Context != nil {
+			t.ctx = opts.Context
+		}
+	}
+
+	return t
+}
+
+// fileVariables parses and merges the variable files in order, returning the
+// merged variables along with the files which provided each of them.
+func (t *tmpl) fileVariables(variableFiles []string) (map[string]interface{}, map[string][]string, error) {
+
 	mergedVariables := make(map[string]interface{})
+	fileOrigins := make(map[string][]string)
+
 	for _, variableFile := range variableFiles {
 		// Process the variable file extension and log DEBUG so the template can be
 		// correctly rendered.
 		var ext string
-		if ext = path.Ext(variableFile); ext != "" {
+		if ext = fileExt(variableFile); ext != "" {
 			log.Debug().Msgf("template/render: variable file extension %s detected", ext)
 		}
 
 		var variables map[string]interface{}
+		var err error
 		switch ext {
 		case terraformVarExtension:
 			variables, err = t.parseTFVars(variableFile)
@@ -78,32 +238,20 @@ func RenderTemplate(templateFile string, variableFiles []string, addr string, fl
 		}
 
 		if err != nil {
-			return
+			return nil, nil, err
 		}
 		for k, v := range variables {
 			mergedVariables[k] = v
+			fileOrigins[k] = append(fileOrigins[k], variableFile)
 		}
 	}
 
-	src, err := ioutil.ReadFile(t.jobTemplateFile)
-	if err != nil {
-		return
-	}
-
-	// If no command line variables are passed; log this as DEBUG to provide much
-	// greater feedback.
-	if len(*t.flagVariables) == 0 {
-		log.Debug().Msgf("template/render: no command line variables passed")
-	}
-
-	tpl, err = t.renderTemplate(string(src), mergedVariables)
-
-	return
+	return mergedVariables, fileOrigins, nil
 }
 
 func (t *tmpl) parseJSONVars(variableFile string) (variables map[string]interface{}, err error) {
 
-	jsonFile, err := ioutil.ReadFile(variableFile)
+	jsonFile, err := t.readFile(variableFile)
 	if err != nil {
 		return
 	}
@@ -118,8 +266,16 @@ func (t *tmpl) parseJSONVars(variableFile string) (variables map[string]interfac
 
 func (t *tmpl) parseTFVars(variableFile string) (variables map[string]interface{}, err error) {
 
+	// The Terraform config loader only reads from disk, so remote variable
+	// files are fetched to a temporary file first.
+	file, cleanup, err := t.localFile(variableFile)
+	if err != nil {
+		return
+	}
+	defer cleanup()
+
 	c := &config.Config{}
-	if c, err = config.LoadFile(variableFile); err != nil {
+	if c, err = config.LoadFile(file); err != nil {
 		return
 	}
 
@@ -133,7 +289,7 @@ func (t *tmpl) parseTFVars(variableFile string) (variables map[string]interface{
 
 func (t *tmpl) parseYAMLVars(variableFile string) (variables map[string]interface{}, err error) {
 
-	yamlFile, err := ioutil.ReadFile(variableFile)
+	yamlFile, err := t.readFile(variableFile)
 	if err != nil {
 		return
 	}
@@ -143,27 +299,112 @@ func (t *tmpl) parseYAMLVars(variableFile string) (variables map[string]interfac
 		return
 	}
 
+	// The YAML decoder uses map[interface{}]interface{} for nested maps; these
+	// are converted so nested variables behave the same regardless of the
+	// variable file format.
+	for k, v := range variables {
+		variables[k] = normalizeYAMLValue(v)
+	}
+
 	return variables, nil
 }
 
-func (t *tmpl) renderTemplate(src string, variables map[string]interface{}) (tpl *bytes.Buffer, err error) {
+// normalizeYAMLValue recursively converts YAML decoded maps to use string keys
+// while preserving lists and scalar values as their native Go types.
+func normalizeYAMLValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, mv := range val {
+			out[fmt.Sprintf("%v", k)] = normalizeYAMLValue(mv)
+		}
+		return out
+	case []interface{}:
+		for i, lv := range val {
+			val[i] = normalizeYAMLValue(lv)
+		}
+		return val
+	default:
+		return val
+	}
+}
 
-	tpl = &bytes.Buffer{}
+// parseJobTemplate reads and parses the job template file. Local files are
+// parsed once per process while they are unchanged, with later renders using
+// a copy of the cached parse bound to the functions of this render.
+func (t *tmpl) parseJobTemplate() (*template.Template, error) {
 
-	// Setup the template file for rendering
-	tmpl := t.newTemplate()
-	if tmpl, err = tmpl.Parse(src); err != nil {
-		return
+	funcs, removed, err := t.templateFuncs()
+	if err != nil {
+		return nil, err
 	}
 
-	if variables != nil {
-		// Merge variables passed on the CLI with those passed through a variables file.
-		err = tmpl.Execute(tpl, helper.VariableMerge(&variables, t.flagVariables))
-	} else {
-		// No variables file passed; render using any passed CLI variables.
-		log.Debug().Msgf("template/render: variable file not passed")
-		err = tmpl.Execute(tpl, t.flagVariables)
+	// A file which can not be stat'd is read as normal so the usual error is
+	// reported, and is not cached.
+	var info os.FileInfo
+	if !isRemoteFile(t.jobTemplateFile) {
+		if info, err = os.Stat(t.jobTemplateFile); err == nil {
+			if tmpl := cachedTemplate(t.jobTemplateFile, info, funcs); tmpl != nil {
+				log.Debug().Msgf("template/render: using cached parse of template %s", t.jobTemplateFile)
+				return tmpl, nil
+			}
+		} else {
+			info = nil
+		}
 	}
 
-	return tpl, err
+	src, err := t.readFile(t.jobTemplateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := newFuncsTemplate(funcs).Parse(string(src))
+	if err != nil {
+		return nil, disallowedFuncError(err, removed)
+	}
+
+	if info != nil {
+		storeTemplate(t.jobTemplateFile, info, funcs, tmpl)
+	}
+	return tmpl, nil
+}
+
+func (t *tmpl) renderTemplate(w io.Writer, tmpl *template.Template, variables map[string]interface{}) error {
+
+	// Resolve any references to other variables within the variable values
+	// once all of the sources have been merged.
+	variables, err := t.interpolateVariables(variables)
+	if err != nil {
+		return err
+	}
+
+	return tmpl.Execute(w, variables)
+}
+
+// variableSources returns the variables of each source which can be ordered
+// using the variable precedence.
+func (t *tmpl) variableSources(fileVars map[string]interface{}) map[string]map[string]interface{} {
+
+	sources := map[string]map[string]interface{}{
+		helper.VarSourceFile: fileVars,
+		helper.VarSourceEnv:  helper.EnvVariables(),
+		helper.VarSourceFlag: make(map[string]interface{}),
+	}
+	if t.flagVariables != nil {
+		for k, v := range *t.flagVariables {
+			sources[helper.VarSourceFlag][k] = v
+		}
+	}
+	return sources
+}
+
+// disallowedFuncError identifies parse errors caused by the template using a
+// function removed by the allow or deny lists and returns a clearer error.
+func disallowedFuncError(err error, removed []string) error {
+	for _, name := range removed {
+		if strings.Contains(err.Error(), fmt.Sprintf("function %q not defined", name)) {
+			return fmt.Errorf("template function %q is not allowed: %v", name, err)
+		}
+	}
+	return err
 }
@@ -1,10 +1,24 @@
 package template
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	nomad "github.com/hashicorp/nomad/api"
+	"github.com/jrasell/levant/client"
+	"github.com/jrasell/levant/helper"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
 )
 
 const (
@@ -25,7 +39,7 @@ func TestTemplater_RenderTemplate(t *testing.T) {
 	fVars := make(map[string]string)
 
 	// Test basic TF template render.
-	job, err = RenderJob("test-fixtures/single_templated.nomad", []string{"test-fixtures/test.tf"}, "", &fVars)
+	job, err = RenderJob("test-fixtures/single_templated.nomad", []string{"test-fixtures/test.tf"}, "", &fVars, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -34,7 +48,7 @@ func TestTemplater_RenderTemplate(t *testing.T) {
 	}
 
 	// Test basic YAML template render.
-	job, err = RenderJob("test-fixtures/single_templated.nomad", []string{"test-fixtures/test.yaml"}, "", &fVars)
+	job, err = RenderJob("test-fixtures/single_templated.nomad", []string{"test-fixtures/test.yaml"}, "", &fVars, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -43,7 +57,7 @@ func TestTemplater_RenderTemplate(t *testing.T) {
 	}
 
 	// Test multiple var-files
-	job, err = RenderJob("test-fixtures/single_templated.nomad", []string{"test-fixtures/test.yaml", "test-fixtures/test-overwrite.yaml"}, "", &fVars)
+	job, err = RenderJob("test-fixtures/single_templated.nomad", []string{"test-fixtures/test.yaml", "test-fixtures/test-overwrite.yaml"}, "", &fVars, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -52,7 +66,7 @@ func TestTemplater_RenderTemplate(t *testing.T) {
 	}
 
 	// Test multiple var-files of different types
-	job, err = RenderJob("test-fixtures/single_templated.nomad", []string{"test-fixtures/test.tf", "test-fixtures/test-overwrite.yaml"}, "", &fVars)
+	job, err = RenderJob("test-fixtures/single_templated.nomad", []string{"test-fixtures/test.tf", "test-fixtures/test-overwrite.yaml"}, "", &fVars, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -62,7 +76,7 @@ func TestTemplater_RenderTemplate(t *testing.T) {
 
 	// Test multiple var-files with var-args
 	fVars["job_name"] = testJobNameOverwrite2
-	job, err = RenderJob("test-fixtures/single_templated.nomad", []string{"test-fixtures/test.tf", "test-fixtures/test-overwrite.yaml"}, "", &fVars)
+	job, err = RenderJob("test-fixtures/single_templated.nomad", []string{"test-fixtures/test.tf", "test-fixtures/test-overwrite.yaml"}, "", &fVars, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -71,7 +85,7 @@ func TestTemplater_RenderTemplate(t *testing.T) {
 	}
 
 	// Test empty var-args and empty variable file render.
-	job, err = RenderJob("test-fixtures/none_templated.nomad", []string{}, "", &fVars)
+	job, err = RenderJob("test-fixtures/none_templated.nomad", []string{}, "", &fVars, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -82,7 +96,7 @@ func TestTemplater_RenderTemplate(t *testing.T) {
 	// Test var-args only render.
 	delete(fVars, "job_name")
 	fVars["job_name"] = testJobName
-	job, err = RenderJob("test-fixtures/single_templated.nomad", []string{}, "", &fVars)
+	job, err = RenderJob("test-fixtures/single_templated.nomad", []string{}, "", &fVars, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -94,7 +108,7 @@ func TestTemplater_RenderTemplate(t *testing.T) {
 	delete(fVars, "job_name")
 	fVars["datacentre"] = testDCName
 	os.Setenv(testEnvName, testEnvValue)
-	job, err = RenderJob("test-fixtures/multi_templated.nomad", []string{"test-fixtures/test.yaml"}, "", &fVars)
+	job, err = RenderJob("test-fixtures/multi_templated.nomad", []string{"test-fixtures/test.yaml"}, "", &fVars, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -108,3 +122,679 @@ func TestTemplater_RenderTemplate(t *testing.T) {
 		t.Fatalf("expected %s but got %v", testEnvValue, *job.TaskGroups[0].Name)
 	}
 }
+
+func TestTemplater_RenderTemplateVarPrecedence(t *testing.T) {
+
+	os.Setenv(helper.EnvVarPrefix+"job_name", "levantEnv")
+	defer os.Unsetenv(helper.EnvVarPrefix + "job_name")
+
+	fVars := map[string]string{"job_name": "levantFlag"}
+
+	cases := []struct {
+		Opts     *RenderOptions
+		Expected string
+	}{
+		{nil, "levantFlag"},
+		{&RenderOptions{VarPrecedence: []string{helper.VarSourceFile, helper.VarSourceEnv, helper.VarSourceFlag}}, "levantFlag"},
+		{&RenderOptions{VarPrecedence: []string{helper.VarSourceFile, helper.VarSourceFlag, helper.VarSourceEnv}}, "levantEnv"},
+	}
+
+	for i, tc := range cases {
+		job, err := RenderJob("test-fixtures/single_templated.nomad", []string{}, "", &fVars, tc.Opts)
+		if err != nil {
+			t.Fatalf("case %d: %v", i, err)
+		}
+		if *job.Name != tc.Expected {
+			t.Fatalf("case %d: expected %s but got %v", i, tc.Expected, *job.Name)
+		}
+	}
+}
+
+func TestTemplater_RenderTemplateRestrictFuncs(t *testing.T) {
+
+	fVars := map[string]string{"job_name": testJobName}
+
+	cases := []struct {
+		Opts  *RenderOptions
+		Error string
+	}{
+		{&RenderOptions{DenyFuncs: []string{"fileContents"}}, ""},
+		{&RenderOptions{AllowFuncs: []string{"env"}}, ""},
+		{&RenderOptions{DenyFuncs: []string{"env"}}, `template function "env" is not allowed`},
+		{&RenderOptions{AllowFuncs: []string{"loop"}}, `template function "env" is not allowed`},
+		{&RenderOptions{DenyFuncs: []string{"missingFunc"}}, `template function "missingFunc" in allow or deny list does not exist`},
+	}
+
+	for i, tc := range cases {
+		_, err := RenderTemplate("test-fixtures/multi_templated.nomad", []string{"test-fixtures/test.yaml"}, "", &fVars, tc.Opts)
+		if tc.Error == "" && err != nil {
+			t.Fatalf("case %d: unexpected error: %v", i, err)
+		}
+		if tc.Error != "" && (err == nil || !strings.Contains(err.Error(), tc.Error)) {
+			t.Fatalf("case %d: expected error containing %q, got %v", i, tc.Error, err)
+		}
+	}
+}
+
+func TestTemplater_RenderTemplateRangeList(t *testing.T) {
+
+	fVars := make(map[string]string)
+
+	job, err := RenderJob("test-fixtures/range_groups.nomad", []string{"test-fixtures/services.yaml"}, "", &fVars, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		Name  string
+		Count int
+		Image string
+		Tags  []string
+	}{
+		{"web", 3, "nginx:1.17", []string{"http", "public"}},
+		{"api", 2, "api:1.0.0", []string{"http"}},
+		{"worker", 1, "worker:1.0.0", []string{}},
+	}
+
+	if len(job.TaskGroups) != len(expected) {
+		t.Fatalf("expected %v groups but got %v", len(expected), len(job.TaskGroups))
+	}
+
+	for i, e := range expected {
+		tg := job.TaskGroups[i]
+		if *tg.Name != e.Name {
+			t.Fatalf("expected group %s but got %v", e.Name, *tg.Name)
+		}
+		if *tg.Count != e.Count {
+			t.Fatalf("expected count %v for group %s but got %v", e.Count, e.Name, *tg.Count)
+		}
+		if image := tg.Tasks[0].Config["image"]; image != e.Image {
+			t.Fatalf("expected image %s for group %s but got %v", e.Image, e.Name, image)
+		}
+		if !reflect.DeepEqual(tg.Tasks[0].Services[0].Tags, e.Tags) {
+			t.Fatalf("expected tags %v for group %s but got %v", e.Tags, e.Name, tg.Tasks[0].Services[0].Tags)
+		}
+	}
+}
+
+func TestTemplater_normalizeYAMLValue(t *testing.T) {
+
+	in := map[interface{}]interface{}{
+		"resources": map[interface{}]interface{}{
+			"cpu": 250,
+			"ports": []interface{}{
+				map[interface{}]interface{}{"label": "http", "static": 80},
+			},
+		},
+	}
+
+	expected := map[string]interface{}{
+		"resources": map[string]interface{}{
+			"cpu": 250,
+			"ports": []interface{}{
+				map[string]interface{}{"label": "http", "static": 80},
+			},
+		},
+	}
+
+	if out := normalizeYAMLValue(in); !reflect.DeepEqual(out, expected) {
+		t.Fatalf("expected %#v but got %#v", expected, out)
+	}
+}
+
+func TestTemplater_nomadVar(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/var/nomad/jobs/example":
+			w.Write([]byte(`{"Path":"nomad/jobs/example","Items":{"password":"s3cr3t"}}`))
+		case "/v1/var/nomad/jobs/missing":
+			http.Error(w, "variable not found", http.StatusNotFound)
+		case "/v1/var/nomad/jobs/a?b#c":
+			w.Write([]byte(`{"Path":"nomad/jobs/a?b#c","Items":{"password":"escaped"}}`))
+		case "/v1/var/nomad/jobs/scoped":
+			if r.URL.Query().Get("region") != "eu" || r.URL.Query().Get("namespace") != "prod" {
+				http.Error(w, "variable not found", http.StatusNotFound)
+				return
+			}
+			w.Write([]byte(`{"Path":"nomad/jobs/scoped","Items":{"password":"scoped"}}`))
+		default:
+			http.Error(w, "Invalid URL", http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	c, err := client.NewNomadTargetClient((&RenderOptions{
+		NomadAddr:      srv.URL,
+		NomadRegion:    "eu",
+		NomadNamespace: "prod",
+	}).nomadClientConfig())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f := nomadVarFunc(c)
+
+	cases := []struct {
+		Path     string
+		Key      string
+		Expected string
+		Error    string
+	}{
+		{"nomad/jobs/example", "password", "s3cr3t", ""},
+		{"/nomad/jobs/example/", "password", "s3cr3t", ""},
+		{"nomad/jobs/example", "username", "", "does not contain key username"},
+		{"nomad/jobs/a?b#c", "password", "escaped", ""},
+		{"nomad/jobs/scoped", "password", "scoped", ""},
+		{"nomad/jobs/missing", "password", "", "Nomad variable nomad/jobs/missing not found"},
+		{"unsupported", "password", "", "require Nomad 1.4 or later"},
+		{"", "password", "", "requires both a variable path and key"},
+	}
+
+	for i, tc := range cases {
+		out, err := f(tc.Path, tc.Key)
+		if tc.Error == "" && err != nil {
+			t.Fatalf("case %d: unexpected error: %v", i, err)
+		}
+		if tc.Error != "" && (err == nil || !strings.Contains(err.Error(), tc.Error)) {
+			t.Fatalf("case %d: expected error containing %q, got %v", i, tc.Error, err)
+		}
+		if out != tc.Expected {
+			t.Fatalf("case %d: expected %q, got %q", i, tc.Expected, out)
+		}
+	}
+}
+
+func TestTemplater_RenderTemplateTo(t *testing.T) {
+
+	fVars := map[string]string{"job_name": testJobName}
+
+	tpl, err := RenderTemplate("test-fixtures/multi_templated.nomad", []string{"test-fixtures/test.yaml"}, "", &fVars, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err = RenderTemplateTo(&buf, "test-fixtures/multi_templated.nomad", []string{"test-fixtures/test.yaml"}, "", &fVars, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if buf.String() != tpl.String() {
+		t.Fatalf("expected streamed output to match buffered output, got %s", buf.String())
+	}
+}
+
+func TestTemplater_RenderTemplateParseCache(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "levant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "cached.nomad")
+	fVars := map[string]string{"name": "first"}
+
+	render := func() string {
+		var buf bytes.Buffer
+		if err := RenderTemplateTo(&buf, path, nil, "", &fVars, nil); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	if err := ioutil.WriteFile(path, []byte("a [[ .name ]]"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out := render(); out != "a first" {
+		t.Fatalf("expected %q, got %q", "a first", out)
+	}
+
+	parseCache.Lock()
+	_, ok := parseCache.entries[path]
+	parseCache.Unlock()
+	if !ok {
+		t.Fatalf("expected the parse of %s to be cached", path)
+	}
+
+	// A later render with different variables uses the cached parse.
+	fVars["name"] = "second"
+	if out := render(); out != "a second" {
+		t.Fatalf("expected %q, got %q", "a second", out)
+	}
+
+	// A change to the file is parsed again, even when the size is unchanged.
+	if err := ioutil.WriteFile(path, []byte("b [[ .name ]]"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if out := render(); out != "b second" {
+		t.Fatalf("expected %q, got %q", "b second", out)
+	}
+
+	// Functions denied by a later render are not available from the cache.
+	if err := ioutil.WriteFile(path, []byte(`[[ env "HOME" ]]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	render()
+	err = RenderTemplateTo(&bytes.Buffer{}, path, nil, "", &fVars, &RenderOptions{DenyFuncs: []string{"env"}})
+	if err == nil || !strings.Contains(err.Error(), `template function "env" is not allowed`) {
+		t.Fatalf("expected denied function error, got %v", err)
+	}
+}
+
+func TestTemplater_RenderTemplateHCLList(t *testing.T) {
+
+	fVars := make(map[string]string)
+
+	job, err := RenderJob("test-fixtures/multi_dc.nomad", []string{"test-fixtures/multi_dc.yaml"}, "", &fVars, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e := []string{"dc1", "dc2", "dc3"}; !reflect.DeepEqual(job.Datacenters, e) {
+		t.Fatalf("expected datacenters %v but got %v", e, job.Datacenters)
+	}
+
+	args := job.TaskGroups[0].Tasks[0].Config["args"]
+	if e := []interface{}{"--port", 6380, "--appendonly", true}; !reflect.DeepEqual(args, e) {
+		t.Fatalf("expected args %v but got %#v", e, args)
+	}
+
+	// Lists passed using the -var flag are comma separated.
+	fVars["datacenters"] = "dc4, dc5"
+	job, err = RenderJob("test-fixtures/multi_dc.nomad", []string{"test-fixtures/multi_dc.yaml"}, "", &fVars, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := []string{"dc4", "dc5"}; !reflect.DeepEqual(job.Datacenters, e) {
+		t.Fatalf("expected datacenters %v but got %v", e, job.Datacenters)
+	}
+
+	if _, err := hclList(map[string]string{}); err == nil {
+		t.Fatal("expected error for map value")
+	}
+}
+
+func TestTemplater_RenderJobRemote(t *testing.T) {
+
+	files := http.FileServer(http.Dir("test-fixtures"))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer s3cr3t" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		files.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	fVars := make(map[string]string)
+	opts := &RenderOptions{RemoteHeaders: http.Header{"Authorization": []string{"Bearer s3cr3t"}}}
+
+	// Test remote template and variable files of each type; the query string
+	// is ignored when determining the variable file format.
+	for _, v := range []string{"/test.tf", "/test.yaml?version=2"} {
+		job, err := RenderJob(srv.URL+"/single_templated.nomad", []string{srv.URL + v}, "", &fVars, opts)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", v, err)
+		}
+		if *job.Name != testJobName {
+			t.Fatalf("%s: expected %s but got %v", v, testJobName, *job.Name)
+		}
+	}
+
+	// Remote files can be mixed with local files.
+	job, err := RenderJob("test-fixtures/single_templated.nomad", []string{srv.URL + "/test.yaml", "test-fixtures/test-overwrite.yaml"}, "", &fVars, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *job.Name != testJobNameOverwrite {
+		t.Fatalf("expected %s but got %v", testJobNameOverwrite, *job.Name)
+	}
+
+	// Failures report the URL and the status code.
+	missing := srv.URL + "/missing.nomad"
+	_, err = RenderJob(missing, []string{srv.URL + "/test.yaml"}, "", &fVars, opts)
+	if err == nil || !strings.Contains(err.Error(), missing) || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("expected error containing %s and 404, got %v", missing, err)
+	}
+
+	_, err = RenderJob(srv.URL+"/single_templated.nomad", []string{srv.URL + "/test.yaml"}, "", &fVars, nil)
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("expected error containing 401, got %v", err)
+	}
+}
+
+func TestTemplater_interpolateVariables(t *testing.T) {
+
+	cases := []struct {
+		Name      string
+		Variables map[string]interface{}
+		Expected  map[string]interface{}
+		Error     string
+	}{
+		{
+			Name: "chained references",
+			Variables: map[string]interface{}{
+				"registry": "registry.example.com",
+				"app":      "[[ .repo ]]/app",
+				"repo":     "[[ .registry ]]/team",
+				"count":    3,
+				"template": "{{ env \"NOMAD_ALLOC_ID\" }}",
+			},
+			Expected: map[string]interface{}{
+				"registry": "registry.example.com",
+				"app":      "registry.example.com/team/app",
+				"repo":     "registry.example.com/team",
+				"count":    3,
+				"template": "{{ env \"NOMAD_ALLOC_ID\" }}",
+			},
+		},
+		{
+			Name: "functions and root references",
+			Variables: map[string]interface{}{
+				"name":  "redis",
+				"upper": "[[ replace $.name \"r\" \"R\" ]]",
+			},
+			Expected: map[string]interface{}{
+				"name":  "redis",
+				"upper": "Redis",
+			},
+		},
+		{
+			Name: "cycle",
+			Variables: map[string]interface{}{
+				"a": "[[ .b ]]",
+				"b": "[[ .c ]]",
+				"c": "[[ .a ]]",
+			},
+			Error: "variable reference cycle detected: a -> b -> c -> a",
+		},
+		{
+			Name:      "self reference",
+			Variables: map[string]interface{}{"a": "x[[ .a ]]"},
+			Error:     "variable reference cycle detected: a -> a",
+		},
+	}
+
+	for _, tc := range cases {
+		tpl := &tmpl{}
+		out, err := tpl.interpolateVariables(tc.Variables)
+		if tc.Error != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.Error) {
+				t.Fatalf("%s: expected error containing %q, got %v", tc.Name, tc.Error, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.Name, err)
+		}
+		if !reflect.DeepEqual(out, tc.Expected) {
+			t.Fatalf("%s: expected %v, got %v", tc.Name, tc.Expected, out)
+		}
+	}
+}
+
+func TestTemplater_RenderJobHCLVersion(t *testing.T) {
+
+	build := "1.0.4"
+	var parsed string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/agent/self":
+			fmt.Fprintf(w, `{"member":{"Tags":{"build":%q}}}`, build)
+		case "/v1/jobs/parse":
+			var req nomad.JobsParseRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			parsed = req.JobHCL
+			fmt.Fprintf(w, `{"ID":%q,"Name":%q}`, testJobName, testJobName)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	fVars := map[string]string{"job_name": testJobName}
+	opts := &RenderOptions{NomadAddr: srv.URL, HCLVersion: HCLVersion2}
+
+	job, err := RenderJob("test-fixtures/single_templated.nomad", []string{}, "", &fVars, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *job.Name != testJobName {
+		t.Fatalf("expected %s but got %v", testJobName, *job.Name)
+	}
+	if !strings.Contains(parsed, testJobName) {
+		t.Fatalf("expected the rendered job to be parsed by Nomad, got %q", parsed)
+	}
+
+	// Clusters which only parse HCL1 are rejected.
+	build = "0.12.9"
+	_, err = RenderJob("test-fixtures/single_templated.nomad", []string{}, "", &fVars, opts)
+	if err == nil || !strings.Contains(err.Error(), "does not support HCL2") {
+		t.Fatalf("expected HCL2 unsupported error, got %v", err)
+	}
+
+	opts.HCLVersion = 3
+	if _, err = RenderJob("test-fixtures/single_templated.nomad", []string{}, "", &fVars, opts); err == nil {
+		t.Fatal("expected error for unsupported HCL version")
+	}
+}
+
+func TestTemplater_RenderTemplateConstraints(t *testing.T) {
+
+	fVars := make(map[string]string)
+
+	job, err := RenderJob("test-fixtures/constraints.nomad", []string{"test-fixtures/constraints.yaml"}, "", &fVars, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*nomad.Constraint{
+		{LTarget: "${node.class}", RTarget: "large", Operand: "="},
+		{LTarget: "${attr.kernel.name}", RTarget: "linux", Operand: "="},
+		{LTarget: "${meta.rack}", RTarget: "^r[0-9]+$", Operand: "regexp"},
+	}
+	if !reflect.DeepEqual(job.Constraints, expected) {
+		t.Fatalf("expected job constraints %v but got %v", expected, job.Constraints)
+	}
+
+	expected = []*nomad.Constraint{
+		{LTarget: "${attr.cpu.numcores}", RTarget: "4", Operand: ">="},
+		{RTarget: "true", Operand: "distinct_hosts"},
+	}
+	if !reflect.DeepEqual(job.TaskGroups[0].Constraints, expected) {
+		t.Fatalf("expected group constraints %v but got %v", expected, job.TaskGroups[0].Constraints)
+	}
+}
+
+func TestTemplater_hclBlock(t *testing.T) {
+
+	out, err := hclBlock("service", map[string]interface{}{
+		"name":    "web",
+		"port":    8080,
+		"tags":    []interface{}{"http", "public"},
+		"meta":    map[string]interface{}{"team.name": "platform"},
+		"check":   []interface{}{map[string]interface{}{"type": "tcp"}},
+		"enabled": true,
+		"unset":   nil,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `service {
+  check {
+    type = "tcp"
+  }
+  enabled = true
+  meta {
+    "team.name" = "platform"
+  }
+  name = "web"
+  port = 8080
+  tags = ["http", "public"]
+}`
+	if out != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, out)
+	}
+
+	if _, err := hclBlock("constraint", []interface{}{"value"}); err == nil {
+		t.Fatal("expected error for list of strings")
+	}
+	if _, err := hclBlock("constraint", "value"); err == nil {
+		t.Fatal("expected error for string value")
+	}
+}
+
+func TestTemplater_explainVariables(t *testing.T) {
+
+	sources := map[string]map[string]interface{}{
+		helper.VarSourceFile: {"job_name": testJobNameOverwrite, "datacenter": testDCName},
+		helper.VarSourceEnv:  {"job_name": testJobNameOverwrite2},
+		helper.VarSourceFlag: {"group_name": testEnvValue},
+	}
+	origins := map[string][]string{
+		"job_name":   {"test.yaml", "test-overwrite.yaml"},
+		"datacenter": {"test.yaml"},
+	}
+
+	expected := []*variableExplanation{
+		{Key: "datacenter", Value: testDCName, Source: "file test.yaml"},
+		{Key: "group_name", Value: testEnvValue, Source: "flag"},
+		{
+			Key:        "job_name",
+			Value:      testJobNameOverwrite2,
+			Source:     "env",
+			Overridden: []string{"file test.yaml", "file test-overwrite.yaml"},
+		},
+	}
+
+	precedence := []string{helper.VarSourceFile, helper.VarSourceEnv, helper.VarSourceFlag}
+	vars, varOrigins := helper.VariableMergeOrdered(precedence, sources)
+	if out := explainVariables(vars, varOrigins, origins); !reflect.DeepEqual(out, expected) {
+		for _, e := range out {
+			t.Logf("%+v", e)
+		}
+		t.Fatal("unexpected variable explanations")
+	}
+}
+
+func TestTemplater_RenderTemplateExplainVars(t *testing.T) {
+
+	var buf bytes.Buffer
+	log.Logger = zerolog.New(&buf)
+
+	fVars := map[string]string{"job_name": testJobNameOverwrite2}
+	files := []string{"test-fixtures/test.yaml", "test-fixtures/test-overwrite.yaml"}
+
+	// The variables are explained even though the template does not exist.
+	_, err := RenderTemplate("test-fixtures/missing.nomad", files, "", &fVars, &RenderOptions{ExplainVars: true})
+	if err == nil {
+		t.Fatal("expected error for missing template")
+	}
+
+	expected := "variable job_name is levantExampleOverwrite2 from flag, overriding file test-fixtures/test.yaml, file test-fixtures/test-overwrite.yaml"
+	if !strings.Contains(buf.String(), expected) {
+		t.Fatalf("expected logs to contain %q, got %s", expected, buf.String())
+	}
+}
+
+func TestTemplater_RenderJobs(t *testing.T) {
+
+	fVars := make(map[string]string)
+
+	jobs, err := RenderJobs("test-fixtures/multi_job.nomad", []string{}, "", &fVars, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 2 {
+		t.Fatalf("expected 2 jobs but got %d", len(jobs))
+	}
+	if *jobs[0].ID != "cache" || *jobs[1].ID != "web" {
+		t.Fatalf("expected jobs cache and web but got %s and %s", *jobs[0].ID, *jobs[1].ID)
+	}
+	if image := jobs[1].TaskGroups[0].Tasks[0].Config["image"]; image != "nginx:1.19" {
+		t.Fatalf("expected nginx:1.19 but got %v", image)
+	}
+
+	// A single job is parsed as the whole document.
+	jobs, err = RenderJobs("test-fixtures/none_templated.nomad", []string{}, "", &fVars, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || *jobs[0].Name != testJobName {
+		t.Fatalf("expected single job %s but got %d jobs", testJobName, len(jobs))
+	}
+
+	if docs := splitJobs("job \"a\" {}\njob \"a\" {}\n"); len(docs) != 2 {
+		t.Fatalf("expected 2 documents but got %d", len(docs))
+	}
+	if docs := splitJobs("job {"); len(docs) != 1 {
+		t.Fatalf("expected unparsable document to be returned unchanged, got %d documents", len(docs))
+	}
+}
+
+func TestTemplater_parseJob(t *testing.T) {
+
+	build := "0.12.4"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/agent/self":
+			fmt.Fprintf(w, `{"member":{"Tags":{"build":%q}}}`, build)
+		case "/v1/jobs/parse":
+			w.Write([]byte(`{"ID":"parsedAsHCL2"}`))
+		default:
+			http.Error(w, "Invalid URL", http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	spec, err := ioutil.ReadFile("test-fixtures/none_templated.nomad")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cases := []struct {
+		Name     string
+		Build    string
+		Addr     string
+		Version  int
+		Expected string
+		Error    bool
+	}{
+		{Name: "auto with HCL1 cluster", Build: "0.12.4", Addr: srv.URL, Version: HCLVersionAuto, Expected: testJobName},
+		{Name: "auto with HCL2 cluster", Build: "1.0.1", Addr: srv.URL, Version: HCLVersionAuto, Expected: "parsedAsHCL2"},
+		{Name: "auto with unreachable cluster", Addr: unreachable.URL, Version: HCLVersionAuto, Expected: testJobName},
+		{Name: "HCL1", Build: "1.0.1", Addr: srv.URL, Version: HCLVersion1, Expected: testJobName},
+		{Name: "HCL2", Build: "1.0.1", Addr: srv.URL, Version: HCLVersion2, Expected: "parsedAsHCL2"},
+		{Name: "HCL2 with HCL1 cluster", Build: "0.12.4", Addr: srv.URL, Version: HCLVersion2, Error: true},
+	}
+
+	for _, tc := range cases {
+		build = tc.Build
+
+		job, err := parseJob(bytes.NewBuffer(spec), &RenderOptions{NomadAddr: tc.Addr, HCLVersion: tc.Version})
+		if tc.Error {
+			if err == nil {
+				t.Fatalf("%s: expected error", tc.Name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.Name, err)
+		}
+		if *job.ID != tc.Expected {
+			t.Fatalf("%s: got job %s, expected %s", tc.Name, *job.ID, tc.Expected)
+		}
+	}
+}
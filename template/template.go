@@ -1,18 +1,32 @@
 package template
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
 	"text/template"
+	"time"
 
 	consul "github.com/hashicorp/consul/api"
+	nomad "github.com/hashicorp/nomad/api"
 )
 
 // tmpl provides everything needed to fully render and job template using
 // inbuilt functions.
 type tmpl struct {
 	consulClient    *consul.Client
+	nomadClient     *nomad.Client
 	flagVariables   *map[string]string
 	jobTemplateFile string
 	variableFiles   []string
+	varPrecedence   []string
+	allowFuncs      []string
+	denyFuncs       []string
+	remoteHeaders   http.Header
+	remoteTimeout   time.Duration
+	explainVars     bool
+	ctx             context.Context
 }
 
 const (
@@ -24,11 +38,67 @@ const (
 	leftDelim             = "[["
 )
 
-// newTemplate returns an empty template with default options set
-func (t *tmpl) newTemplate() *template.Template {
+// newTemplate returns an empty template with default options set along with
+// the names of any functions removed by the allow and deny lists.
+func (t *tmpl) newTemplate() (*template.Template, []string, error) {
+	funcs, removed, err := t.templateFuncs()
+	if err != nil {
+		return nil, nil, err
+	}
+	return newFuncsTemplate(funcs), removed, nil
+}
+
+// templateFuncs returns the functions available to the template once the
+// allow and deny lists are applied, along with the names of those removed.
+func (t *tmpl) templateFuncs() (template.FuncMap, []string, error) {
+	funcs := funcMap(t.consulClient, t.nomadClient)
+
+	removed, err := restrictFuncs(funcs, t.allowFuncs, t.denyFuncs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return funcs, removed, nil
+}
+
+// newFuncsTemplate returns an empty template with default options set which
+// uses the functions.
+func newFuncsTemplate(funcs template.FuncMap) *template.Template {
 	tmpl := template.New("jobTemplate")
 	tmpl.Delims(leftDelim, rightDelim)
 	tmpl.Option("missingkey=zero")
-	tmpl.Funcs(funcMap(t.consulClient))
+	tmpl.Funcs(funcs)
 	return tmpl
 }
+
+// restrictFuncs removes the functions which are not in the allow list, when
+// one is given, or are in the deny list from the function map. The names of
+// the removed functions are returned.
+func restrictFuncs(funcs template.FuncMap, allow, deny []string) ([]string, error) {
+
+	for _, name := range append(append([]string{}, allow...), deny...) {
+		if _, ok := funcs[name]; !ok {
+			return nil, fmt.Errorf("template function %q in allow or deny list does not exist", name)
+		}
+	}
+
+	allowed := make(map[string]bool, len(allow))
+	for _, name := range allow {
+		allowed[name] = true
+	}
+
+	denied := make(map[string]bool, len(deny))
+	for _, name := range deny {
+		denied[name] = true
+	}
+
+	var removed []string
+	for name := range funcs {
+		if (len(allow) > 0 && !allowed[name]) || denied[name] {
+			delete(funcs, name)
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(removed)
+
+	return removed, nil
+}
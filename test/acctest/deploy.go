@@ -26,7 +26,7 @@ func (c DeployTestStepRunner) Run(s *TestState) error {
 	}
 	c.Vars["job_name"] = s.JobName
 
-	job, err := template.RenderJob("fixtures/"+c.FixtureName, []string{}, "", &c.Vars)
+	job, err := template.RenderJob("fixtures/"+c.FixtureName, []string{}, "", &c.Vars, nil)
 	if err != nil {
 		return fmt.Errorf("error rendering template: %s", err)
 	}
@@ -43,8 +43,8 @@ func (c DeployTestStepRunner) Run(s *TestState) error {
 		},
 	}
 
-	if !levant.TriggerDeployment(cfg, nil) {
-		return fmt.Errorf("deployment failed")
+	if err := levant.TriggerDeployment(cfg, nil); err != nil {
+		return err
 	}
 
 	return nil